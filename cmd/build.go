@@ -1,15 +1,22 @@
 package main
 
 import (
+	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/dustin/go-humanize"
+	"github.com/go-openapi/swag"
 	"github.com/openshift/appliance/pkg/asset/appliance"
 	"github.com/openshift/appliance/pkg/asset/config"
+	"github.com/openshift/appliance/pkg/asset/data"
 	"github.com/openshift/appliance/pkg/asset/deploy"
 	"github.com/openshift/appliance/pkg/asset/installer"
+	"github.com/openshift/appliance/pkg/asset/recovery"
 	"github.com/openshift/appliance/pkg/asset/upgrade"
 	"github.com/openshift/appliance/pkg/consts"
+	"github.com/openshift/appliance/pkg/fileutil"
 	"github.com/openshift/appliance/pkg/log"
 	"github.com/openshift/installer/pkg/asset"
 	assetstore "github.com/openshift/installer/pkg/asset/store"
@@ -17,6 +24,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
 )
 
 var (
@@ -24,10 +32,14 @@ var (
 		debugBootstrap    bool
 		debugBaseIgnition bool
 		isLiveISO         bool
+		streamData        bool
+		concurrency       int
+		lock              bool
 	}
 
-	envConfig    config.EnvConfig
-	deployConfig *config.DeployConfig
+	envConfig      config.EnvConfig
+	deployConfig   *config.DeployConfig
+	addNodesConfig *config.AddNodesConfig
 )
 
 func NewBuildCmd() *cobra.Command {
@@ -40,6 +52,13 @@ func NewBuildCmd() *cobra.Command {
 	cmd.AddCommand(getBuildISOCmd())
 	cmd.AddCommand(getBuildUpgradeISOCmd())
 	cmd.AddCommand(getBuildLiveISOCmd())
+	cmd.AddCommand(getBuildAddNodesISOCmd())
+	cmd.Flags().BoolVar(&buildOpts.streamData, "stream-data", false,
+		"Write the data ISO directly into the appliance disk image (reduces the required disk space, but the data ISO isn't cached)")
+	cmd.Flags().BoolVar(&buildOpts.lock, "lock", false,
+		fmt.Sprintf("Only resolve the content of the appliance config and write it to %s (without building)", config.ApplianceLockFilename))
+	cmd.PersistentFlags().IntVar(&buildOpts.concurrency, "concurrency", 3,
+		"Maximum number of independent assets (e.g. base disk image, base ISO, data ISO) to generate in parallel")
 	cmd.PersistentFlags().BoolVar(&buildOpts.debugBootstrap, "debug-bootstrap", false, "")
 	cmd.PersistentFlags().BoolVar(&buildOpts.debugBaseIgnition, "debug-base-ignition", false, "")
 	if err := cmd.PersistentFlags().MarkHidden("debug-bootstrap"); err != nil {
@@ -88,8 +107,29 @@ func getBuildLiveISOCmd() *cobra.Command {
 	return cmd
 }
 
+func getBuildAddNodesISOCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:    "add-nodes-iso",
+		Short:  "Build an ISO for adding nodes to an appliance cluster",
+		PreRun: preRunBuild,
+		Run:    runBuildAddNodesISO,
+	}
+
+	addNodesConfig = &config.AddNodesConfig{}
+	cmd.Flags().StringVar(&addNodesConfig.Kubeconfig, "kubeconfig", "",
+		fmt.Sprintf("Kubeconfig file of the appliance cluster (default: %s under assets directory)", consts.DefaultKubeconfigPath))
+
+	return cmd
+}
+
 func runBuild(cmd *cobra.Command, args []string) {
+	if buildOpts.lock {
+		runBuildLock(cmd)
+		return
+	}
+
 	timer.StartTimer(timer.TotalTimeElapsed)
+	diskUsage := startDiskUsageMonitor()
 
 	cleanup := log.SetupFileHook(rootOpts.dir)
 	defer cleanup()
@@ -104,6 +144,16 @@ func runBuild(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	// Generate independent assets in parallel
+	warmAssets := []asset.Asset{&appliance.BaseDiskImage{}, &recovery.BaseISO{}}
+	if !envConfig.StreamData {
+		// A streamed data ISO isn't cached, so it's generated only as part of the disk image
+		warmAssets = append(warmAssets, &data.DataISO{})
+	}
+	if err := generateAssetsInParallel(cmd, warmAssets...); err != nil {
+		logrus.Fatal(err)
+	}
+
 	// Generate ApplianceDiskImage asset (including all of its dependencies)
 	if err := getAssetStore().Fetch(cmd.Context(), &applianceDiskImage); err != nil {
 		logrus.Fatal(errors.Wrapf(err, "failed to fetch %s", applianceDiskImage.Name()))
@@ -115,11 +165,16 @@ func runBuild(cmd *cobra.Command, args []string) {
 		logrus.Fatal(errors.Wrapf(err, "failed to fetch %s", installerBinary.Name()))
 	}
 
+	// Pin the resolved content for later builds
+	generateLockIfNeeded(cmd)
+
 	// Get binary name (openshift-install or openshift-install-fips)
 	installerBinaryName := applianceDiskImage.InstallerBinaryName
 
 	timer.StopTimer(timer.TotalTimeElapsed)
 	timer.LogSummary()
+	logrus.Infof("Peak disk usage: %s", humanize.IBytes(diskUsage.Stop()))
+	logReleaseVersion(cmd)
 
 	logrus.Info()
 	logrus.Infof("Appliance disk image was successfully created in the 'assets' directory: %s", filepath.Base(applianceDiskImage.File.Filename))
@@ -131,6 +186,15 @@ func runBuild(cmd *cobra.Command, args []string) {
 	}
 }
 
+func runBuildLock(cmd *cobra.Command) {
+	cleanup := log.SetupFileHook(rootOpts.dir)
+	defer cleanup()
+
+	if err := generateLock(cmd); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
 func runBuildISO(cmd *cobra.Command, args []string) {
 	cleanup := log.SetupFileHook(rootOpts.dir)
 	defer cleanup()
@@ -184,8 +248,37 @@ func runBuildUpgradeISO(cmd *cobra.Command, args []string) {
 	logrus.Infof("2. oc apply -f %s", upgradeISO.UpgradeManifestFileName)
 }
 
+func runBuildAddNodesISO(cmd *cobra.Command, args []string) {
+	cleanup := log.SetupFileHook(rootOpts.dir)
+	defer cleanup()
+
+	// Generate AddNodesConfig asset
+	if err := getAssetStore().Fetch(cmd.Context(), addNodesConfig); err != nil {
+		logrus.Fatal(err)
+	}
+
+	// Generate AddNodesISO asset
+	addNodesISO := appliance.AddNodesISO{}
+	if err := getAssetStore().Fetch(cmd.Context(), &addNodesISO); err != nil {
+		logrus.Fatal(errors.Wrapf(err, "failed to fetch %s", addNodesISO.Name()))
+	}
+
+	// Remove state file (cleanup)
+	if err := deleteStateFile(rootOpts.dir); err != nil {
+		logrus.Fatal(err)
+	}
+
+	logrus.Info()
+	logrus.Infof("Appliance add nodes ISO is available in the 'assets' directory: %s", filepath.Base(addNodesISO.File.Filename))
+	logrus.Info()
+	logrus.Infof("To add the nodes:")
+	logrus.Infof("1. Boot each node from the ISO (keep it attached until the node joins the cluster)")
+	logrus.Infof("2. Approve the CSRs of the nodes (oc get csr)")
+}
+
 func runBuildLiveISO(cmd *cobra.Command, args []string) {
 	timer.StartTimer(timer.TotalTimeElapsed)
+	diskUsage := startDiskUsageMonitor()
 
 	cleanup := log.SetupFileHook(rootOpts.dir)
 	defer cleanup()
@@ -200,6 +293,11 @@ func runBuildLiveISO(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	// Generate independent assets in parallel
+	if err := generateAssetsInParallel(cmd, &recovery.BaseISO{}, &data.DataISO{}); err != nil {
+		logrus.Fatal(err)
+	}
+
 	// Generate ApplianceLiveISO asset (including all of its dependencies)
 	if err := getAssetStore().Fetch(cmd.Context(), &applianceLiveISO); err != nil {
 		logrus.Fatal(errors.Wrapf(err, "failed to fetch %s", applianceLiveISO.Name()))
@@ -211,11 +309,16 @@ func runBuildLiveISO(cmd *cobra.Command, args []string) {
 		logrus.Fatal(errors.Wrapf(err, "failed to fetch %s", installerBinary.Name()))
 	}
 
+	// Pin the resolved content for later builds
+	generateLockIfNeeded(cmd)
+
 	// Get binary name (openshift-install or openshift-install-fips)
 	installerBinaryName := applianceLiveISO.InstallerBinaryName
 
 	timer.StopTimer(timer.TotalTimeElapsed)
 	timer.LogSummary()
+	logrus.Infof("Peak disk usage: %s", humanize.IBytes(diskUsage.Stop()))
+	logReleaseVersion(cmd)
 
 	logrus.Info()
 	logrus.Infof("Appliance live ISO was successfully created in the 'assets' directory: %s", filepath.Base(applianceLiveISO.File.Filename))
@@ -233,6 +336,7 @@ func preRunBuild(cmd *cobra.Command, args []string) {
 		DebugBootstrap:    buildOpts.debugBootstrap,
 		DebugBaseIgnition: buildOpts.debugBaseIgnition,
 		IsLiveISO:         buildOpts.isLiveISO,
+		StreamData:        buildOpts.streamData,
 	}
 
 	// Generate EnvConfig asset
@@ -246,6 +350,65 @@ func preRunBuildLiveISO(cmd *cobra.Command, args []string) {
 	preRunBuild(cmd, args)
 }
 
+// generateAssetsInParallel generates assets that depend only on EnvConfig and ApplianceConfig
+// concurrently (bounded by the concurrency flag). The asset store isn't safe for concurrent use,
+// so the parents are fetched once and the assets are generated from them (into the cache dir).
+// The assets are then fetched by the asset store (i.e. recorded in the state file), reusing the cached files.
+func generateAssetsInParallel(cmd *cobra.Command, assets ...asset.Asset) error {
+	assetStore := getAssetStore()
+	parents := asset.Parents{}
+	for _, parent := range []asset.Asset{&config.EnvConfig{}, &config.ApplianceConfig{}} {
+		if err := assetStore.Fetch(cmd.Context(), parent); err != nil {
+			return errors.Wrapf(err, "failed to fetch %s", parent.Name())
+		}
+		parents.Add(parent)
+	}
+
+	restoreSpinners := log.EnableMultiProgress()
+	g := errgroup.Group{}
+	g.SetLimit(max(buildOpts.concurrency, 1))
+	for _, a := range assets {
+		g.Go(func() error {
+			if err := a.Generate(parents); err != nil {
+				return errors.Wrapf(err, "failed to generate %s", a.Name())
+			}
+			return nil
+		})
+	}
+	err := g.Wait()
+	restoreSpinners()
+	if err != nil {
+		return err
+	}
+
+	for _, a := range assets {
+		if err := assetStore.Fetch(cmd.Context(), a); err != nil {
+			return errors.Wrapf(err, "failed to fetch %s", a.Name())
+		}
+	}
+	return nil
+}
+
+// logReleaseVersion logs the requested OCP release version and the version it was resolved to
+func logReleaseVersion(cmd *cobra.Command) {
+	applianceConfig := config.ApplianceConfig{}
+	if err := getAssetStore().Fetch(cmd.Context(), &applianceConfig); err != nil {
+		logrus.Fatal(errors.Wrapf(err, "failed to fetch %s", applianceConfig.Name()))
+	}
+	// The release is resolved when loading the config (i.e. no need to resolve it again)
+	ocpRelease := applianceConfig.Config.OcpRelease
+	logrus.Infof("OCP release version: %s (requested: %s)", ocpRelease.Version, applianceConfig.RequestedVersion)
+	logrus.Infof("OCP release image: %s", swag.StringValue(ocpRelease.URL))
+}
+
+// startDiskUsageMonitor tracks the disk space used by the build
+// (on the filesystem of the assets directory, relative to the start of the build)
+func startDiskUsageMonitor() *fileutil.DiskUsageMonitor {
+	monitor := fileutil.NewDiskUsageMonitor(rootOpts.dir, 5*time.Second)
+	monitor.Start()
+	return monitor
+}
+
 func getAssetStore() asset.Store {
 	assetStore, err := assetstore.NewStore(rootOpts.dir)
 	if err != nil {
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 
 	"github.com/openshift/appliance/pkg/asset/config"
+	"github.com/openshift/appliance/pkg/types"
+	"github.com/openshift/installer/pkg/asset"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -40,3 +44,90 @@ func NewGenerateConfigCmd() *cobra.Command {
 
 	return cmd
 }
+
+func NewConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the appliance config manifest",
+	}
+	cmd.AddCommand(getConfigMigrateCmd())
+	cmd.AddCommand(getConfigRenderCmd())
+	return cmd
+}
+
+func getConfigRenderCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: "render",
+		Short: fmt.Sprintf("Print the effective appliance config manifest (merged with %s overlays, secrets redacted)",
+			config.ApplianceConfigOverlaysDir),
+		Args: cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			configFilePath := filepath.Join(rootOpts.dir, config.ApplianceConfigFilename)
+			data, err := os.ReadFile(configFilePath)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			base := &asset.File{Filename: config.ApplianceConfigFilename, Data: data}
+
+			overlays, err := config.ReadOverlays(rootOpts.dir)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+
+			merged, err := config.MergeApplianceConfig(base, overlays)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			rendered, err := config.RenderApplianceConfig(merged)
+			if err != nil {
+				logrus.Fatal(errors.Wrapf(err, "can't parse %s", config.ApplianceConfigFilename))
+			}
+			fmt.Print(string(rendered))
+		},
+	}
+	return cmd
+}
+
+func getConfigMigrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: fmt.Sprintf("Convert the appliance config manifest to apiVersion %s", types.ApplianceConfigApiVersion),
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			configFilePath := filepath.Join(rootOpts.dir, config.ApplianceConfigFilename)
+			data, err := os.ReadFile(configFilePath)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			_, apiVersion, err := config.ParseApplianceConfig(data)
+			if err != nil {
+				logrus.Fatal(errors.Wrapf(err, "can't parse %s", config.ApplianceConfigFilename))
+			}
+			if apiVersion == types.ApplianceConfigApiVersion {
+				logrus.Infof("Config file is already in apiVersion %s", apiVersion)
+				return
+			}
+			if apiVersion != types.ApplianceConfigV1Beta1ApiVersion {
+				logrus.Fatalf("Unsupported apiVersion %q, only %s can be migrated to %s",
+					apiVersion, types.ApplianceConfigV1Beta1ApiVersion, types.ApplianceConfigApiVersion)
+			}
+
+			migrated, err := config.MigrateApplianceConfig(data)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+
+			// Keep the original file (the comments aren't preserved in the migrated file)
+			backupFilePath := fmt.Sprintf("%s.%s", configFilePath, apiVersion)
+			if err = os.WriteFile(backupFilePath, data, 0644); err != nil { // #nosec G306
+				logrus.Fatal(err)
+			}
+			if err = os.WriteFile(configFilePath, migrated, 0644); err != nil { // #nosec G306
+				logrus.Fatal(err)
+			}
+			logrus.Infof("Migrated %s from apiVersion %s to %s (original file: %s)",
+				config.ApplianceConfigFilename, apiVersion, types.ApplianceConfigApiVersion, filepath.Base(backupFilePath))
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/openshift/appliance/pkg/asset/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	explainOpts struct {
+		schema bool
+	}
+)
+
+func NewExplainCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "explain [field.path]",
+		Short: fmt.Sprintf("Describe the fields of %s (e.g. 'explain registry.port')", config.ApplianceConfigFilename),
+		Args:  cobra.MaximumNArgs(1),
+		Run:   runExplain,
+	}
+	cmd.Flags().BoolVar(&explainOpts.schema, "schema", false,
+		fmt.Sprintf("Print the JSON Schema of %s (e.g. for validating configs in editors and CI)", config.ApplianceConfigFilename))
+	return cmd
+}
+
+func runExplain(cmd *cobra.Command, args []string) {
+	schema, err := config.ApplianceConfigSchema()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	if explainOpts.schema {
+		data, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	path := ""
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if err = schema.Explain(os.Stdout, path); err != nil {
+		logrus.Fatal(err)
+	}
+}
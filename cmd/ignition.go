@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	igntypes "github.com/coreos/ignition/v2/config/v3_4/types"
+	"github.com/openshift/appliance/pkg/asset/config"
+	"github.com/openshift/appliance/pkg/asset/ignition"
+	ignitionutil "github.com/openshift/appliance/pkg/ignition"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/thoas/go-funk"
+)
+
+const (
+	ignitionRenderDir = "ignition-render"
+)
+
+var (
+	ignitionOpts struct {
+		phase     string
+		outputDir string
+	}
+)
+
+func NewIgnitionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ignition",
+		Short: "Render and diff the appliance ignition configs",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			runRootCmd(cmd, args)
+			if !funk.ContainsString(ignition.Phases, ignitionOpts.phase) {
+				logrus.Fatalf("Invalid phase %q (supported phases: %s)", ignitionOpts.phase, strings.Join(ignition.Phases, ", "))
+			}
+			if err := getAssetStore().Fetch(cmd.Context(), &config.EnvConfig{
+				AssetsDir:      rootOpts.dir,
+				RenderIgnition: true,
+			}); err != nil {
+				logrus.Fatal(err)
+			}
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			if err := deleteStateFile(rootOpts.dir); err != nil {
+				logrus.Fatal(err)
+			}
+		},
+	}
+	cmd.PersistentFlags().StringVar(&ignitionOpts.phase, "phase", "",
+		fmt.Sprintf("Phase of the ignition config (%s)", strings.Join(ignition.Phases, "|")))
+	cmd.PersistentFlags().StringVar(&ignitionOpts.outputDir, "output-dir", ignitionRenderDir,
+		"Directory of the rendered ignition configs (relative to the assets directory)")
+	if err := cmd.MarkPersistentFlagRequired("phase"); err != nil {
+		logrus.Fatal(err)
+	}
+	cmd.AddCommand(getIgnitionRenderCmd())
+	cmd.AddCommand(getIgnitionDiffCmd())
+	return cmd
+}
+
+func getIgnitionRenderCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "render",
+		Short: "Write the ignition config of a phase, with the decoded files and systemd units as a directory tree",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			tree, err := renderIgnition(cmd, ignitionOpts.phase)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			renderDir := filepath.Join(rootOpts.dir, ignitionOpts.outputDir, ignitionOpts.phase)
+			if err = ignitionutil.WriteTree(tree, renderDir); err != nil {
+				logrus.Fatal(err)
+			}
+			logrus.Infof("Rendered %s ignition at assets directory: %s",
+				ignitionOpts.phase, filepath.Join(ignitionOpts.outputDir, ignitionOpts.phase))
+		},
+	}
+	return cmd
+}
+
+func getIgnitionDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Print the changes in the ignition config of a phase since a previous render",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			renderDir := filepath.Join(rootOpts.dir, ignitionOpts.outputDir, ignitionOpts.phase)
+			previous, err := ignitionutil.ReadTree(renderDir)
+			if err != nil {
+				logrus.Fatal(errors.Wrapf(err, "failed to read a previous render (run 'ignition render' first)"))
+			}
+			current, err := renderIgnition(cmd, ignitionOpts.phase)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			diff := ignitionutil.DiffTrees(previous, current)
+			if diff == "" {
+				logrus.Infof("No changes in %s ignition", ignitionOpts.phase)
+				return
+			}
+			fmt.Print(diff)
+		},
+	}
+	return cmd
+}
+
+// renderIgnition generates the ignition config of the phase and lays it out as a tree
+func renderIgnition(cmd *cobra.Command, phase string) (map[string][]byte, error) {
+	applianceConfig := &config.ApplianceConfig{}
+	if err := getAssetStore().Fetch(cmd.Context(), applianceConfig); err != nil {
+		return nil, err
+	}
+
+	var ignitionConfig *igntypes.Config
+	switch phase {
+	case ignition.DeployPhase:
+		if err := getAssetStore().Fetch(cmd.Context(), &config.DeployConfig{TargetDevice: "/dev/sda"}); err != nil {
+			return nil, err
+		}
+		deployIgnition := &ignition.DeployIgnition{}
+		if err := getAssetStore().Fetch(cmd.Context(), deployIgnition); err != nil {
+			return nil, err
+		}
+		ignitionConfig = &deployIgnition.Config
+	case ignition.BootstrapPhase:
+		bootstrapIgnition := &ignition.BootstrapIgnition{}
+		if err := getAssetStore().Fetch(cmd.Context(), bootstrapIgnition); err != nil {
+			return nil, err
+		}
+		ignitionConfig = &bootstrapIgnition.Config
+	case ignition.InstallPhase:
+		installIgnition := &ignition.InstallIgnition{}
+		if err := getAssetStore().Fetch(cmd.Context(), installIgnition); err != nil {
+			return nil, err
+		}
+		ignitionConfig = &installIgnition.Config
+	case ignition.RecoveryPhase, ignition.UnconfiguredPhase:
+		recoveryIgnition := &ignition.RecoveryIgnition{}
+		if err := getAssetStore().Fetch(cmd.Context(), recoveryIgnition); err != nil {
+			return nil, err
+		}
+		ignitionConfig = &recoveryIgnition.Merged
+		if phase == ignition.UnconfiguredPhase {
+			ignitionConfig = &recoveryIgnition.Unconfigured
+		}
+	case ignition.AddNodesPhase, ignition.AddNodesInstallPhase:
+		addNodesIgnition := &ignition.AddNodesIgnition{}
+		if err := getAssetStore().Fetch(cmd.Context(), addNodesIgnition); err != nil {
+			return nil, err
+		}
+		ignitionConfig = &addNodesIgnition.Live
+		if phase == ignition.AddNodesInstallPhase {
+			ignitionConfig = &addNodesIgnition.Install
+		}
+	}
+
+	return ignitionutil.RenderTree(ignitionConfig, applianceConfig.GetIgnitionSpecVersion())
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/openshift/appliance/pkg/asset/config"
+	"github.com/openshift/appliance/pkg/lock"
+	"github.com/openshift/appliance/pkg/log"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func NewLockCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lock",
+		Short: "Manage the appliance lock file (pinned content for reproducible builds)",
+	}
+	cmd.AddCommand(getLockUpdateCmd())
+	return cmd
+}
+
+func getLockUpdateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:    "update",
+		Short:  "Resolve the latest content of the appliance config and refresh the lock file",
+		Args:   cobra.ExactArgs(0),
+		PreRun: preRunBuild,
+		Run:    runLockUpdate,
+		PostRun: func(cmd *cobra.Command, args []string) {
+			if err := deleteStateFile(rootOpts.dir); err != nil {
+				logrus.Fatal(err)
+			}
+		},
+	}
+	return cmd
+}
+
+func runLockUpdate(cmd *cobra.Command, args []string) {
+	cleanup := log.SetupFileHook(rootOpts.dir)
+	defer cleanup()
+
+	// Move the current lock file aside, so the config is resolved from scratch
+	lockPath := filepath.Join(rootOpts.dir, config.ApplianceLockFilename)
+	backupPath := lockPath + ".bak"
+	if err := os.Rename(lockPath, backupPath); err != nil && !os.IsNotExist(err) {
+		logrus.Fatal(err)
+	}
+
+	if err := generateLock(cmd); err != nil {
+		if err := os.Rename(backupPath, lockPath); err != nil && !os.IsNotExist(err) {
+			logrus.Error(err)
+		}
+		logrus.Fatal(err)
+	}
+	if err := os.RemoveAll(backupPath); err != nil {
+		logrus.Fatal(err)
+	}
+
+	logrus.Info()
+	logrus.Infof("Run 'clean --cache' before re-building the appliance with the updated lock file")
+}
+
+// generateLock resolves the content of the appliance config and writes it to the lock file
+func generateLock(cmd *cobra.Command) error {
+	applianceConfig := config.ApplianceConfig{}
+	if err := getAssetStore().Fetch(cmd.Context(), &applianceConfig); err != nil {
+		return errors.Wrapf(err, "failed to fetch %s", applianceConfig.Name())
+	}
+
+	spinner := log.NewSpinner(
+		"Resolving appliance content...",
+		"Successfully resolved appliance content",
+		"Failed to resolve appliance content",
+		&envConfig,
+	)
+	applianceLock, err := lock.NewLock(lock.LockConfig{
+		EnvConfig:       &envConfig,
+		ApplianceConfig: &applianceConfig,
+	}).Generate()
+	if err != nil {
+		return log.StopSpinner(spinner, err)
+	}
+	if err = config.WriteLock(rootOpts.dir, applianceLock); err != nil {
+		return log.StopSpinner(spinner, err)
+	}
+	if err = log.StopSpinner(spinner, nil); err != nil {
+		return err
+	}
+
+	logrus.Infof("Generated lock file in assets directory: %s (OCP %s)", config.ApplianceLockFilename, applianceLock.Release.Version)
+	return nil
+}
+
+// generateLockIfNeeded writes the lock file on the first build (i.e. when the build isn't locked yet)
+func generateLockIfNeeded(cmd *cobra.Command) {
+	applianceConfig := config.ApplianceConfig{}
+	if err := getAssetStore().Fetch(cmd.Context(), &applianceConfig); err != nil {
+		logrus.Fatal(errors.Wrapf(err, "failed to fetch %s", applianceConfig.Name()))
+	}
+	if applianceConfig.Lock != nil {
+		return
+	}
+	if err := generateLock(cmd); err != nil {
+		logrus.Fatal(err)
+	}
+}
@@ -27,6 +27,11 @@ func applianceMain() {
 		NewBuildCmd(),
 		NewCleanCmd(),
 		NewGenerateConfigCmd(),
+		NewConfigCmd(),
+		NewExplainCmd(),
+		NewIgnitionCmd(),
+		NewVersionsCmd(),
+		NewLockCmd(),
 
 		// Hidden commands for debug
 		NewGenerateInstallIgnitionCmd(),
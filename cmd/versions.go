@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/go-openapi/swag"
+	"github.com/openshift/appliance/pkg/asset/config"
+	"github.com/openshift/appliance/pkg/consts"
+	"github.com/openshift/appliance/pkg/graph"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	versionsOpts struct {
+		version   string
+		channels  []string
+		arch      string
+		graphURL  string
+		graphFile string
+	}
+)
+
+func NewVersionsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "versions",
+		Short: "List the available OCP release versions per channel and architecture",
+		Args:  cobra.ExactArgs(0),
+		Run:   runVersions,
+	}
+	cmd.Flags().StringVar(&versionsOpts.version, "version", consts.MaxOcpVersion, "OCP release version in major.minor format")
+	cmd.Flags().StringSliceVar(&versionsOpts.channels, "channel",
+		[]string{string(graph.ReleaseChannelStable), string(graph.ReleaseChannelFast), string(graph.ReleaseChannelEUS), string(graph.ReleaseChannelCandidate)},
+		"OCP release update channels to list (stable|fast|eus|candidate)")
+	cmd.Flags().StringVar(&versionsOpts.arch, "cpu-architecture", config.CpuArchitectureX86, "OCP release CPU architecture (x86_64|aarch64|ppc64le)")
+	cmd.Flags().StringVar(&versionsOpts.graphURL, "graph-url", graph.CincinnatiAddress, "Cincinnati graph URL (e.g. an OpenShift Update Service instance)")
+	cmd.Flags().StringVar(&versionsOpts.graphFile, "graph-file", "", "Path to a saved graph JSON file (use instead of --graph-url)")
+	cmd.MarkFlagsMutuallyExclusive("graph-url", "graph-file")
+	return cmd
+}
+
+func runVersions(cmd *cobra.Command, args []string) {
+	graphConfig := graph.GraphConfig{
+		Arch:              config.GetReleaseArchitectureByCPU(versionsOpts.arch),
+		Version:           versionsOpts.version,
+		CincinnatiAddress: swag.String(versionsOpts.graphURL),
+	}
+	if versionsOpts.graphFile != "" {
+		graphConfig.GraphFile = swag.String(versionsOpts.graphFile)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHANNEL\tARCH\tVERSION\tPAYLOAD")
+	for _, c := range versionsOpts.channels {
+		channel := graph.ReleaseChannel(c)
+		graphConfig.Channel = &channel
+		releases, err := graph.NewGraph(graphConfig).GetVersions()
+		if err != nil {
+			logrus.Warnf("Failed to list versions of %s: %v", c, err)
+			continue
+		}
+		for _, release := range releases {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c, versionsOpts.arch, release.Version, release.Payload)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		logrus.Fatal(err)
+	}
+}
@@ -0,0 +1,236 @@
+package appliance
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/openshift/appliance/pkg/asset/config"
+	"github.com/openshift/appliance/pkg/asset/ignition"
+	"github.com/openshift/appliance/pkg/consts"
+	"github.com/openshift/appliance/pkg/coreos"
+	"github.com/openshift/appliance/pkg/fileutil"
+	ignitionutil "github.com/openshift/appliance/pkg/ignition"
+	"github.com/openshift/appliance/pkg/installer"
+	"github.com/openshift/appliance/pkg/log"
+	"github.com/openshift/appliance/pkg/reproducible"
+	"github.com/openshift/appliance/pkg/syslinux"
+	"github.com/openshift/appliance/pkg/xorriso"
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+	"github.com/openshift/installer/pkg/asset"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	addNodesWorkDir      = "add-nodes"
+	addNodesIsoWorkDir   = "add-nodes-iso"
+	addNodesImageName    = "/images/add-nodes-appliance.img"
+	addNodesIgnitionPath = "/usr/lib/ignition/base.d/99-add-nodes.ign"
+)
+
+// AddNodesISO is an asset that generates the ISO for adding nodes to an appliance cluster.
+// The node ISO (created by the installer) is extended with the registry data,
+// so the added nodes pull the release images from a local registry.
+type AddNodesISO struct {
+	File *asset.File
+}
+
+var _ asset.Asset = (*AddNodesISO)(nil)
+
+// Dependencies returns the assets on which the AddNodesISO asset depends.
+func (a *AddNodesISO) Dependencies() []asset.Asset {
+	return []asset.Asset{
+		&config.EnvConfig{},
+		&config.ApplianceConfig{},
+		&config.AddNodesConfig{},
+		&ignition.AddNodesIgnition{},
+	}
+}
+
+// Generate the add nodes ISO.
+func (a *AddNodesISO) Generate(dependencies asset.Parents) error {
+	envConfig := &config.EnvConfig{}
+	applianceConfig := &config.ApplianceConfig{}
+	addNodesConfig := &config.AddNodesConfig{}
+	addNodesIgnition := &ignition.AddNodesIgnition{}
+	dependencies.Get(envConfig, applianceConfig, addNodesConfig, addNodesIgnition)
+
+	// The data ISO of the appliance build is reused (rather than mirroring the release images again)
+	dataIsoFile := envConfig.FindInCache(applianceConfig.GetDataImageFileName())
+	if dataIsoFile == "" {
+		return errors.Errorf("missing %s in the cache directory, build the appliance disk image first "+
+			"(without --stream-data, which doesn't keep the data ISO in the cache)", applianceConfig.GetDataImageFileName())
+	}
+
+	nodesConfigPath := filepath.Join(envConfig.AssetsDir, consts.NodesConfigFileName)
+	nodesConfig, err := os.ReadFile(nodesConfigPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.Errorf("missing %s in the assets directory (describes the nodes to add)", consts.NodesConfigFileName)
+		}
+		return err
+	}
+	kubeconfig := addNodesConfig.Kubeconfig
+	if kubeconfig == "" {
+		kubeconfig = filepath.Join(envConfig.AssetsDir, consts.DefaultKubeconfigPath)
+	}
+	if _, err = os.Stat(kubeconfig); err != nil {
+		return errors.Wrapf(err, "invalid kubeconfig of the appliance cluster")
+	}
+
+	// Create work dir (with the nodes config)
+	workDir := filepath.Join(envConfig.TempDir, addNodesWorkDir)
+	if err = os.RemoveAll(workDir); err != nil {
+		return err
+	}
+	if err = os.MkdirAll(workDir, os.ModePerm); err != nil {
+		return err
+	}
+	if err = os.WriteFile(filepath.Join(workDir, consts.NodesConfigFileName), nodesConfig, 0600); err != nil {
+		return err
+	}
+
+	spinner := log.NewSpinner(
+		"Generating node ISO...",
+		"Successfully generated node ISO",
+		"Failed to generate node ISO",
+		envConfig,
+	)
+	installerConfig := installer.InstallerConfig{
+		EnvConfig:       envConfig,
+		ApplianceConfig: applianceConfig,
+	}
+	nodeImagePath, err := installer.NewInstaller(installerConfig).CreateNodeImage(workDir, kubeconfig)
+	if err != nil {
+		return log.StopSpinner(spinner, err)
+	}
+	if err = log.StopSpinner(spinner, nil); err != nil {
+		return err
+	}
+
+	return a.buildAddNodesISO(envConfig, applianceConfig, nodeImagePath, dataIsoFile, addNodesIgnition)
+}
+
+// Name returns the human-friendly name of the asset.
+func (a *AddNodesISO) Name() string {
+	return "Appliance add nodes ISO"
+}
+
+func (a *AddNodesISO) buildAddNodesISO(
+	envConfig *config.EnvConfig,
+	applianceConfig *config.ApplianceConfig,
+	nodeImagePath string,
+	dataIsoFile string,
+	addNodesIgnition *ignition.AddNodesIgnition) error {
+
+	// Create work dir
+	workDir, err := os.MkdirTemp(envConfig.TempDir, addNodesIsoWorkDir)
+	if err != nil {
+		return err
+	}
+
+	// Create data dir
+	dataDir := filepath.Join(workDir, liveIsoDataDir)
+	if err = os.MkdirAll(dataDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	spinner := log.NewSpinner(
+		"Copying data ISO...",
+		"Successfully copied data ISO",
+		"Failed to copy data ISO",
+		envConfig,
+	)
+	spinner.DirToMonitor = workDir
+
+	// Extract node ISO
+	if err = isoeditor.Extract(nodeImagePath, workDir); err != nil {
+		logrus.Errorf("Failed to extract ISO: %s", err.Error())
+		return log.StopSpinner(spinner, err)
+	}
+
+	// Split data.iso file and output to work dir
+	// (to bypass ISO9660 limitation for large files)
+	dataIsoSplitFile := filepath.Join(dataDir, filepath.Base(dataIsoFile))
+	if err = fileutil.SplitFile(dataIsoFile, dataIsoSplitFile, "3G"); err != nil {
+		logrus.Error(err)
+		return log.StopSpinner(spinner, err)
+	}
+
+	if err = log.StopSpinner(spinner, nil); err != nil {
+		return err
+	}
+
+	spinner = log.NewSpinner(
+		"Generating appliance add nodes ISO...",
+		"Successfully generated appliance add nodes ISO",
+		"Failed to generate appliance add nodes ISO",
+		envConfig,
+	)
+	spinner.FileToMonitor = consts.AddNodesIsoFileName
+
+	// Create add-nodes-appliance.img file
+	// (merged by ignition with the config embedded in the node ISO)
+	coreOSConfig := coreos.CoreOSConfig{
+		ApplianceConfig: applianceConfig,
+		EnvConfig:       envConfig,
+	}
+	c := coreos.NewCoreOS(coreOSConfig)
+	ignitionBytes, err := ignitionutil.MarshalIgnitionConfig(&addNodesIgnition.Live, applianceConfig.GetIgnitionSpecVersion())
+	if err != nil {
+		logrus.Errorf("Failed to marshal add nodes ignition to json: %s", err.Error())
+		return log.StopSpinner(spinner, err)
+	}
+	addNodesImagePath := filepath.Join(workDir, addNodesImageName)
+	if err = c.WrapIgnition(ignitionBytes, addNodesIgnitionPath, addNodesImagePath); err != nil {
+		logrus.Errorf("Failed to create add nodes image: %s", err.Error())
+		return log.StopSpinner(spinner, err)
+	}
+
+	// Add add-nodes-appliance.img to initrd
+	if err = appendInitrdImage(workDir, addNodesImageName); err != nil {
+		return log.StopSpinner(spinner, err)
+	}
+
+	// Append the kernel arguments (boot.kernelArguments and boot.console)
+	if err = coreos.AppendKargs(workDir, applianceConfig.GetBootKernelArguments()); err != nil {
+		return log.StopSpinner(spinner, err)
+	}
+
+	// Generate add nodes ISO
+	volumeID, err := isoeditor.VolumeIdentifier(nodeImagePath)
+	if err != nil {
+		return log.StopSpinner(spinner, err)
+	}
+	addNodesIsoFileName := filepath.Join(envConfig.AssetsDir, consts.AddNodesIsoFileName)
+	if err = isoeditor.Create(addNodesIsoFileName, workDir, volumeID); err != nil {
+		logrus.Errorf("Failed to create ISO: %s", err.Error())
+		return log.StopSpinner(spinner, err)
+	}
+
+	// Set fixed timestamps (reproducible builds), before isohybrid writes the partition tables
+	if envConfig.SourceDateEpoch != nil {
+		if err = xorriso.NewXorriso(nil).SetDates(addNodesIsoFileName, *envConfig.SourceDateEpoch); err != nil {
+			return log.StopSpinner(spinner, err)
+		}
+	}
+
+	hybrid := syslinux.NewIsoHybrid(nil)
+	if envConfig.SourceDateEpoch == nil {
+		if err = hybrid.Convert(addNodesIsoFileName); err != nil {
+			logrus.Errorf("Error creating isohybrid: %s", err)
+		}
+	} else {
+		// Replace the random MBR disk signature and GPT GUIDs as well
+		if err = hybrid.ConvertWithID(addNodesIsoFileName, reproducible.MBRID(envConfig.ImageSeed)); err != nil {
+			return log.StopSpinner(spinner, err)
+		}
+		if err = reproducible.SetPartitionTableGUIDs(addNodesIsoFileName, envConfig.ImageSeed); err != nil {
+			return log.StopSpinner(spinner, err)
+		}
+	}
+
+	a.File = &asset.File{Filename: addNodesIsoFileName}
+
+	return log.StopSpinner(spinner, nil)
+}
@@ -1,6 +1,8 @@
 package appliance
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
 
 	"github.com/go-openapi/swag"
@@ -10,15 +12,23 @@ import (
 	"github.com/openshift/appliance/pkg/asset/recovery"
 	"github.com/openshift/appliance/pkg/consts"
 	"github.com/openshift/appliance/pkg/conversions"
+	"github.com/openshift/appliance/pkg/debugfs"
 	"github.com/openshift/appliance/pkg/executer"
+	"github.com/openshift/appliance/pkg/fileutil"
 	"github.com/openshift/appliance/pkg/installer"
 	"github.com/openshift/appliance/pkg/log"
+	"github.com/openshift/appliance/pkg/reproducible"
 	"github.com/openshift/appliance/pkg/templates"
 	"github.com/openshift/installer/pkg/asset"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	// Index of the boot partition (ext4) in the CoreOS disk image partitions
+	bootPartitionIndex = 2
+)
+
 // ApplianceDiskImage is an asset that generates the OpenShift-based appliance.
 type ApplianceDiskImage struct {
 	File                *asset.File
@@ -59,9 +69,13 @@ func (a *ApplianceDiskImage) Generate(dependencies asset.Parents) error {
 	if err := templates.RenderTemplateFile(
 		consts.UserCfgTemplateFile,
 		templates.GetUserCfgTemplateData(
-			consts.GrubMenuEntryName,
-			swag.BoolValue(applianceConfig.Config.EnableFips),
-			true),
+			applianceConfig.GetGrubMenuEntryName(),
+			applianceConfig.GetGrubDefaultEntry(),
+			applianceConfig.GetGrubTimeout(),
+			"",
+			swag.BoolValue(applianceConfig.Config.Cluster.EnableFips),
+			applianceConfig.GetConsoleArguments(),
+			applianceConfig.GetKernelArguments()),
 		envConfig.TempDir); err != nil {
 		return log.StopSpinner(spinner, err)
 	}
@@ -71,16 +85,20 @@ func (a *ApplianceDiskImage) Generate(dependencies asset.Parents) error {
 	dataIsoSize := dataISO.Size
 	baseImageFile := baseDiskImage.File.Filename
 	baseIsoSize := templates.NewPartitions().GetBootPartitionsSize(baseImageFile)
-	diskSize := a.getDiskSize(applianceConfig.Config.DiskSizeGB, baseIsoSize, recoveryIsoSize, dataIsoSize)
+	diskSize := a.getDiskSize(applianceConfig.Config.Disk.SizeGB, baseIsoSize, recoveryIsoSize, dataIsoSize)
 
 	applianceImageFile := filepath.Join(envConfig.AssetsDir, consts.ApplianceFileName)
 	recoveryIsoFile := filepath.Join(envConfig.CacheDir, consts.RecoveryIsoFileName)
-	dataIsoFile := filepath.Join(envConfig.CacheDir, consts.DataIsoFileName)
+	var dataIsoFile string
+	if dataISO.File != nil {
+		dataIsoFile = dataISO.File.Filename
+	}
 	userCfgFile := templates.GetFilePathByTemplate(consts.UserCfgTemplateFile, envConfig.TempDir)
-	isCompact := applianceConfig.Config.DiskSizeGB == nil
+	isCompact := applianceConfig.Config.Disk.SizeGB == nil
 	gfTemplateData := templates.GetGuestfishScriptTemplateData(
 		isCompact, diskSize, baseIsoSize, recoveryIsoSize, dataIsoSize, baseImageFile,
-		applianceImageFile, recoveryIsoFile, dataIsoFile, userCfgFile, consts.GrubCfgFilePath, envConfig.TempDir)
+		applianceImageFile, recoveryIsoFile, dataIsoFile, userCfgFile, consts.GrubCfgFilePath, envConfig.TempDir,
+		envConfig.SourceDateEpoch == nil)
 	if err := templates.RenderTemplateFile(
 		consts.GuestfishScriptTemplateFile,
 		gfTemplateData,
@@ -96,6 +114,24 @@ func (a *ApplianceDiskImage) Generate(dependencies asset.Parents) error {
 		return log.StopSpinner(spinner, errors.Wrapf(err, "guestfish script failure"))
 	}
 
+	// Write the data ISO directly into the data partition
+	if dataISO.DataDirPath != "" {
+		partitions := templates.NewPartitions().GetAgentPartitions(diskSize, baseIsoSize, recoveryIsoSize, dataIsoSize, isCompact)
+		if err := a.streamDataISO(envConfig, applianceImageFile, dataISO, partitions.DataPartition); err != nil {
+			return log.StopSpinner(spinner, err)
+		}
+	}
+
+	// Handle GRUB with fixed timestamps, and replace the random partitions GUIDs (reproducible builds)
+	if envConfig.SourceDateEpoch != nil {
+		if err := a.updateBootPartition(envConfig, applianceImageFile, baseImageFile, userCfgFile); err != nil {
+			return log.StopSpinner(spinner, err)
+		}
+		if err := reproducible.SetPartitionTableGUIDs(applianceImageFile, envConfig.ImageSeed); err != nil {
+			return log.StopSpinner(spinner, err)
+		}
+	}
+
 	a.File = &asset.File{Filename: applianceImageFile}
 
 	installerConfig := installer.InstallerConfig{
@@ -112,6 +148,73 @@ func (a *ApplianceDiskImage) Name() string {
 	return "Appliance disk image"
 }
 
+func (a *ApplianceDiskImage) streamDataISO(envConfig *config.EnvConfig, applianceImageFile string, dataISO *data.DataISO,
+	dataPartition *templates.Partition) error {
+	logrus.Debugf("Streaming data ISO into the appliance disk image data partition (start sector: %d)", dataPartition.StartSector)
+
+	f, err := os.OpenFile(applianceImageFile, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			logrus.Errorf("Failed to close appliance disk image: %s", err.Error())
+		}
+	}()
+
+	offset := dataPartition.StartSector * templates.SectorSize
+	if err = dataISO.Stream(envConfig, fileutil.NewSparseWriter(f, offset)); err != nil {
+		return errors.Wrapf(err, "failed to write data ISO to the appliance disk image")
+	}
+	return nil
+}
+
+// updateBootPartition appends user.cfg to the GRUB config and removes the default boot loader entries
+// (same as the guestfish script). The boot partition is updated using debugfs rather than mounting it,
+// so all the updated timestamps are set to SOURCE_DATE_EPOCH.
+func (a *ApplianceDiskImage) updateBootPartition(envConfig *config.EnvConfig, applianceImageFile, baseImageFile, userCfgFile string) error {
+	logrus.Debug("Updating boot partition with debugfs")
+
+	// The boot partition is copied as is from the base disk image
+	coreosPartitions, err := templates.NewPartitions().GetCoreOSPartitions(baseImageFile)
+	if err != nil {
+		return err
+	}
+	bootPartitionOffset := coreosPartitions[bootPartitionIndex].StartSector * templates.SectorSize
+
+	d := debugfs.NewDebugfs(nil)
+	grubCfgFile := filepath.Join(filepath.Dir(userCfgFile), "grub.cfg")
+	if err = d.DumpFile(applianceImageFile, bootPartitionOffset, consts.GrubCfgFilePath, grubCfgFile); err != nil {
+		return err
+	}
+	if err = appendFile(grubCfgFile, userCfgFile); err != nil {
+		return err
+	}
+	return d.Write(applianceImageFile, bootPartitionOffset, []string{
+		"rm /grub2/grub.cfg",
+		fmt.Sprintf("write %s /grub2/grub.cfg", grubCfgFile),
+		"rm /boot/loader/entries/ostree-1-rhcos.conf",
+		"rm /boot/loader/entries/ostree-1.conf",
+	}, envConfig.SourceDateEpoch)
+}
+
+// appendFile appends the content of the source file to the dest file
+func appendFile(dest, source string) error {
+	content, err := os.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(dest, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(content); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 func (a *ApplianceDiskImage) getDiskSize(diskSizeGB *int, baseIsoSize, recoveryIsoSize, dataIsoSize int64) int64 {
 	if diskSizeGB != nil {
 		return int64(*diskSizeGB)
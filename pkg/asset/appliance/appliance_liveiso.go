@@ -1,7 +1,6 @@
 package appliance
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -14,9 +13,12 @@ import (
 	"github.com/openshift/appliance/pkg/consts"
 	"github.com/openshift/appliance/pkg/coreos"
 	"github.com/openshift/appliance/pkg/fileutil"
+	ignitionutil "github.com/openshift/appliance/pkg/ignition"
 	"github.com/openshift/appliance/pkg/installer"
 	"github.com/openshift/appliance/pkg/log"
+	"github.com/openshift/appliance/pkg/reproducible"
 	"github.com/openshift/appliance/pkg/syslinux"
+	"github.com/openshift/appliance/pkg/xorriso"
 	"github.com/openshift/assisted-image-service/pkg/isoeditor"
 	"github.com/openshift/installer/pkg/asset"
 	"github.com/sirupsen/logrus"
@@ -29,7 +31,6 @@ const (
 	bootstrapIgnitionPath         = "/usr/lib/ignition/base.d/99-bootstrap.ign"
 	defaultGrubConfigFilePath     = "EFI/redhat/grub.cfg"
 	defaultIsolinuxConfigFilePath = "isolinux/isolinux.cfg"
-	defaultKargsConfigFilePath    = "coreos/kargs.json"
 )
 
 // ApplianceLiveISO is an asset that generates the OpenShift-based appliance.
@@ -61,7 +62,7 @@ func (a *ApplianceLiveISO) Generate(dependencies asset.Parents) error {
 	dependencies.Get(envConfig, applianceConfig, dataISO, baseISO, recoveryIgnition)
 
 	// Build the live ISO
-	if err := a.buildLiveISO(envConfig, applianceConfig, recoveryIgnition); err != nil {
+	if err := a.buildLiveISO(envConfig, applianceConfig, dataISO, recoveryIgnition); err != nil {
 		return err
 	}
 
@@ -71,7 +72,7 @@ func (a *ApplianceLiveISO) Generate(dependencies asset.Parents) error {
 		EnvConfig:       envConfig,
 	}
 	c := coreos.NewCoreOS(coreOSConfig)
-	ignitionBytes, err := json.Marshal(recoveryIgnition.Unconfigured)
+	ignitionBytes, err := ignitionutil.MarshalIgnitionConfig(&recoveryIgnition.Unconfigured, applianceConfig.GetIgnitionSpecVersion())
 	if err != nil {
 		logrus.Errorf("Failed to marshal recovery ignition to json: %s", err.Error())
 		return err
@@ -102,6 +103,7 @@ func (a *ApplianceLiveISO) Name() string {
 func (a *ApplianceLiveISO) buildLiveISO(
 	envConfig *config.EnvConfig,
 	applianceConfig *config.ApplianceConfig,
+	dataISO *data.DataISO,
 	recoveryIgnition *ignition.RecoveryIgnition) error {
 
 	// Create work dir
@@ -146,8 +148,8 @@ func (a *ApplianceLiveISO) buildLiveISO(
 
 	// Split data.iso file and output to work dir
 	// (to bypass ISO9660 limitation for large files)
-	dataIsoFile := filepath.Join(envConfig.CacheDir, consts.DataIsoFileName)
-	dataIsoSplitFile := filepath.Join(dataDir, consts.DataIsoFileName)
+	dataIsoFile := dataISO.File.Filename
+	dataIsoSplitFile := filepath.Join(dataDir, filepath.Base(dataIsoFile))
 	if err = fileutil.SplitFile(dataIsoFile, dataIsoSplitFile, "3G"); err != nil {
 		logrus.Error(err)
 		return err
@@ -171,7 +173,7 @@ func (a *ApplianceLiveISO) buildLiveISO(
 		EnvConfig:       envConfig,
 	}
 	c := coreos.NewCoreOS(coreOSConfig)
-	ignitionBytes, err := json.Marshal(recoveryIgnition.Bootstrap)
+	ignitionBytes, err := ignitionutil.MarshalIgnitionConfig(&recoveryIgnition.Bootstrap, applianceConfig.GetIgnitionSpecVersion())
 	if err != nil {
 		logrus.Errorf("Failed to marshal recovery ignition to json: %s", err.Error())
 		return log.StopSpinner(spinner, err)
@@ -183,21 +185,13 @@ func (a *ApplianceLiveISO) buildLiveISO(
 	}
 
 	// Add bootstrap.img to initrd
-	replacement := fmt.Sprintf("$1 $2 %s", bootstrapImageName)
-	grubCfgPath := filepath.Join(workDir, defaultGrubConfigFilePath)
-	if err := editFile(grubCfgPath, `(?m)^(\s+initrd) (.+| )+$`, replacement); err != nil {
-		return err
-	}
-	replacement = fmt.Sprintf("${1},%s ${2}", bootstrapImageName)
-	isolinuxConfigFilePath := filepath.Join(workDir, defaultIsolinuxConfigFilePath)
-	if err := editFile(isolinuxConfigFilePath, `(?m)^(\s+append.*initrd=\S+) (.*)$`, replacement); err != nil {
+	if err := appendInitrdImage(workDir, bootstrapImageName); err != nil {
 		return err
 	}
 
-	// Fix offset in kargs.json
-	initrdImageOffset := int64(len(bootstrapImageName) + 1)
-	if err := fixKargsOffset(workDir, defaultIsolinuxConfigFilePath, initrdImageOffset); err != nil {
-		return err
+	// Append the kernel arguments (boot.kernelArguments and boot.console)
+	if err := coreos.AppendKargs(workDir, applianceConfig.GetBootKernelArguments()); err != nil {
+		return log.StopSpinner(spinner, err)
 	}
 
 	// Generate live ISO
@@ -211,61 +205,59 @@ func (a *ApplianceLiveISO) buildLiveISO(
 		return err
 	}
 
+	// Set fixed timestamps (reproducible builds), before isohybrid writes the partition tables
+	if envConfig.SourceDateEpoch != nil {
+		if err = xorriso.NewXorriso(nil).SetDates(liveIsoFileName, *envConfig.SourceDateEpoch); err != nil {
+			return log.StopSpinner(spinner, err)
+		}
+	}
+
 	hybrid := syslinux.NewIsoHybrid(nil)
-	if err = hybrid.Convert(liveIsoFileName); err != nil {
-		logrus.Errorf("Error creating isohybrid: %s", err)
+	if envConfig.SourceDateEpoch == nil {
+		if err = hybrid.Convert(liveIsoFileName); err != nil {
+			logrus.Errorf("Error creating isohybrid: %s", err)
+		}
+	} else {
+		// Replace the random MBR disk signature and GPT GUIDs as well
+		if err = hybrid.ConvertWithID(liveIsoFileName, reproducible.MBRID(envConfig.ImageSeed)); err != nil {
+			return log.StopSpinner(spinner, err)
+		}
+		if err = reproducible.SetPartitionTableGUIDs(liveIsoFileName, envConfig.ImageSeed); err != nil {
+			return log.StopSpinner(spinner, err)
+		}
 	}
 
 	return log.StopSpinner(spinner, nil)
 }
 
-func editFile(fileName string, reString string, replacement string) error {
-	content, err := os.ReadFile(fileName)
-	if err != nil {
+// appendInitrdImage appends the image to the initrd of the boot configs of an extracted CoreOS ISO
+func appendInitrdImage(workDir, imageName string) error {
+	replacement := fmt.Sprintf("$1 $2 %s", imageName)
+	grubCfgPath := filepath.Join(workDir, defaultGrubConfigFilePath)
+	if err := editFile(grubCfgPath, `(?m)^(\s+initrd) (.+| )+$`, replacement); err != nil {
 		return err
 	}
-
-	re := regexp.MustCompile(reString)
-	newContent := re.ReplaceAllString(string(content), replacement)
-
-	if err := os.WriteFile(fileName, []byte(newContent), 0600); err != nil {
+	replacement = fmt.Sprintf("${1},%s ${2}", imageName)
+	isolinuxConfigFilePath := filepath.Join(workDir, defaultIsolinuxConfigFilePath)
+	if err := editFile(isolinuxConfigFilePath, `(?m)^(\s+append.*initrd=\S+) (.*)$`, replacement); err != nil {
 		return err
 	}
 
-	return nil
+	// Fix offset in kargs.json
+	initrdImageOffset := int64(len(imageName) + 1)
+	return coreos.FixKargsOffset(workDir, defaultIsolinuxConfigFilePath, initrdImageOffset)
 }
 
-func fixKargsOffset(workDir, configPath string, offset int64) error {
-	kargsConfigFilePath := filepath.Join(workDir, defaultKargsConfigFilePath)
-	kargsData, err := os.ReadFile(kargsConfigFilePath)
+func editFile(fileName string, reString string, replacement string) error {
+	content, err := os.ReadFile(fileName)
 	if err != nil {
 		return err
 	}
 
-	var kargsConfig struct {
-		Default string `json:"default"`
-		Files   []struct {
-			End    string `json:"end"`
-			Offset int64  `json:"offset"`
-			Pad    string `json:"pad"`
-			Path   string `json:"path"`
-		} `json:"files"`
-		Size int64 `json:"size"`
-	}
-	if err := json.Unmarshal(kargsData, &kargsConfig); err != nil {
-		return err
-	}
-	for i, file := range kargsConfig.Files {
-		if file.Path == configPath {
-			kargsConfig.Files[i].Offset = file.Offset + offset
-		}
-	}
+	re := regexp.MustCompile(reString)
+	newContent := re.ReplaceAllString(string(content), replacement)
 
-	workConfigFileContent, err := json.MarshalIndent(kargsConfig, "", "  ")
-	if err != nil {
-		return err
-	}
-	if err := os.WriteFile(kargsConfigFilePath, workConfigFileContent, 0600); err != nil {
+	if err := os.WriteFile(fileName, []byte(newContent), 0600); err != nil {
 		return err
 	}
 
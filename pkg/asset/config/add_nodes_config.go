@@ -0,0 +1,26 @@
+package config
+
+import (
+	"github.com/openshift/installer/pkg/asset"
+)
+
+type AddNodesConfig struct {
+	Kubeconfig string
+}
+
+var _ asset.Asset = (*AddNodesConfig)(nil)
+
+// Dependencies returns no dependencies.
+func (e *AddNodesConfig) Dependencies() []asset.Asset {
+	return []asset.Asset{}
+}
+
+// Generate AddNodesConfig asset
+func (e *AddNodesConfig) Generate(dependencies asset.Parents) error {
+	return nil
+}
+
+// Name returns the human-friendly name of the asset.
+func (e *AddNodesConfig) Name() string {
+	return "Add Nodes Config"
+}
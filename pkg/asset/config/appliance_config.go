@@ -1,11 +1,16 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/go-openapi/swag"
 	"github.com/hashicorp/go-version"
@@ -14,17 +19,21 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/thoas/go-funk"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/yaml"
 
 	"github.com/openshift/appliance/pkg/consts"
 	"github.com/openshift/appliance/pkg/executer"
 	"github.com/openshift/appliance/pkg/graph"
+	"github.com/openshift/appliance/pkg/log/redact"
 	"github.com/openshift/appliance/pkg/types"
 )
 
 const (
 	ApplianceConfigFilename       = "appliance-config.yaml"
+	applianceConfigKind           = "ApplianceConfig"
 	CustomClusterManifestsDir     = "openshift"
 	CustomClusterManifestsPattern = "*.yaml"
 
@@ -38,14 +47,23 @@ const (
 	ReleaseArchitectureARM64   = "arm64"
 	ReleaseArchitecturePPC64le = "ppc64le"
 
+	// Operator Subscription install plan approvals
+	InstallPlanApprovalAutomatic = "Automatic"
+	InstallPlanApprovalManual    = "Manual"
+
 	// Validation values
 	MinDiskSize     = 150
 	RegistryMinPort = 1024
 	RegistryMaxPort = 65535
 
+	coreUserName = "core"
+
 	// Validation commands
 	PodmanPull = "podman pull %s"
 
+	// Required for the erofs data partition format (erofs-utils)
+	mkfsErofsBinary = "mkfs.erofs"
+
 	// Release
 	templateGetVersion = "oc adm release info %s -o template --template '{{.metadata.version}}'"
 	templateGetDigest  = "oc adm release info %s -o template --template '{{.digest}}'"
@@ -54,6 +72,9 @@ const (
 var (
 	cpuArchitectures             = []string{CpuArchitectureX86, CpuArchitectureAARCH64, CpuArchitecturePPC64le}
 	releaseImage, releaseVersion string
+
+	// userNameRegex matches valid user and group names (see useradd(8))
+	userNameRegex = regexp.MustCompile(`^[a-z_][a-z0-9_-]{0,31}$`)
 )
 
 // ApplianceConfig reads the appliance-config.yaml file.
@@ -61,6 +82,13 @@ type ApplianceConfig struct {
 	File     *asset.File
 	Config   *types.ApplianceConfig
 	Template string
+
+	// RequestedVersion is the ocpRelease version as specified in appliance-config.yaml
+	// (before resolving it into the release version)
+	RequestedVersion string
+
+	// Lock is the content pinned in appliance-lock.yaml (nil when not locked)
+	Lock *types.ApplianceLock
 }
 
 var _ asset.WritableAsset = (*ApplianceConfig)(nil)
@@ -83,161 +111,11 @@ func (a *ApplianceConfig) GetConfigFilename() string {
 
 // Generate generates the Agent Config manifest.
 func (a *ApplianceConfig) Generate(dependencies asset.Parents) error {
-	applianceConfigTemplate := `#
-# Note: This is a sample ApplianceConfig file showing
-# which fields are available to aid you in creating your
-# own appliance-config.yaml file.
-#
-apiVersion: %s
-kind: ApplianceConfig
-
-ocpRelease:
-  # OCP release version in major.minor or major.minor.patch format
-  # (in case of major.minor, latest patch version will be used)
-  # If the specified version is not yet available, the latest supported version will be used.
-  # Supported versions: %s-%s
-  version: ocp-release-version
-
-  # OCP release update channel: stable|fast|eus|candidate
-  # Default: %s
-  # [Optional]
-  # channel: ocp-release-channel
-
-  # OCP release CPU architecture: x86_64|aarch64|ppc64le
-  # Default: %s
-  # [Optional]
-  # cpuArchitecture: cpu-architecture
-
-  # OCP release URL (use instead of channel/architecture)
-  # [Optional]
-  # url: ocp-release-url
-
-# Virtual size of the appliance disk image.
-# If specified, should be at least %dGiB.
-# If not specified, the disk image should be resized when 
-# cloning to a device (e.g. using virt-resize tool).
-# [Optional]
-# diskSizeGB: disk-size
-
-# PullSecret is required for mirroring the OCP release payload
-# Can be obtained from: https://console.redhat.com/openshift/install/pull-secret
-pullSecret: pull-secret
-
-# Public SSH key for accessing the appliance during the bootstrap phase
-# [Optional]
-# sshKey: ssh-key
-
-# Password of user 'core' for connecting from console
-# [Optional]
-# userCorePass: user-core-pass
-
-# Local image registry details (used when building the appliance)
-# Note: building an image internally by default.
-# [Optional]
-# imageRegistry:
-  # The URI for the image
-  # Default: ""
-  # Examples: 
-  # - docker.io/library/registry:2
-  # - quay.io/libpod/registry:2.8
-  # [Optional]
-  # uri: uri
-  #
-  # The image registry container TCP port to bind. A valid port number is between %d and %d.
-  # Default: %d
-  # [Optional]
-  # port: port
-  #
-  # Use the registry binary built internally (to avoid nested containers when running the image).
-  # Default: false
-  # [Optional]
-  # useBinary: %t
-
-# Path to pre-mirrored images from oc-mirror workspace.
-# When provided, skips image mirroring and uses the pre-mirrored registry data.
-# The path should point to an oc-mirror workspace directory containing a 'data' subdirectory.
-# [Optional]
-# mirrorPath: /path/to/mirror/workspace
-
-# Enable all default CatalogSources (on openshift-marketplace namespace).
-# Should be disabled for disconnected environments.
-# Default: false
-# [Optional]
-# enableDefaultSources: %t
-
-# Stop the local registry post cluster installation.
-# Note that additional images and operators won't be available when stopped.
-# Default: false
-# [Optional]
-# stopLocalRegistry: %t
-
-# Create PinnedImageSets for both the master and worker MCPs.
-# The PinnedImageSets will include all the images included in the appliance disk image.
-# Requires openshift version 4.16 or above.
-# WARNING: 
-# As of 4.18, PinnedImageSets feature is still not GA.
-# Thus, enabling it will set the cluster to tech preview,
-# which means the cluster cannot be upgraded
-# (i.e. should only be used for testing purposes).
-# Default: false
-# [Optional]
-# createPinnedImageSets: %t
-
-# Enable FIPS mode for the cluster.
-# Note: 'fips' should be enabled also in install-config.yaml.
-# Default: false
-# [Optional]
-# enableFips: %t
-
-# Enable the interactive installation flow.
-# Should be enabled to provide cluster configuration through the web UI
-# (i.e. instead of using a config-image).
-# Default: false
-# [Optional]
-# enableInteractiveFlow: %t
-
-# Rename CatalogSource names generated by oc-mirror to the default naming.
-# E.g. 'redhat-operators' instead of 'cs-redhat-operator-index-v4-19'.
-# Default: false
-# [Optional]
-# useDefaultSourceNames: %t
-
-# Disable sigstore attachments for the listed registry hosts during oc-mirror.
-# Required when source registries do not publish OCI .sig attachment manifests (failing oc-mirror v2).
-# Example: certified operator bundles on registry.connect.redhat.com.
-# Registries that already have configuration under /etc/containers/registries.d/ are skipped.
-# [Optional]
-# disableSigstoreRegistries:
-#   - registry.connect.redhat.com
-
-# Additional images to be included in the appliance disk image.
-# [Optional]
-# additionalImages:
-#   - name: image-url
-
-# Images to avoid including in the appliance disk image (by name or regular expression).
-# [Optional]
-# blockedImages:
-#   - name: image-url
-
-# Operators to be included in the appliance disk image.
-# See examples in https://github.com/openshift/oc-mirror/blob/main/docs/imageset-config-ref.yaml.
-# [Optional]
-# operators:
-# - catalog: catalog-uri
-#   packages:
-#     - name: package-name
-#       channels:
-#         - name: channel-name
-`
-	a.Template = fmt.Sprintf(
-		applianceConfigTemplate,
-		types.ApplianceConfigApiVersion,
-		consts.MinOcpVersion, consts.MaxOcpVersion,
-		graph.ReleaseChannelStable, CpuArchitectureX86, MinDiskSize,
-		RegistryMinPort, RegistryMaxPort, consts.RegistryPort, consts.UseRegistryBinary,
-		consts.EnableDefaultSources, consts.StopLocalRegistry, consts.CreatePinnedImageSets,
-		consts.EnableFips, consts.EnableInteractiveFlow, consts.UseDefaultSourceNames)
+	schema, err := ApplianceConfigSchema()
+	if err != nil {
+		return err
+	}
+	a.Template = schema.Template()
 
 	return nil
 }
@@ -276,8 +154,24 @@ func (a *ApplianceConfig) Load(f asset.FileFetcher) (bool, error) {
 		return false, errors.Wrap(err, fmt.Sprintf("failed to load %s file", a.GetConfigFilename()))
 	}
 
-	config := &types.ApplianceConfig{}
-	if err = yaml.UnmarshalStrict(file.Data, config); err != nil {
+	// Merge the config overlays (if exist)
+	overlays, err := fetchOverlays(f)
+	if err != nil {
+		return false, err
+	}
+	if len(overlays) > 0 {
+		data, err := MergeApplianceConfig(file, overlays)
+		if err != nil {
+			return false, err
+		}
+		for _, overlay := range overlays {
+			logrus.Infof("Applied config overlay: %s", overlay.Filename)
+		}
+		file = &asset.File{Filename: file.Filename, Data: data}
+	}
+
+	config, apiVersion, err := ParseApplianceConfig(file.Data)
+	if err != nil {
 		// Log full error only on debug level
 		logrus.Debug(err)
 
@@ -293,7 +187,18 @@ func (a *ApplianceConfig) Load(f asset.FileFetcher) (bool, error) {
 
 	a.File, a.Config = file, config
 
-	if err = a.validateConfig(f).ToAggregate(); err != nil {
+	if apiVersion == types.ApplianceConfigV1Beta1ApiVersion {
+		logrus.Warnf("%s apiVersion %s is deprecated, run 'config migrate' to convert it to %s",
+			a.GetConfigFilename(), apiVersion, types.ApplianceConfigApiVersion)
+	}
+
+	// Resolve the secret references (the resolved values are validated)
+	if err = toFileFieldPaths(a.resolveSecretRefs(f), apiVersion).ToAggregate(); err != nil {
+		return false, errors.Wrapf(err, "invalid Appliance Config configuration")
+	}
+	a.registerSecrets()
+
+	if err = toFileFieldPaths(a.validateConfig(f), apiVersion).ToAggregate(); err != nil {
 		return false, errors.Wrapf(err, "invalid Appliance Config configuration")
 	}
 
@@ -313,6 +218,12 @@ func (a *ApplianceConfig) Load(f asset.FileFetcher) (bool, error) {
 		return false, err
 	}
 
+	// Pin the config to appliance-lock.yaml (if exists)
+	a.RequestedVersion = config.OcpRelease.Version
+	if err = a.loadLock(f); err != nil {
+		return false, err
+	}
+
 	// Get OCP release image URL and version
 	releaseImage, releaseVersion, err = a.GetRelease()
 	if err != nil {
@@ -321,21 +232,51 @@ func (a *ApplianceConfig) Load(f asset.FileFetcher) (bool, error) {
 	config.OcpRelease.URL = &releaseImage
 	config.OcpRelease.Version = releaseVersion
 
-	if config.ImageRegistry == nil {
-		config.ImageRegistry = &types.ImageRegistry{
-			URI:  swag.String(""),
-			Port: swag.Int(consts.RegistryPort),
-		}
-	} else {
-		if config.ImageRegistry.URI == nil {
-			config.ImageRegistry.URI = swag.String("")
+	if config.Registry.URI == nil {
+		config.Registry.URI = swag.String("")
+	}
+	if config.Registry.Port == nil {
+		config.Registry.Port = swag.Int(consts.RegistryPort)
+	}
+
+	loadedApplianceConfig = a
+	return true, nil
+}
+
+// ParseApplianceConfig parses the content of an appliance-config.yaml file, and returns
+// the config (converted to the current apiVersion) and the apiVersion of the file.
+func ParseApplianceConfig(data []byte) (*types.ApplianceConfig, string, error) {
+	typeMeta := &metav1.TypeMeta{}
+	if err := yaml.Unmarshal(data, typeMeta); err != nil {
+		return nil, "", err
+	}
+
+	switch typeMeta.APIVersion {
+	case types.ApplianceConfigV1Beta1ApiVersion:
+		configV1Beta1 := &types.ApplianceConfigV1Beta1{}
+		if err := yaml.UnmarshalStrict(data, configV1Beta1); err != nil {
+			return nil, "", err
 		}
-		if config.ImageRegistry.Port == nil {
-			config.ImageRegistry.Port = swag.Int(consts.RegistryPort)
+		return configV1Beta1.Convert(), typeMeta.APIVersion, nil
+	default:
+		config := &types.ApplianceConfig{}
+		if err := yaml.UnmarshalStrict(data, config); err != nil {
+			return nil, "", err
 		}
+		return config, typeMeta.APIVersion, nil
 	}
+}
 
-	return true, nil
+// toFileFieldPaths returns the validation errors with the field paths of the config file apiVersion
+// (the config is validated after its conversion to the current apiVersion)
+func toFileFieldPaths(errs field.ErrorList, apiVersion string) field.ErrorList {
+	if apiVersion != types.ApplianceConfigV1Beta1ApiVersion {
+		return errs
+	}
+	for _, err := range errs {
+		err.Field = types.V1Beta1FieldPath(err.Field)
+	}
+	return errs
 }
 
 func (a *ApplianceConfig) GetCpuArchitecture() string {
@@ -351,6 +292,96 @@ func (a *ApplianceConfig) GetCoreosIsoName() string {
 	return consts.CoreosIsoName
 }
 
+// GetIgnitionSpecVersion returns the ignition spec version of the generated ignition configs (by the OCP release version)
+func (a *ApplianceConfig) GetIgnitionSpecVersion() string {
+	minOcpVer, _ := version.NewVersion(consts.MinOcpVersionForIgnitionSpec)
+	ocpVer, err := version.NewVersion(a.Config.OcpRelease.Version)
+	if err != nil || ocpVer.LessThan(minOcpVer) {
+		return consts.IgnitionSpecVersionLegacy
+	}
+	return consts.IgnitionSpecVersion
+}
+
+// GetDataPartitionFormat returns the filesystem format of the data partition
+func (a *ApplianceConfig) GetDataPartitionFormat() string {
+	if format := swag.StringValue(a.Config.Disk.DataPartitionFormat); format != "" {
+		return format
+	}
+	return consts.DataPartitionFormat
+}
+
+// GetConsoleArguments returns the kernel console arguments (empty when boot.console isn't specified)
+func (a *ApplianceConfig) GetConsoleArguments() []string {
+	console := swag.StringValue(a.Config.Boot.Console)
+	if console == "" {
+		return nil
+	}
+	return []string{"console=tty0", "console=" + console}
+}
+
+// GetKernelArguments returns the additional kernel arguments (without the console arguments)
+func (a *ApplianceConfig) GetKernelArguments() []string {
+	if a.Config.Boot.KernelArguments == nil {
+		return nil
+	}
+	return *a.Config.Boot.KernelArguments
+}
+
+// GetBootKernelArguments returns the kernel arguments appended to the default ones
+// (i.e. of the live/deployment ISOs and the cluster nodes)
+func (a *ApplianceConfig) GetBootKernelArguments() []string {
+	return append(a.GetConsoleArguments(), a.GetKernelArguments()...)
+}
+
+// GetGrubTimeout returns the GRUB menu timeout in seconds
+func (a *ApplianceConfig) GetGrubTimeout() int {
+	if a.Config.Boot.Grub.Timeout != nil {
+		return *a.Config.Boot.Grub.Timeout
+	}
+	return consts.GrubTimeout
+}
+
+// GetGrubMenuEntryName returns the name of the appliance GRUB menu entry
+func (a *ApplianceConfig) GetGrubMenuEntryName() string {
+	if name := swag.StringValue(a.Config.Boot.Grub.MenuEntryName); name != "" {
+		return name
+	}
+	return consts.GrubMenuEntryName
+}
+
+// GetGrubRecoveryMenuEntryName returns the name of the recovery GRUB menu entry (of the cluster nodes)
+func (a *ApplianceConfig) GetGrubRecoveryMenuEntryName() string {
+	if name := swag.StringValue(a.Config.Boot.Grub.RecoveryMenuEntryName); name != "" {
+		return name
+	}
+	return consts.GrubMenuEntryNameRecovery
+}
+
+// GetGrubDefaultEntry returns the default GRUB menu entry of the appliance disk image (a name or an index)
+func (a *ApplianceConfig) GetGrubDefaultEntry() string {
+	if entry := swag.StringValue(a.Config.Boot.Grub.DefaultEntry); entry != "" {
+		return entry
+	}
+	return a.GetGrubMenuEntryName()
+}
+
+// GetBuildSeed returns the seed used for deriving the values that are otherwise random
+func (a *ApplianceConfig) GetBuildSeed() string {
+	return swag.StringValue(a.Config.BuildSeed)
+}
+
+// GetDataImageFileName returns the file name of the data partition image
+func (a *ApplianceConfig) GetDataImageFileName() string {
+	switch a.GetDataPartitionFormat() {
+	case consts.DataPartitionFormatSquashfs:
+		return consts.DataSquashfsFileName
+	case consts.DataPartitionFormatErofs:
+		return consts.DataErofsFileName
+	default:
+		return consts.DataIsoFileName
+	}
+}
+
 func GetReleaseArchitectureByCPU(arch string) string {
 	switch arch {
 	case CpuArchitectureX86:
@@ -372,9 +403,12 @@ func (a *ApplianceConfig) GetRelease() (string, string, error) {
 
 	if a.Config.OcpRelease.URL == nil {
 		graphConfig := graph.GraphConfig{
-			Arch:    GetReleaseArchitectureByCPU(*a.Config.OcpRelease.CpuArchitecture),
-			Version: a.Config.OcpRelease.Version,
-			Channel: a.Config.OcpRelease.Channel,
+			Arch:                 GetReleaseArchitectureByCPU(*a.Config.OcpRelease.CpuArchitecture),
+			Version:              a.Config.OcpRelease.Version,
+			Channel:              a.Config.OcpRelease.Channel,
+			CincinnatiAddress:    a.Config.OcpRelease.GraphURL,
+			GraphFile:            a.Config.OcpRelease.GraphFile,
+			AllowVersionFallback: swag.BoolValue(a.Config.OcpRelease.AllowVersionFallback),
 		}
 
 		g := graph.NewGraph(graphConfig)
@@ -387,7 +421,7 @@ func (a *ApplianceConfig) GetRelease() (string, string, error) {
 		releaseVersion, err = executer.NewExecuter().Execute(cmd)
 		if err != nil {
 			logrus.Debugf("Error executing command: %s, error: %v", cmd, err)
-			return "", "", nil
+			return "", "", errors.Wrapf(err, "failed to get the release version of %s", releaseImage)
 		}
 		releaseVersion = strings.Trim(releaseVersion, "'")
 		logrus.Debugf("Release version: %s", releaseVersion)
@@ -398,7 +432,7 @@ func (a *ApplianceConfig) GetRelease() (string, string, error) {
 			cmd := fmt.Sprintf(templateGetDigest, releaseImage)
 			releaseDigest, err = executer.NewExecuter().Execute(cmd)
 			if err != nil {
-				return "", "", nil
+				return "", "", errors.Wrapf(err, "failed to get the release digest of %s", releaseImage)
 			}
 			releaseDigest = strings.Trim(releaseDigest, "'")
 			releaseImage = appendDigest(releaseImage, releaseDigest)
@@ -439,19 +473,19 @@ func (a *ApplianceConfig) validateConfig(f asset.FileFetcher) field.ErrorList {
 		allErrs = append(allErrs, err...)
 	}
 
-	// Validate diskSizeGB
+	// Validate disk.sizeGB
 	if err := a.validateDiskSize(); err != nil {
-		allErrs = append(allErrs, field.Invalid(field.NewPath("diskSizeGB"), a.Config.DiskSizeGB, err.Error()))
+		allErrs = append(allErrs, field.Invalid(field.NewPath("disk.sizeGB"), a.Config.Disk.SizeGB, err.Error()))
 	}
 
-	// Validate imageRegistry
+	// Validate registry
 	if err := a.validateImageRegistry(); err != nil {
 		allErrs = append(allErrs, err...)
 	}
 
-	// Validate pinnedImageSet
+	// Validate cluster.createPinnedImageSets
 	if err := a.validatePinnedImageSet(); err != nil {
-		allErrs = append(allErrs, field.Invalid(field.NewPath("createPinnedImageSets"), a.Config.CreatePinnedImageSets, err.Error()))
+		allErrs = append(allErrs, field.Invalid(field.NewPath("cluster.createPinnedImageSets"), a.Config.Cluster.CreatePinnedImageSets, err.Error()))
 	}
 
 	// Validate pullSecret
@@ -466,39 +500,65 @@ func (a *ApplianceConfig) validateConfig(f asset.FileFetcher) field.ErrorList {
 		}
 	}
 
-	// Validate mirrorPath
+	// Validate sshKeys and users
+	if err := a.validateUsers(); err != nil {
+		allErrs = append(allErrs, err...)
+	}
+
+	// Validate mirror.path
 	if err := a.validateMirrorPath(); err != nil {
 		allErrs = append(allErrs, err...)
 	}
 
+	// Validate disk.dataPartitionFormat
+	if err := a.validateDataPartitionFormat(); err != nil {
+		allErrs = append(allErrs, err...)
+	}
+
+	// Validate boot
+	if err := a.validateBoot(); err != nil {
+		allErrs = append(allErrs, err...)
+	}
+
+	// Validate the installation of the operators
+	if err := a.validateOperatorsInstall(); err != nil {
+		allErrs = append(allErrs, err...)
+	}
+
+	// Validate cluster.catalogSourceNames
+	if err := a.validateCatalogSourceNames(); err != nil {
+		allErrs = append(allErrs, err...)
+	}
+
+	// Validate mirror.helm
+	if err := a.validateHelm(f); err != nil {
+		allErrs = append(allErrs, err...)
+	}
+
 	return allErrs
 }
 
 func (a *ApplianceConfig) validateImageRegistry() field.ErrorList {
 	allErrs := field.ErrorList{}
 
-	if a.Config.ImageRegistry == nil {
-		return nil
-	}
-
-	if a.Config.ImageRegistry.URI != nil {
-		uri := swag.StringValue(a.Config.ImageRegistry.URI)
+	if a.Config.Registry.URI != nil {
+		uri := swag.StringValue(a.Config.Registry.URI)
 		if uri != "" { // Building an image internally when the uri is empty
-			cmd := fmt.Sprintf(PodmanPull, swag.StringValue(a.Config.ImageRegistry.URI))
+			cmd := fmt.Sprintf(PodmanPull, swag.StringValue(a.Config.Registry.URI))
 			logrus.Debugf("Running uri validation cmd: %s", cmd)
 			if _, err := executer.NewExecuter().Execute(cmd); err != nil {
-				allErrs = append(allErrs, field.ErrorList{field.Invalid(field.NewPath("imageRegistry.uri"),
-					swag.StringValue(a.Config.ImageRegistry.URI),
+				allErrs = append(allErrs, field.ErrorList{field.Invalid(field.NewPath("registry.uri"),
+					swag.StringValue(a.Config.Registry.URI),
 					fmt.Sprintf("Invalid uri: %s", err.Error()))}...)
 			}
 		}
 	}
 
-	if a.Config.ImageRegistry.Port != nil {
-		registryPort := swag.IntValue(a.Config.ImageRegistry.Port)
+	if a.Config.Registry.Port != nil {
+		registryPort := swag.IntValue(a.Config.Registry.Port)
 		if registryPort < RegistryMinPort || registryPort > RegistryMaxPort {
-			allErrs = append(allErrs, field.ErrorList{field.Invalid(field.NewPath("imageRegistry.port"),
-				swag.IntValue(a.Config.ImageRegistry.Port),
+			allErrs = append(allErrs, field.ErrorList{field.Invalid(field.NewPath("registry.port"),
+				swag.IntValue(a.Config.Registry.Port),
 				fmt.Sprintf("registryPort must be between %d and %d", RegistryMinPort, RegistryMaxPort))}...)
 		}
 	}
@@ -514,7 +574,7 @@ func (a *ApplianceConfig) validateApiVersion() field.ErrorList {
 	default:
 		return field.ErrorList{field.Invalid(field.NewPath("apiVersion"),
 			a.Config.APIVersion,
-			fmt.Sprintf("apiVersion must be %q", types.ApplianceConfigApiVersion))}
+			fmt.Sprintf("apiVersion must be %q (or the deprecated %q)", types.ApplianceConfigApiVersion, types.ApplianceConfigV1Beta1ApiVersion))}
 	}
 	return nil
 }
@@ -556,6 +616,27 @@ func (a *ApplianceConfig) validateOcpRelease() field.ErrorList {
 		a.Config.OcpRelease.Channel = &channel
 	}
 
+	// Validate ocpRelease.graphURL and ocpRelease.graphFile
+	if a.Config.OcpRelease.GraphURL != nil && a.Config.OcpRelease.GraphFile != nil {
+		allErrs = append(allErrs, field.ErrorList{field.Invalid(field.NewPath("ocpRelease.graphFile"),
+			swag.StringValue(a.Config.OcpRelease.GraphFile),
+			"graphFile and graphURL are mutually exclusive")}...)
+	}
+	if a.Config.OcpRelease.GraphURL != nil {
+		if u, err := url.Parse(swag.StringValue(a.Config.OcpRelease.GraphURL)); err != nil || u.Scheme == "" || u.Host == "" {
+			allErrs = append(allErrs, field.ErrorList{field.Invalid(field.NewPath("ocpRelease.graphURL"),
+				swag.StringValue(a.Config.OcpRelease.GraphURL),
+				"graphURL must be an absolute URL (e.g. https://osus.example.com/api/upgrades_info/graph)")}...)
+		}
+	}
+	if a.Config.OcpRelease.GraphFile != nil {
+		if _, err := os.Stat(swag.StringValue(a.Config.OcpRelease.GraphFile)); err != nil {
+			allErrs = append(allErrs, field.ErrorList{field.Invalid(field.NewPath("ocpRelease.graphFile"),
+				swag.StringValue(a.Config.OcpRelease.GraphFile),
+				fmt.Sprintf("failed to access graph file: %v", err))}...)
+		}
+	}
+
 	// Validate ocpRelease.cpuArchitecture
 	if swag.StringValue(a.Config.OcpRelease.CpuArchitecture) != "" {
 		switch *a.Config.OcpRelease.CpuArchitecture {
@@ -573,17 +654,17 @@ func (a *ApplianceConfig) validateOcpRelease() field.ErrorList {
 }
 
 func (a *ApplianceConfig) validateDiskSize() error {
-	if a.Config.DiskSizeGB == nil {
+	if a.Config.Disk.SizeGB == nil {
 		return nil
 	}
-	if *a.Config.DiskSizeGB < MinDiskSize {
-		return fmt.Errorf("diskSizeGB must be at least %d GiB", MinDiskSize)
+	if *a.Config.Disk.SizeGB < MinDiskSize {
+		return fmt.Errorf("disk.sizeGB must be at least %d GiB", MinDiskSize)
 	}
 	return nil
 }
 
 func (a *ApplianceConfig) validatePinnedImageSet() error {
-	if !swag.BoolValue(a.Config.CreatePinnedImageSets) {
+	if !swag.BoolValue(a.Config.Cluster.CreatePinnedImageSets) {
 		return nil
 	}
 	minOcpVer, _ := version.NewVersion(consts.MinOcpVersionForPinnedImageSet)
@@ -594,30 +675,100 @@ func (a *ApplianceConfig) validatePinnedImageSet() error {
 	return nil
 }
 
+func (a *ApplianceConfig) validateUsers() field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if a.Config.SshKeys != nil {
+		for i, sshKey := range *a.Config.SshKeys {
+			if err := validate.SSHPublicKey(sshKey); err != nil {
+				allErrs = append(allErrs, field.Invalid(field.NewPath("sshKeys").Index(i), sshKey, err.Error()))
+			}
+		}
+	}
+
+	if a.Config.Users == nil {
+		return allErrs
+	}
+	names := map[string]bool{}
+	for i, user := range *a.Config.Users {
+		userPath := field.NewPath("users").Index(i)
+		switch {
+		case user.Name == coreUserName:
+			allErrs = append(allErrs, field.Invalid(userPath.Child("name"), user.Name,
+				"user 'core' is configured by userCorePass and sshKeys"))
+		case !userNameRegex.MatchString(user.Name):
+			allErrs = append(allErrs, field.Invalid(userPath.Child("name"), user.Name, "invalid user name"))
+		case names[user.Name]:
+			allErrs = append(allErrs, field.Duplicate(userPath.Child("name"), user.Name))
+		}
+		names[user.Name] = true
+
+		for j, group := range user.Groups {
+			if !userNameRegex.MatchString(group) {
+				allErrs = append(allErrs, field.Invalid(userPath.Child("groups").Index(j), group, "invalid group name"))
+			}
+		}
+		for j, sshKey := range user.SshKeys {
+			if err := validate.SSHPublicKey(sshKey); err != nil {
+				allErrs = append(allErrs, field.Invalid(userPath.Child("sshKeys").Index(j), sshKey, err.Error()))
+			}
+		}
+		if user.PasswordHash != nil && !passwordHashRegex.MatchString(*user.PasswordHash) {
+			allErrs = append(allErrs, field.Invalid(userPath.Child("passwordHash"), redact.Value,
+				"should be a crypt(3) password hash (e.g. generated by 'mkpasswd --method=bcrypt')"))
+		}
+		if len(user.SshKeys) == 0 && user.PasswordHash == nil {
+			allErrs = append(allErrs, field.Required(userPath, "either sshKeys or passwordHash should be specified"))
+		}
+	}
+
+	return allErrs
+}
+
+// GetSshKeys returns the public SSH keys of user 'core' (sshKey and sshKeys)
+func (a *ApplianceConfig) GetSshKeys() []string {
+	sshKeys := []string{}
+	if a.Config.SshKey != nil {
+		sshKeys = append(sshKeys, *a.Config.SshKey)
+	}
+	if a.Config.SshKeys != nil {
+		sshKeys = append(sshKeys, *a.Config.SshKeys...)
+	}
+	return sshKeys
+}
+
+// GetUsers returns the additional users
+func (a *ApplianceConfig) GetUsers() []types.User {
+	if a.Config.Users == nil {
+		return nil
+	}
+	return *a.Config.Users
+}
+
 func (a *ApplianceConfig) validateMirrorPath() field.ErrorList {
 	allErrs := field.ErrorList{}
 
-	if a.Config.MirrorPath != nil {
-		mirrorPath := swag.StringValue(a.Config.MirrorPath)
+	if a.Config.Mirror.Path != nil {
+		mirrorPath := swag.StringValue(a.Config.Mirror.Path)
 		if mirrorPath != "" {
 			// Validate mirror path exists and is a directory
 			info, err := os.Stat(mirrorPath)
 			if err != nil {
 				if os.IsNotExist(err) {
-					allErrs = append(allErrs, field.Invalid(field.NewPath("mirrorPath"),
+					allErrs = append(allErrs, field.Invalid(field.NewPath("mirror.path"),
 						mirrorPath, "mirror path does not exist"))
 				} else {
-					allErrs = append(allErrs, field.Invalid(field.NewPath("mirrorPath"),
+					allErrs = append(allErrs, field.Invalid(field.NewPath("mirror.path"),
 						mirrorPath, fmt.Sprintf("failed to access mirror path: %v", err)))
 				}
 			} else if !info.IsDir() {
-				allErrs = append(allErrs, field.Invalid(field.NewPath("mirrorPath"),
+				allErrs = append(allErrs, field.Invalid(field.NewPath("mirror.path"),
 					mirrorPath, "mirror path must be a directory"))
 			} else {
 				// Validate data subdirectory exists
 				dataDir := filepath.Join(mirrorPath, "data")
 				if _, err := os.Stat(dataDir); err != nil {
-					allErrs = append(allErrs, field.Invalid(field.NewPath("mirrorPath"),
+					allErrs = append(allErrs, field.Invalid(field.NewPath("mirror.path"),
 						mirrorPath, "mirror path must contain a 'data' subdirectory (expected oc-mirror workspace structure)"))
 				}
 			}
@@ -629,6 +780,199 @@ func (a *ApplianceConfig) validateMirrorPath() field.ErrorList {
 	return allErrs
 }
 
+func (a *ApplianceConfig) validateDataPartitionFormat() field.ErrorList {
+	if a.Config.Disk.DataPartitionFormat == nil {
+		return nil
+	}
+
+	switch *a.Config.Disk.DataPartitionFormat {
+	case consts.DataPartitionFormatISO9660:
+	case consts.DataPartitionFormatSquashfs:
+	case consts.DataPartitionFormatErofs:
+		if _, err := exec.LookPath(mkfsErofsBinary); err != nil {
+			return field.ErrorList{field.Invalid(field.NewPath("disk.dataPartitionFormat"),
+				*a.Config.Disk.DataPartitionFormat,
+				fmt.Sprintf("%s (erofs-utils) is not available on the build host", mkfsErofsBinary))}
+		}
+	default:
+		return field.ErrorList{field.Invalid(field.NewPath("disk.dataPartitionFormat"),
+			*a.Config.Disk.DataPartitionFormat,
+			"Unsupported data partition format (supported formats: iso9660|squashfs|erofs)")}
+	}
+	return nil
+}
+
+func (a *ApplianceConfig) validateBoot() field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for i, karg := range a.GetKernelArguments() {
+		if karg == "" || strings.ContainsAny(karg, " \t\n") {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("boot.kernelArguments").Index(i),
+				karg, "A kernel argument must be non-empty and without whitespaces"))
+		}
+	}
+
+	if a.Config.Boot.Console != nil {
+		console := *a.Config.Boot.Console
+		switch {
+		case console == "" || strings.ContainsAny(console, " \t\n"):
+			allErrs = append(allErrs, field.Invalid(field.NewPath("boot.console"),
+				console, "The console must be non-empty and without whitespaces"))
+		case strings.HasPrefix(console, "console="):
+			allErrs = append(allErrs, field.Invalid(field.NewPath("boot.console"),
+				console, "The console should be specified without the 'console=' prefix (e.g. ttyS0,115200n8)"))
+		}
+	}
+
+	grub := a.Config.Boot.Grub
+	if grub.Timeout != nil && *grub.Timeout < 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("boot.grub.timeout"),
+			*grub.Timeout, "The timeout must be a non-negative number of seconds"))
+	}
+	for _, entry := range []struct {
+		path  string
+		value *string
+	}{
+		{"boot.grub.defaultEntry", grub.DefaultEntry},
+		{"boot.grub.menuEntryName", grub.MenuEntryName},
+		{"boot.grub.recoveryMenuEntryName", grub.RecoveryMenuEntryName},
+	} {
+		// The names are single-quoted in the GRUB config
+		if entry.value != nil && (*entry.value == "" || strings.ContainsAny(*entry.value, "'\n")) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath(entry.path),
+				*entry.value, "A menu entry must be non-empty and without single quotes or newlines"))
+		}
+	}
+
+	return allErrs
+}
+
+func (a *ApplianceConfig) validateOperatorsInstall() field.ErrorList {
+	allErrs := field.ErrorList{}
+	if a.Config.Mirror.Operators == nil {
+		return allErrs
+	}
+
+	for i, operator := range *a.Config.Mirror.Operators {
+		for j, p := range operator.Packages {
+			path := field.NewPath("mirror.operators").Index(i).Child("packages").Index(j)
+			if p.InstallPlanApproval != nil &&
+				!funk.ContainsString([]string{InstallPlanApprovalAutomatic, InstallPlanApprovalManual}, *p.InstallPlanApproval) {
+				allErrs = append(allErrs, field.NotSupported(path.Child("installPlanApproval"),
+					*p.InstallPlanApproval, []string{InstallPlanApprovalAutomatic, InstallPlanApprovalManual}))
+			}
+			if p.Namespace != nil {
+				for _, msg := range validation.IsDNS1123Label(*p.Namespace) {
+					allErrs = append(allErrs, field.Invalid(path.Child("namespace"), *p.Namespace, msg))
+				}
+			}
+			if p.TargetNamespaces != nil {
+				if swag.StringValue(p.Namespace) == consts.GlobalOperatorsNamespace {
+					allErrs = append(allErrs, field.Invalid(path.Child("targetNamespaces"), *p.TargetNamespaces,
+						fmt.Sprintf("not supported for namespace %s (i.e. its existing OperatorGroup is used)", consts.GlobalOperatorsNamespace)))
+				}
+				for k, namespace := range *p.TargetNamespaces {
+					for _, msg := range validation.IsDNS1123Label(namespace) {
+						allErrs = append(allErrs, field.Invalid(path.Child("targetNamespaces").Index(k), namespace, msg))
+					}
+				}
+			}
+			if !swag.BoolValue(p.Install) && (p.Namespace != nil || p.TargetNamespaces != nil || p.InstallPlanApproval != nil) {
+				logrus.Warnf("%s: namespace, targetNamespaces and installPlanApproval are ignored without 'install: true'", path)
+			}
+		}
+	}
+
+	return allErrs
+}
+
+func (a *ApplianceConfig) validateCatalogSourceNames() field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	var catalogs []string
+	if a.Config.Mirror.Operators != nil {
+		for _, operator := range *a.Config.Mirror.Operators {
+			catalogs = append(catalogs, operator.Catalog)
+		}
+	}
+
+	var keys []string
+	for catalog := range a.Config.Cluster.CatalogSourceNames {
+		keys = append(keys, catalog)
+	}
+	sort.Strings(keys)
+
+	names := map[string]bool{}
+	for _, catalog := range keys {
+		cs := a.Config.Cluster.CatalogSourceNames[catalog]
+		path := field.NewPath("cluster", "catalogSourceNames").Key(catalog)
+		if !funk.ContainsString(catalogs, catalog) {
+			allErrs = append(allErrs, field.Invalid(path, catalog, "catalog is not specified in mirror.operators"))
+		}
+		if cs.Name != nil {
+			for _, msg := range validation.IsDNS1123Subdomain(*cs.Name) {
+				allErrs = append(allErrs, field.Invalid(path.Child("name"), *cs.Name, msg))
+			}
+			if names[*cs.Name] {
+				allErrs = append(allErrs, field.Duplicate(path.Child("name"), *cs.Name))
+			}
+			names[*cs.Name] = true
+		}
+		if cs.PollInterval != nil {
+			if _, err := time.ParseDuration(*cs.PollInterval); err != nil {
+				allErrs = append(allErrs, field.Invalid(path.Child("pollInterval"), *cs.PollInterval, err.Error()))
+			}
+		}
+	}
+
+	return allErrs
+}
+
+func (a *ApplianceConfig) validateHelm(f asset.FileFetcher) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if a.Config.Mirror.Helm == nil {
+		return allErrs
+	}
+
+	for i, repo := range a.Config.Mirror.Helm.Repositories {
+		path := field.NewPath("mirror", "helm", "repositories").Index(i)
+		if repo.Name == "" {
+			allErrs = append(allErrs, field.Required(path.Child("name"), "repository name is required"))
+		}
+		if u, err := url.Parse(repo.URL); err != nil || !u.IsAbs() {
+			allErrs = append(allErrs, field.Invalid(path.Child("url"), repo.URL, "repository url must be an absolute URL"))
+		}
+		for j, chart := range repo.Charts {
+			if chart.Name == "" {
+				allErrs = append(allErrs, field.Required(path.Child("charts").Index(j).Child("name"), "chart name is required"))
+			}
+		}
+	}
+
+	for i, chart := range a.Config.Mirror.Helm.Local {
+		path := field.NewPath("mirror", "helm", "local").Index(i)
+		if chart.Name == "" {
+			allErrs = append(allErrs, field.Required(path.Child("name"), "chart name is required"))
+		}
+		if chart.Path == "" {
+			allErrs = append(allErrs, field.Required(path.Child("path"), "chart path is required"))
+			continue
+		}
+		// A relative path is relative to the assets directory
+		var err error
+		if filepath.IsAbs(chart.Path) {
+			_, err = os.Stat(chart.Path)
+		} else {
+			_, err = f.FetchByName(chart.Path)
+		}
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("path"), chart.Path, "chart path does not exist"))
+		}
+	}
+
+	return allErrs
+}
+
 func (a *ApplianceConfig) storePullSecret() error {
 	// Get home dir (~)
 	homeDir, err := os.UserHomeDir()
@@ -649,3 +993,46 @@ func (a *ApplianceConfig) storePullSecret() error {
 
 	return nil
 }
+
+// MigrateApplianceConfig converts the content of an appliance-config.yaml file in a previous
+// apiVersion to the current apiVersion.
+// Note: the comments of the original file aren't preserved.
+func MigrateApplianceConfig(data []byte) ([]byte, error) {
+	config, apiVersion, err := ParseApplianceConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	if apiVersion != types.ApplianceConfigV1Beta1ApiVersion {
+		return nil, errors.Errorf("can't migrate apiVersion %q, only %q is supported", apiVersion, types.ApplianceConfigV1Beta1ApiVersion)
+	}
+	return marshalApplianceConfig(config)
+}
+
+// marshalApplianceConfig returns the config as YAML (in the current apiVersion)
+func marshalApplianceConfig(config *types.ApplianceConfig) ([]byte, error) {
+	// Drop the empty fields (i.e. metadata and unset sections) from the output
+	configJson, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	if err = json.Unmarshal(configJson, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "apiVersion")
+	delete(fields, "kind")
+	if metadata, ok := fields["metadata"].(map[string]interface{}); ok && len(metadata) == 1 && metadata["creationTimestamp"] == nil {
+		delete(fields, "metadata")
+	}
+	for key, value := range fields {
+		if section, ok := value.(map[string]interface{}); ok && len(section) == 0 {
+			delete(fields, key)
+		}
+	}
+
+	configYaml, err := yaml.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("apiVersion: %s\nkind: %s\n%s", types.ApplianceConfigApiVersion, applianceConfigKind, configYaml)), nil
+}
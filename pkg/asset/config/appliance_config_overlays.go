@@ -0,0 +1,140 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/openshift/installer/pkg/asset"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ApplianceConfigOverlaysDir contains config files that are merged (in lexical order)
+	// on top of appliance-config.yaml (e.g. site specific settings).
+	ApplianceConfigOverlaysDir = "appliance-config.d"
+)
+
+// ApplianceConfigOverlaysPatterns are the file patterns of the config overlays
+var ApplianceConfigOverlaysPatterns = []string{
+	filepath.Join(ApplianceConfigOverlaysDir, "*.yaml"),
+	filepath.Join(ApplianceConfigOverlaysDir, "*.yml"),
+}
+
+// fetchOverlays returns the config overlays from the assets directory (sorted by name)
+func fetchOverlays(f asset.FileFetcher) ([]*asset.File, error) {
+	overlays := []*asset.File{}
+	for _, pattern := range ApplianceConfigOverlaysPatterns {
+		files, err := f.FetchByPattern(pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load %s files", pattern)
+		}
+		overlays = append(overlays, files...)
+	}
+	asset.SortFiles(overlays)
+	return overlays, nil
+}
+
+// ReadOverlays returns the config overlays of the assets directory (sorted by name),
+// e.g. for rendering the effective config outside of the assets store.
+func ReadOverlays(dir string) ([]*asset.File, error) {
+	return fetchOverlays(&dirFileFetcher{directory: dir})
+}
+
+// dirFileFetcher fetches the files of a directory (same as the assets store file fetcher)
+type dirFileFetcher struct {
+	directory string
+}
+
+func (f *dirFileFetcher) FetchByName(name string) (*asset.File, error) {
+	data, err := os.ReadFile(filepath.Join(f.directory, name))
+	if err != nil {
+		return nil, err
+	}
+	return &asset.File{Filename: name, Data: data}, nil
+}
+
+func (f *dirFileFetcher) FetchByPattern(pattern string) ([]*asset.File, error) {
+	matches, err := filepath.Glob(filepath.Join(f.directory, pattern))
+	if err != nil {
+		return nil, err
+	}
+	files := make([]*asset.File, 0, len(matches))
+	for _, path := range matches {
+		filename, err := filepath.Rel(f.directory, path)
+		if err != nil {
+			return nil, err
+		}
+		file, err := f.FetchByName(filename)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// MergeApplianceConfig merges the overlays (in order) on top of the base appliance-config.yaml,
+// using JSON merge patch semantics (RFC 7386): objects are merged recursively, any other value
+// (including lists) is replaced, and a null value removes the field.
+// The overlays must use the same apiVersion (and kind) as the base config, if specified.
+func MergeApplianceConfig(base *asset.File, overlays []*asset.File) ([]byte, error) {
+	baseLayer, err := parseConfigLayer(base)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := json.Marshal(baseLayer)
+	if err != nil {
+		return nil, err
+	}
+	for _, overlay := range overlays {
+		patch, err := parseConfigLayer(overlay)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range []string{"apiVersion", "kind"} {
+			if value, ok := patch[key]; ok && !reflect.DeepEqual(value, baseLayer[key]) {
+				return nil, errors.Errorf("%s %s (%v) doesn't match the %s of %s (%v)",
+					overlay.Filename, key, value, key, base.Filename, baseLayer[key])
+			}
+		}
+		patchData, err := json.Marshal(patch)
+		if err != nil {
+			return nil, err
+		}
+		if merged, err = jsonpatch.MergePatch(merged, patchData); err != nil {
+			return nil, errors.Wrapf(err, "failed to merge %s", overlay.Filename)
+		}
+	}
+	return merged, nil
+}
+
+func parseConfigLayer(file *asset.File) (map[string]interface{}, error) {
+	layer := map[string]interface{}{}
+	data, err := yaml.YAMLToJSON(file.Data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't parse %s", file.Filename)
+	}
+	if string(data) == "null" {
+		// Empty file
+		return layer, nil
+	}
+	if err = json.Unmarshal(data, &layer); err != nil {
+		return nil, errors.Wrapf(err, "%s should contain config fields", file.Filename)
+	}
+	return layer, nil
+}
+
+// RenderApplianceConfig returns the effective config (in the current apiVersion)
+// of a merged appliance-config.yaml, with the secrets redacted.
+func RenderApplianceConfig(data []byte) ([]byte, error) {
+	config, _, err := ParseApplianceConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	redactSecrets(config)
+	return marshalApplianceConfig(config)
+}
@@ -0,0 +1,745 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/openshift/appliance/pkg/consts"
+	"github.com/openshift/appliance/pkg/graph"
+	"github.com/openshift/appliance/pkg/types"
+	"github.com/pkg/errors"
+	"github.com/thoas/go-funk"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+	schemaTypeObject  = "object"
+	schemaTypeArray   = "array"
+	schemaTypeString  = "string"
+	schemaTypeInteger = "integer"
+	schemaTypeBoolean = "boolean"
+
+	// templateWidth is the maximal width of the documentation lines in the template
+	templateWidth  = 100
+	templateHeader = `#
+# Note: This is a sample ApplianceConfig file showing
+# which fields are available to aid you in creating your
+# own appliance-config.yaml file.
+# See also: openshift-appliance explain <field.path>
+#
+`
+)
+
+// kebabCaseRegex matches the word boundaries of a camelCase field name
+var kebabCaseRegex = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+// JSONSchema is the subset of JSON Schema used for describing appliance-config.yaml
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Default              interface{}            `json:"default,omitempty"`
+	Minimum              *int                   `json:"minimum,omitempty"`
+	Maximum              *int                   `json:"maximum,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+
+	// fields is the properties names in declaration order (for explain)
+	fields []string
+	// example is the value of the field in the generated template
+	example interface{}
+	// uncommented is set for optional fields that aren't commented out in the generated template
+	uncommented bool
+}
+
+// fieldDoc documents a field of appliance-config.yaml (keyed by its path)
+type fieldDoc struct {
+	description string
+	defaultVal  interface{}
+	enum        []interface{}
+	minimum     *int
+	maximum     *int
+	required    bool
+	// example is the value in the generated template (the default value, or a placeholder, otherwise).
+	// For arrays, an example item, and for maps, an example key.
+	example interface{}
+	// uncommented fields are set in the generated template even though they're optional
+	uncommented bool
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+var includeBundleDocs = map[string]fieldDoc{
+	"minVersion": {description: "MinVersion to include, plus all versions in the upgrade graph to the MaxVersion."},
+	"maxVersion": {description: "MaxVersion to include as the channel head version."},
+	"minBundle": {description: "MinBundle to include, plus all bundles in the upgrade graph to the channel head. " +
+		"Set this field only if the named bundle has no semantic version metadata."},
+}
+
+// applianceConfigDocs holds the documentation of each field in types.ApplianceConfig.
+// Keep in sync with the Go types (enforced by ApplianceConfigSchema).
+var applianceConfigDocs = map[string]fieldDoc{
+	"apiVersion": {
+		description: fmt.Sprintf("The configuration version (%s is deprecated, see 'openshift-appliance config migrate').",
+			types.ApplianceConfigV1Beta1ApiVersion),
+		enum:     []interface{}{types.ApplianceConfigApiVersion},
+		required: true,
+	},
+	"kind": {
+		description: "The configuration kind.",
+		enum:        []interface{}{applianceConfigKind},
+		required:    true,
+	},
+	"metadata": {description: "Standard object metadata (not used by the appliance)."},
+
+	"ocpRelease": {description: "The OCP release to include in the appliance."},
+	"ocpRelease.version": {
+		description: fmt.Sprintf("OCP release version in major.minor or major.minor.patch format "+
+			"(in case of major.minor, latest patch version will be used). Supported versions: %s-%s.",
+			consts.MinOcpVersion, consts.MaxOcpVersion),
+		example: "ocp-release-version",
+	},
+	"ocpRelease.channel": {
+		description: "OCP release update channel.",
+		defaultVal:  string(graph.ReleaseChannelStable),
+		enum: []interface{}{string(graph.ReleaseChannelStable), string(graph.ReleaseChannelFast),
+			string(graph.ReleaseChannelEUS), string(graph.ReleaseChannelCandidate)},
+	},
+	"ocpRelease.cpuArchitecture": {
+		description: "OCP release CPU architecture.",
+		defaultVal:  CpuArchitectureX86,
+		enum:        []interface{}{CpuArchitectureX86, CpuArchitectureAARCH64, CpuArchitecturePPC64le},
+	},
+	"ocpRelease.url": {description: "OCP release URL (use instead of channel/architecture)."},
+	"ocpRelease.graphURL": {
+		description: "Cincinnati graph URL for resolving the release (e.g. an OpenShift Update Service instance).",
+		defaultVal:  graph.CincinnatiAddress,
+	},
+	"ocpRelease.graphFile": {
+		description: "Path to a saved graph JSON file for resolving the release offline (use instead of graphURL), " +
+			`e.g. saved by 'curl -H "Accept: application/json" "<graphURL>?channel=stable-<major.minor>&arch=amd64" > graph.json'.`,
+		example: "/path/to/graph.json",
+	},
+	"ocpRelease.allowVersionFallback": {
+		description: "Use the latest supported version when the specified version is not yet available (otherwise, the build fails).",
+		defaultVal:  consts.AllowVersionFallback,
+		uncommented: true,
+	},
+
+	"pullSecret": {
+		description: "PullSecret is required for mirroring the OCP release payload (or use pullSecretFile). " +
+			"Can be obtained from: https://console.redhat.com/openshift/install/pull-secret. Supports ${ENV_VAR} interpolation.",
+		uncommented: true,
+	},
+	"pullSecretFile": {
+		description: "Path to a file containing the pull secret (use instead of pullSecret). " +
+			"A relative path is relative to the assets directory. Supports ${ENV_VAR} interpolation.",
+		example: "/path/to/pull-secret.json",
+	},
+	"sshKey": {
+		description: "Public SSH key for accessing the appliance during the bootstrap phase. Supports ${ENV_VAR} interpolation.",
+	},
+	"sshKeyFile": {
+		description: "Path to a file containing the public SSH key (use instead of sshKey). " +
+			"A relative path is relative to the assets directory. Supports ${ENV_VAR} interpolation.",
+		example: "/path/to/id_rsa.pub",
+	},
+	"userCorePass": {
+		description: "Password of user 'core' for connecting from console. Supports ${ENV_VAR} interpolation.",
+	},
+	"userCorePassHash": {
+		description: "Password hash of user 'core' (use instead of userCorePass), " +
+			"e.g. generated by 'mkpasswd --method=bcrypt'. Supports ${ENV_VAR} interpolation.",
+	},
+	"sshKeys": {
+		description: "Additional public SSH keys of user 'core' (i.e. in addition to sshKey). " +
+			"Added also to the cluster nodes post installation.",
+		example: "ssh-key",
+	},
+	"users": {
+		description: "Additional users to create in the appliance and in the cluster nodes post installation " +
+			"(e.g. a break-glass user). Either sshKeys or passwordHash should be specified for each user.",
+	},
+	"users.name":         {description: "Name of the user.", example: "user-name"},
+	"users.groups":       {description: "Supplementary groups of the user (e.g. wheel).", example: "wheel"},
+	"users.sshKeys":      {description: "Public SSH keys of the user.", example: "ssh-key"},
+	"users.passwordHash": {description: "Password hash of the user, e.g. generated by 'mkpasswd --method=bcrypt'."},
+	"buildSeed": {
+		description: "Seed for deriving the values that are otherwise random on each build " +
+			"(i.e. the partitions GUIDs, the MBR disk signatures and the filesystems UUIDs). " +
+			"Use with the SOURCE_DATE_EPOCH env var for reproducible builds.",
+	},
+
+	"registry": {description: "Local image registry details (used when building the appliance and post cluster installation)."},
+	"registry.uri": {
+		description: "The URI for the registry image (e.g. quay.io/libpod/registry:2.8). Building an image internally by default.",
+		defaultVal:  "",
+	},
+	"registry.port": {
+		description: "The image registry container TCP port to bind.",
+		defaultVal:  consts.RegistryPort,
+		minimum:     intPtr(RegistryMinPort),
+		maximum:     intPtr(RegistryMaxPort),
+	},
+	"registry.useBinary": {
+		description: "Use the registry binary built internally (to avoid nested containers when running the image).",
+		defaultVal:  consts.UseRegistryBinary,
+	},
+	"registry.stopPostInstall": {
+		description: "Stop the local registry post cluster installation. " +
+			"Note that additional images and operators won't be available when stopped.",
+		defaultVal: consts.StopLocalRegistry,
+	},
+	"registry.skipPostInstall": {
+		description: "Skip setting up the local registry post cluster installation.",
+		defaultVal:  consts.SkipLocalRegistry,
+	},
+
+	"mirror": {description: "Images to mirror into the appliance disk image."},
+	"mirror.path": {
+		description: "Path to pre-mirrored images from oc-mirror workspace (containing a 'data' subdirectory). " +
+			"When provided, skips image mirroring and uses the pre-mirrored registry data.",
+		example: "/path/to/mirror/workspace",
+	},
+	"mirror.disableSigstoreRegistries": {
+		description: "Disable sigstore attachments for the listed registry hosts during oc-mirror " +
+			"(required when the source registries don't publish the sigstore attachments, " +
+			"e.g. certified operator bundles on registry.connect.redhat.com).",
+		example: "registry.connect.redhat.com",
+	},
+	"mirror.additionalImages": {description: "Additional images to be included in the appliance disk image."},
+	"mirror.additionalImages.name": {
+		description: "Name of the image (preferably an exact image pin: registry/namespace/name@sha256:<hash>).",
+		example:     "image-url",
+	},
+	"mirror.blockedImages":      {description: "Images to avoid including in the appliance disk image."},
+	"mirror.blockedImages.name": {description: "Name of the image (or a regular expression).", example: "image-url"},
+	"mirror.operators": {
+		description: "Operators to be included in the appliance disk image. " +
+			"See examples in https://github.com/openshift/oc-mirror/blob/main/docs/imageset-config-ref.yaml.",
+	},
+	"mirror.operators.catalog": {
+		description: "Catalog image to mirror (preferably an exact image pin: registry/namespace/name@sha256:<hash>).",
+		example:     "catalog-uri",
+	},
+	"mirror.operators.packages":      {description: "Packages to include (with their dependencies)."},
+	"mirror.operators.packages.name": {description: "Name of package.", example: "package-name"},
+	"mirror.operators.packages.channels": {
+		description: "Channels to include (the full package is included if no channels or versions are specified).",
+	},
+	"mirror.operators.packages.channels.name": {description: "Name of channel.", example: "channel-name"},
+	"mirror.operators.packages.install": {
+		description: "Install the package in the cluster (i.e. generate its Namespace, OperatorGroup and Subscription, " +
+			"subscribed to the first channel, or to the default channel if no channels are specified).",
+		defaultVal: false,
+	},
+	"mirror.operators.packages.namespace": {
+		description: "Namespace to install the package into (default: the package name). " +
+			"For 'openshift-operators', only the Subscription is generated (i.e. AllNamespaces install mode).",
+		example: "namespace-name",
+	},
+	"mirror.operators.packages.targetNamespaces": {
+		description: "Target namespaces of the generated OperatorGroup (default: the package namespace, i.e. OwnNamespace install mode). " +
+			"An empty list selects the AllNamespaces install mode (e.g. for operators that don't support OwnNamespace).",
+		example: "namespace-name",
+	},
+	"mirror.operators.packages.installPlanApproval": {
+		description: "Approval of the Subscription's install plans.",
+		defaultVal:  InstallPlanApprovalAutomatic,
+		enum:        []interface{}{InstallPlanApprovalAutomatic, InstallPlanApprovalManual},
+	},
+	"mirror.helm": {
+		description: "Helm charts to be included in the appliance disk image (with the images they reference). " +
+			"The chart archives are available on the node at /mnt/agentdata/helm-charts.",
+	},
+	"mirror.helm.repositories":                {description: "Helm repositories to mirror the charts from."},
+	"mirror.helm.repositories.name":           {description: "Name of the repository.", example: "repository-name"},
+	"mirror.helm.repositories.url":            {description: "URL of the repository.", example: "repository-url"},
+	"mirror.helm.repositories.charts":         {description: "Charts to mirror from the repository."},
+	"mirror.helm.repositories.charts.name":    {description: "Name of the chart.", example: "chart-name"},
+	"mirror.helm.repositories.charts.version": {description: "Version of the chart (the latest version if not specified).", example: "chart-version"},
+	"mirror.helm.repositories.charts.path":    {description: "Not used for repository charts."},
+	"mirror.helm.repositories.charts.imagePaths": {
+		description: "Additional paths in the chart's values to find images in.",
+	},
+	"mirror.helm.local":         {description: "Local chart archives or directories to mirror."},
+	"mirror.helm.local.name":    {description: "Name of the chart.", example: "chart-name"},
+	"mirror.helm.local.version": {description: "Not used for local charts."},
+	"mirror.helm.local.path": {
+		description: "Path of the chart (an absolute path, or relative to the assets directory).",
+		example:     "chart-path",
+	},
+	"mirror.helm.local.imagePaths": {description: "Additional paths in the chart's values to find images in."},
+
+	"cluster": {description: "Cluster installation settings."},
+	"cluster.enableFips": {
+		description: "Enable FIPS mode for the cluster ('fips' should be enabled also in install-config.yaml).",
+		defaultVal:  consts.EnableFips,
+	},
+	"cluster.enableInteractiveFlow": {
+		description: "Enable the interactive installation flow " +
+			"(to provide cluster configuration through the web UI instead of a config-image).",
+		defaultVal: consts.EnableInteractiveFlow,
+	},
+	"cluster.enableDefaultSources": {
+		description: "Enable all default CatalogSources (on openshift-marketplace namespace). " +
+			"Should be disabled for disconnected environments.",
+		defaultVal: consts.EnableDefaultSources,
+	},
+	"cluster.useDefaultSourceNames": {
+		description: "Rename CatalogSource names generated by oc-mirror to the default naming " +
+			"(e.g. 'redhat-operators' instead of 'cs-redhat-operator-index-v4-19').",
+		defaultVal: consts.UseDefaultSourceNames,
+	},
+	"cluster.catalogSourceNames": {
+		description: "Configure the CatalogSources generated by oc-mirror, keyed by the catalog image " +
+			"(as specified in mirror.operators). Overrides the naming of 'useDefaultSourceNames'.",
+		example: "registry.example.com/my-org/my-operator-index:v1",
+	},
+	"cluster.catalogSourceNames.name": {
+		description: "Name of the CatalogSource.",
+		example:     "my-operators",
+	},
+	"cluster.catalogSourceNames.displayName": {
+		description: "Display name of the CatalogSource.",
+		example:     "My Operators",
+	},
+	"cluster.catalogSourceNames.priority": {
+		description: "Priority of the CatalogSource (a higher priority is preferred when resolving dependencies).",
+		example:     10,
+	},
+	"cluster.catalogSourceNames.pollInterval": {
+		description: "Interval for polling the catalog image for updates.",
+		example:     "30m",
+	},
+	"cluster.createPinnedImageSets": {
+		description: fmt.Sprintf("Create PinnedImageSets for both the master and worker MCPs, "+
+			"including all the images in the appliance disk image (requires openshift version %s or above). "+
+			"Sets the cluster to tech preview (i.e. the cluster cannot be upgraded).",
+			consts.MinOcpVersionForPinnedImageSet),
+		defaultVal: consts.CreatePinnedImageSets,
+	},
+
+	"disk": {description: "Appliance disk image settings."},
+	"disk.sizeGB": {
+		description: "Virtual size of the appliance disk image in GiB. " +
+			"If not specified, the disk image should be resized when cloning to a device (e.g. using virt-resize).",
+		minimum: intPtr(MinDiskSize),
+	},
+	"disk.dataPartitionFormat": {
+		description: "Filesystem format of the data partition (agentdata) holding the registry images " +
+			"(squashfs and erofs are compressed). erofs requires mkfs.erofs (erofs-utils) on the build host.",
+		defaultVal: consts.DataPartitionFormat,
+		enum: []interface{}{consts.DataPartitionFormatISO9660, consts.DataPartitionFormatSquashfs,
+			consts.DataPartitionFormatErofs},
+	},
+
+	"boot": {description: "Kernel command line settings of the appliance boot paths and the cluster nodes."},
+	"boot.kernelArguments": {
+		description: "Additional kernel arguments of the appliance (disk image, recovery, live ISO and deployment ISO) " +
+			"and of the cluster nodes (applied by a MachineConfig).",
+	},
+	"boot.console": {
+		description: "Primary kernel console (e.g. 'ttyS0,115200n8' for a serial console), " +
+			"in addition to the 'tty0' console.",
+		example: "console",
+	},
+	"boot.grub": {description: "GRUB menu settings."},
+	"boot.grub.timeout": {
+		description: "Seconds to wait before booting the default entry (0 for unattended boots).",
+		defaultVal:  consts.GrubTimeout,
+		minimum:     intPtr(0),
+	},
+	"boot.grub.defaultEntry": {
+		description: "Default entry of the appliance disk image (a menu entry name or index). " +
+			"Default: the appliance entry (menuEntryName).",
+	},
+	"boot.grub.menuEntryName": {
+		description: "Name of the appliance entry (in the appliance disk image).",
+		defaultVal:  consts.GrubMenuEntryName,
+	},
+	"boot.grub.recoveryMenuEntryName": {
+		description: "Name of the recovery entry (in the cluster nodes, for reinstalling the cluster).",
+		defaultVal:  consts.GrubMenuEntryNameRecovery,
+	},
+	"boot.grub.recoveryPasswordHash": {
+		description: fmt.Sprintf("Password hash of the GRUB superuser ('%s'), required for booting the recovery entry "+
+			"(and for editing the GRUB menu), e.g. generated by 'grub2-mkpasswd-pbkdf2'.", consts.GrubSuperuser),
+		example: "grub.pbkdf2.sha512.10000.<salt>.<hash>",
+	},
+}
+
+func init() {
+	// IncludeBundle is inlined in both packages and channels
+	for _, prefix := range []string{"mirror.operators.packages", "mirror.operators.packages.channels"} {
+		for name, doc := range includeBundleDocs {
+			applianceConfigDocs[fmt.Sprintf("%s.%s", prefix, name)] = doc
+		}
+	}
+}
+
+// ApplianceConfigSchema returns the JSON Schema of appliance-config.yaml,
+// generated from types.ApplianceConfig.
+func ApplianceConfigSchema() (*JSONSchema, error) {
+	schema, err := schemaForValue(reflect.TypeOf(types.ApplianceConfig{}), "")
+	if err != nil {
+		return nil, err
+	}
+	schema.Schema = jsonSchemaDraft
+	schema.Title = applianceConfigKind
+	schema.Description = fmt.Sprintf("The configuration file of the appliance (%s).", ApplianceConfigFilename)
+	return schema, nil
+}
+
+func schemaForType(t reflect.Type, path string) (*JSONSchema, error) {
+	doc, ok := applianceConfigDocs[path]
+	if !ok {
+		return nil, errors.Errorf("missing documentation for field %s", path)
+	}
+	schema, err := schemaForValue(t, path)
+	if err != nil {
+		return nil, err
+	}
+	schema.Description = doc.description
+	schema.Default = doc.defaultVal
+	schema.Enum = doc.enum
+	schema.Minimum = doc.minimum
+	schema.Maximum = doc.maximum
+	schema.example = doc.example
+	schema.uncommented = doc.uncommented
+	return schema, nil
+}
+
+// schemaForValue returns the schema of a value of type t (the fields of
+// objects, including the items of an array, are documented under path)
+func schemaForValue(t reflect.Type, path string) (*JSONSchema, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	schema := &JSONSchema{}
+	switch {
+	case t == reflect.TypeOf(metav1.ObjectMeta{}):
+		schema.Type = schemaTypeObject
+	case t.Kind() == reflect.Struct:
+		schema.Type = schemaTypeObject
+		schema.AdditionalProperties = new(bool)
+		schema.Properties = map[string]*JSONSchema{}
+		if err := addStructFields(schema, t, path); err != nil {
+			return nil, err
+		}
+	case t.Kind() == reflect.Slice:
+		items, err := schemaForValue(t.Elem(), path)
+		if err != nil {
+			return nil, err
+		}
+		schema.Type = schemaTypeArray
+		schema.Items = items
+	case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+		values, err := schemaForValue(t.Elem(), path)
+		if err != nil {
+			return nil, err
+		}
+		schema.Type = schemaTypeObject
+		schema.AdditionalProperties = values
+	case t.Kind() == reflect.String:
+		schema.Type = schemaTypeString
+	case t.Kind() == reflect.Int:
+		schema.Type = schemaTypeInteger
+	case t.Kind() == reflect.Bool:
+		schema.Type = schemaTypeBoolean
+	default:
+		return nil, errors.Errorf("unsupported type %s for field %s", t.Kind(), path)
+	}
+	return schema, nil
+}
+
+func addStructFields(schema *JSONSchema, t reflect.Type, path string) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		if field.Anonymous && name == "" {
+			// Inlined struct (e.g. TypeMeta)
+			if err := addStructFields(schema, field.Type, path); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" || name == "-" {
+			continue
+		}
+
+		fieldPath := name
+		if path != "" {
+			fieldPath = fmt.Sprintf("%s.%s", path, name)
+		}
+		fieldSchema, err := schemaForType(field.Type, fieldPath)
+		if err != nil {
+			return err
+		}
+		schema.Properties[name] = fieldSchema
+		schema.fields = append(schema.fields, name)
+
+		// Non-pointer fields without omitempty are mandatory
+		optional := field.Type.Kind() == reflect.Ptr || strings.Contains(opts, "omitempty")
+		if !optional || applianceConfigDocs[fieldPath].required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return nil
+}
+
+// elem returns the schema of the array items or the map values (or the schema itself otherwise)
+func (s *JSONSchema) elem() *JSONSchema {
+	if s.Items != nil {
+		return s.Items
+	}
+	if values, ok := s.AdditionalProperties.(*JSONSchema); ok {
+		return values
+	}
+	return s
+}
+
+// Lookup returns the schema of the field in the specified path (e.g. 'registry.port').
+// Array and map fields are traversed through their items/values (e.g. 'mirror.operators.catalog').
+func (s *JSONSchema) Lookup(path string) (*JSONSchema, error) {
+	schema := s
+	if path == "" {
+		return schema, nil
+	}
+	for _, name := range strings.Split(path, ".") {
+		schema = schema.elem()
+		field, ok := schema.Properties[name]
+		if !ok {
+			return nil, errors.Errorf("field %s does not exist", path)
+		}
+		schema = field
+	}
+	return schema, nil
+}
+
+// typeName returns the field type in 'oc explain' notation (e.g. <[]Object>)
+func (s *JSONSchema) typeName() string {
+	switch s.Type {
+	case schemaTypeObject:
+		if values, ok := s.AdditionalProperties.(*JSONSchema); ok {
+			return fmt.Sprintf("<map[string]%s>", strings.Trim(values.typeName(), "<>"))
+		}
+		return "<Object>"
+	case schemaTypeArray:
+		return fmt.Sprintf("<[]%s>", strings.Trim(s.Items.typeName(), "<>"))
+	default:
+		return fmt.Sprintf("<%s>", s.Type)
+	}
+}
+
+// Explain writes the documentation of the field in the specified path (similar to 'oc explain')
+func (s *JSONSchema) Explain(w io.Writer, path string) error {
+	schema, err := s.Lookup(path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "KIND:     %s\n", applianceConfigKind)
+	fmt.Fprintf(w, "VERSION:  %s\n\n", types.ApplianceConfigApiVersion)
+	if path != "" {
+		name := path[strings.LastIndex(path, ".")+1:]
+		fmt.Fprintf(w, "FIELD:    %s %s\n\n", name, schema.typeName())
+	}
+	fmt.Fprintf(w, "DESCRIPTION:\n     %s\n", schema.Description)
+
+	fmt.Fprintln(w)
+	if schema.Default != nil {
+		defaultVal, _ := json.Marshal(schema.Default)
+		fmt.Fprintf(w, "DEFAULT:  %s\n", defaultVal)
+	}
+	if len(schema.Enum) > 0 {
+		values := make([]string, len(schema.Enum))
+		for i, v := range schema.Enum {
+			values[i] = fmt.Sprint(v)
+		}
+		fmt.Fprintf(w, "VALUES:   %s\n", strings.Join(values, ", "))
+	}
+	if schema.Minimum != nil {
+		fmt.Fprintf(w, "MINIMUM:  %d\n", *schema.Minimum)
+	}
+	if schema.Maximum != nil {
+		fmt.Fprintf(w, "MAXIMUM:  %d\n", *schema.Maximum)
+	}
+
+	object := schema.elem()
+	if len(object.fields) > 0 {
+		fmt.Fprint(w, "FIELDS:\n")
+		for _, name := range object.fields {
+			field := object.Properties[name]
+			required := ""
+			if funk.ContainsString(object.Required, name) {
+				required = " -required-"
+			}
+			fmt.Fprintf(w, "   %s\t%s%s\n", name, field.typeName(), required)
+			fmt.Fprintf(w, "     %s\n\n", field.Description)
+		}
+	}
+	return nil
+}
+
+// Template returns the appliance-config.yaml template (written by 'generate-config'),
+// showing the fields in declaration order with their documentation.
+// Optional fields are commented out (except for the fields marked as uncommented).
+func (s *JSONSchema) Template() string {
+	w := &templateWriter{}
+	w.WriteString(templateHeader)
+	w.object("", "", s, false)
+	return w.String()
+}
+
+// templateWriter writes the fields of the template (each line is commented out at its own indentation,
+// so uncommenting a field and its parents yields a valid YAML)
+type templateWriter struct {
+	strings.Builder
+}
+
+func (w *templateWriter) line(indent string, commented bool, text string) {
+	if commented {
+		text = "# " + text
+	}
+	w.WriteString(strings.TrimRight(indent+text, " ") + "\n")
+}
+
+// object writes the fields of the object, where the first field is prefixed by itemPrefix
+// (i.e. '- ' for the items of an array)
+func (w *templateWriter) object(indent, itemPrefix string, s *JSONSchema, commented bool) {
+	first := true
+	for _, name := range s.fields {
+		field := s.Properties[name]
+		if field.Type == schemaTypeObject && field.Properties == nil && field.AdditionalProperties == nil {
+			// Not configurable (i.e. metadata)
+			continue
+		}
+
+		fieldIndent, prefix := indent, itemPrefix
+		if !first {
+			// The next fields of an array item are aligned with the first one
+			fieldIndent, prefix = indent+strings.Repeat(" ", len(itemPrefix)), ""
+			if fieldIndent == "" {
+				w.WriteString("\n")
+			} else {
+				w.line(fieldIndent, true, "")
+			}
+		}
+		first = false
+
+		required := funk.ContainsString(s.Required, name)
+		w.doc(fieldIndent, field, required)
+		w.value(fieldIndent, prefix, name, field, commented || !(required || field.uncommented))
+	}
+}
+
+func (w *templateWriter) doc(indent string, s *JSONSchema, required bool) {
+	for _, line := range wrapText(s.Description, templateWidth-len(indent)-len("# ")) {
+		w.line(indent, true, line)
+	}
+	if len(s.Enum) > 0 {
+		values := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			values[i] = templateScalar(v)
+		}
+		w.line(indent, true, fmt.Sprintf("Values: %s", strings.Join(values, "|")))
+	}
+	if s.Default != nil {
+		w.line(indent, true, fmt.Sprintf("Default: %s", templateScalar(s.Default)))
+	}
+	if s.Minimum != nil {
+		w.line(indent, true, fmt.Sprintf("Minimum: %d", *s.Minimum))
+	}
+	if s.Maximum != nil {
+		w.line(indent, true, fmt.Sprintf("Maximum: %d", *s.Maximum))
+	}
+	if !required {
+		w.line(indent, true, "[Optional]")
+	}
+}
+
+// value writes the field with its example value (nested values are indented under the field)
+func (w *templateWriter) value(indent, prefix, name string, s *JSONSchema, commented bool) {
+	nested := indent + strings.Repeat(" ", len(prefix)) + "  "
+	switch {
+	case s.Type == schemaTypeArray:
+		w.line(indent, commented, fmt.Sprintf("%s%s:", prefix, name))
+		if s.Items.Type == schemaTypeObject {
+			w.object(nested, "- ", s.Items, commented)
+		} else {
+			w.line(nested, commented, fmt.Sprintf("- %s", s.templateExample(name)))
+		}
+	case s.Type == schemaTypeObject:
+		w.line(indent, commented, fmt.Sprintf("%s%s:", prefix, name))
+		if values, ok := s.AdditionalProperties.(*JSONSchema); ok {
+			w.value(nested, "", s.templateExample("key"), values, commented)
+		} else {
+			w.object(nested, "", s, commented)
+		}
+	default:
+		w.line(indent, commented, fmt.Sprintf("%s%s: %s", prefix, name, s.templateExample(name)))
+	}
+}
+
+// templateExample returns the value of the field in the template: the example,
+// the default value or a placeholder (the field name in kebab-case)
+func (s *JSONSchema) templateExample(name string) string {
+	switch {
+	case s.example != nil:
+		return templateScalar(s.example)
+	case s.Default != nil && s.Default != "":
+		return templateScalar(s.Default)
+	case len(s.Enum) > 0:
+		return templateScalar(s.Enum[0])
+	case s.Minimum != nil:
+		return templateScalar(*s.Minimum)
+	case s.Type == schemaTypeBoolean:
+		return templateScalar(false)
+	}
+	return strings.ToLower(kebabCaseRegex.ReplaceAllString(name, "$1-$2"))
+}
+
+// templateScalar returns the value in YAML notation (i.e. quoted if needed)
+func templateScalar(v interface{}) string {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// wrapText splits the text into lines of up to width characters (unless a single word is longer)
+func wrapText(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+len(word)+1 > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,265 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/go-openapi/swag"
+	"github.com/openshift/appliance/pkg/log/redact"
+	"github.com/openshift/appliance/pkg/reproducible"
+	"github.com/openshift/appliance/pkg/types"
+	"github.com/openshift/installer/pkg/asset"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var (
+	// envVarRegex matches ${ENV_VAR} references
+	envVarRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+	// passwordHashRegex matches crypt(3) hashes (e.g. $2a$10$..., $6$salt$... or $y$j9T$...)
+	passwordHashRegex = regexp.MustCompile(`^\$[0-9a-z]+\$\S+$`)
+
+	// grubPasswordHashRegex matches GRUB PBKDF2 hashes (generated by grub2-mkpasswd-pbkdf2)
+	grubPasswordHashRegex = regexp.MustCompile(`^grub\.pbkdf2\.sha512\.[0-9]+\.[0-9A-Fa-f]+\.[0-9A-Fa-f]+$`)
+)
+
+// interpolateEnv replaces ${ENV_VAR} references with the values of the environment variables
+func interpolateEnv(value string) (string, error) {
+	var missing []string
+	interpolated := envVarRegex.ReplaceAllStringFunc(value, func(ref string) string {
+		name := envVarRegex.FindStringSubmatch(ref)[1]
+		envValue, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return envValue
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return interpolated, nil
+}
+
+// readSecretFile returns the (trimmed) content of a secret file.
+// A relative path is relative to the assets directory.
+func readSecretFile(f asset.FileFetcher, path string) (string, error) {
+	if filepath.IsAbs(path) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	file, err := f.FetchByName(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(file.Data)), nil
+}
+
+// resolveSecretRefs interpolates the secret fields and reads the referenced secret files,
+// so the resolved values are used (and validated) as if specified inline.
+func (a *ApplianceConfig) resolveSecretRefs(f asset.FileFetcher) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for _, secret := range []struct {
+		path  string
+		value *string
+	}{
+		{"pullSecret", &a.Config.PullSecret},
+		{"pullSecretFile", a.Config.PullSecretFile},
+		{"sshKey", a.Config.SshKey},
+		{"sshKeyFile", a.Config.SshKeyFile},
+		{"userCorePass", a.Config.UserCorePass},
+		{"userCorePassHash", a.Config.UserCorePassHash},
+		{"boot.grub.recoveryPasswordHash", a.Config.Boot.Grub.RecoveryPasswordHash},
+	} {
+		if secret.value == nil {
+			continue
+		}
+		interpolated, err := interpolateEnv(*secret.value)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath(secret.path), *secret.value, err.Error()))
+			continue
+		}
+		*secret.value = interpolated
+	}
+	if len(allErrs) > 0 {
+		return allErrs
+	}
+
+	// pullSecretFile
+	if a.Config.PullSecretFile != nil {
+		if a.Config.PullSecret != "" {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("pullSecretFile"), "pullSecret and pullSecretFile are mutually exclusive"))
+		} else if pullSecret, err := readSecretFile(f, *a.Config.PullSecretFile); err != nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("pullSecretFile"), *a.Config.PullSecretFile, err.Error()))
+		} else {
+			a.Config.PullSecret = pullSecret
+		}
+	}
+
+	// sshKeyFile
+	if a.Config.SshKeyFile != nil {
+		if a.Config.SshKey != nil {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("sshKeyFile"), "sshKey and sshKeyFile are mutually exclusive"))
+		} else if sshKey, err := readSecretFile(f, *a.Config.SshKeyFile); err != nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("sshKeyFile"), *a.Config.SshKeyFile, err.Error()))
+		} else {
+			a.Config.SshKey = swag.String(sshKey)
+		}
+	}
+
+	// userCorePassHash
+	if a.Config.UserCorePassHash != nil {
+		if a.Config.UserCorePass != nil {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("userCorePassHash"), "userCorePass and userCorePassHash are mutually exclusive"))
+		} else if !passwordHashRegex.MatchString(*a.Config.UserCorePassHash) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("userCorePassHash"), redact.Value,
+				"should be a crypt(3) password hash (e.g. generated by 'mkpasswd --method=bcrypt')"))
+		}
+	}
+
+	// boot.grub.recoveryPasswordHash
+	if hash := a.Config.Boot.Grub.RecoveryPasswordHash; hash != nil && !grubPasswordHashRegex.MatchString(*hash) {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("boot.grub.recoveryPasswordHash"), redact.Value,
+			"should be a GRUB password hash (e.g. generated by 'grub2-mkpasswd-pbkdf2')"))
+	}
+
+	return allErrs
+}
+
+// GetCorePassHash returns the password hash of user 'core' (empty when no password is specified).
+// A pre-hashed userCorePassHash is used as is, otherwise userCorePass is hashed.
+// The salt is derived from the seed when specified (see EnvConfig.ImageSeed), otherwise it's random.
+func (a *ApplianceConfig) GetCorePassHash(seed string) (string, error) {
+	if a.Config.UserCorePassHash != nil {
+		return *a.Config.UserCorePassHash, nil
+	}
+	if a.Config.UserCorePass == nil {
+		return "", nil
+	}
+	return reproducible.HashPassword(*a.Config.UserCorePass, coreUserName, seed)
+}
+
+// registerSecrets redacts the (resolved) secrets from the logs
+func (a *ApplianceConfig) registerSecrets() {
+	redact.Register(a.Config.PullSecret, swag.StringValue(a.Config.UserCorePass), swag.StringValue(a.Config.UserCorePassHash),
+		swag.StringValue(a.Config.Boot.Grub.RecoveryPasswordHash))
+	for _, user := range a.GetUsers() {
+		redact.Register(swag.StringValue(user.PasswordHash))
+	}
+
+	// Redact also the credentials of each registry in the pull secret
+	pullSecret := struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}{}
+	if err := json.Unmarshal([]byte(a.Config.PullSecret), &pullSecret); err != nil {
+		return
+	}
+	for _, registryAuth := range pullSecret.Auths {
+		redact.Register(registryAuth.Auth)
+		if creds, err := base64.StdEncoding.DecodeString(registryAuth.Auth); err == nil {
+			if _, password, found := strings.Cut(string(creds), ":"); found {
+				redact.Register(string(creds), password)
+			}
+		}
+	}
+}
+
+// redactSecrets replaces the secret values in the config
+func redactSecrets(config *types.ApplianceConfig) {
+	if config.PullSecret != "" {
+		config.PullSecret = redact.Value
+	}
+	if config.UserCorePass != nil {
+		config.UserCorePass = swag.String(redact.Value)
+	}
+	if config.UserCorePassHash != nil {
+		config.UserCorePassHash = swag.String(redact.Value)
+	}
+	if config.Boot.Grub.RecoveryPasswordHash != nil {
+		config.Boot.Grub.RecoveryPasswordHash = swag.String(redact.Value)
+	}
+	if config.Users != nil {
+		users := make([]types.User, len(*config.Users))
+		for i, user := range *config.Users {
+			if user.PasswordHash != nil {
+				user.PasswordHash = swag.String(redact.Value)
+			}
+			users[i] = user
+		}
+		config.Users = &users
+	}
+}
+
+// loadedApplianceConfig is the appliance config loaded from the assets directory,
+// which provides the secrets that are excluded from the asset state file.
+// Note that it's set by Load, i.e. it relies on the asset store loading the on-disk asset
+// before unmarshalling the asset from the state file (see asset.StoreImpl).
+var loadedApplianceConfig *ApplianceConfig
+
+// persistedApplianceConfig is an alias type of the asset (i.e. without the json.Marshaler/Unmarshaler)
+type persistedApplianceConfig ApplianceConfig
+
+// MarshalJSON excludes the secrets from the asset (i.e. when persisted in the asset state file)
+func (a *ApplianceConfig) MarshalJSON() ([]byte, error) {
+	state := persistedApplianceConfig(*a)
+	if a.Config != nil {
+		config := *a.Config
+		redactSecrets(&config)
+		state.Config = &config
+	}
+	if a.File != nil {
+		// The file content is persisted as the (redacted) effective config
+		data, err := RenderApplianceConfig(a.File.Data)
+		if err != nil {
+			data = nil
+		}
+		state.File = &asset.File{Filename: a.File.Filename, Data: data}
+	}
+	return json.Marshal(state)
+}
+
+// UnmarshalJSON restores the secrets of a persisted asset from the config loaded from the assets directory,
+// when its redacted content is unchanged (i.e. the persisted asset matches the on-disk asset and isn't considered dirty).
+// Note that a change of only the secrets isn't detected, as the secrets aren't persisted in any form.
+func (a *ApplianceConfig) UnmarshalJSON(data []byte) error {
+	state := persistedApplianceConfig{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	*a = ApplianceConfig(state)
+	if a.Config == nil {
+		// Not loaded from a config file (i.e. no secrets)
+		return nil
+	}
+	if loadedApplianceConfig == nil {
+		return errors.Errorf("%s is missing (the secrets aren't persisted in the asset state file)", ApplianceConfigFilename)
+	}
+	loadedData, err := loadedApplianceConfig.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	// The state file is indented (i.e. compare the compact forms)
+	persisted, loaded := &bytes.Buffer{}, &bytes.Buffer{}
+	if err = json.Compact(persisted, data); err != nil {
+		return err
+	}
+	if err = json.Compact(loaded, loadedData); err != nil {
+		return err
+	}
+	if bytes.Equal(persisted.Bytes(), loaded.Bytes()) {
+		*a = *loadedApplianceConfig
+	}
+	return nil
+}
@@ -1,10 +1,21 @@
 package config
 
 import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/go-openapi/swag"
 	. "github.com/onsi/ginkgo/v2/dsl/core"
 	. "github.com/onsi/gomega"
+	"github.com/openshift/appliance/pkg/consts"
+	"github.com/openshift/appliance/pkg/log/redact"
+	"github.com/openshift/appliance/pkg/types"
+	"github.com/openshift/installer/pkg/asset"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
 )
 
 func TestConfig(t *testing.T) {
@@ -35,3 +46,876 @@ var _ = Describe("appendDigest", func() {
 			To(Equal("registry.example.com:5000/img@sha256:abc123"))
 	})
 })
+
+var _ = Describe("dataPartitionFormat", func() {
+	It("defaults to iso9660", func() {
+		a := &ApplianceConfig{Config: &types.ApplianceConfig{}}
+		Expect(a.validateDataPartitionFormat()).To(BeEmpty())
+		Expect(a.GetDataPartitionFormat()).To(Equal(consts.DataPartitionFormatISO9660))
+		Expect(a.GetDataImageFileName()).To(Equal(consts.DataIsoFileName))
+	})
+
+	It("uses a compressed image for squashfs", func() {
+		a := &ApplianceConfig{Config: &types.ApplianceConfig{
+			Disk: types.DiskConfig{DataPartitionFormat: swag.String(consts.DataPartitionFormatSquashfs)},
+		}}
+		Expect(a.validateDataPartitionFormat()).To(BeEmpty())
+		Expect(a.GetDataImageFileName()).To(Equal(consts.DataSquashfsFileName))
+	})
+
+	It("uses a compressed image for erofs", func() {
+		binDir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(binDir, mkfsErofsBinary), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+		GinkgoT().Setenv("PATH", binDir)
+
+		a := &ApplianceConfig{Config: &types.ApplianceConfig{
+			Disk: types.DiskConfig{DataPartitionFormat: swag.String(consts.DataPartitionFormatErofs)},
+		}}
+		Expect(a.validateDataPartitionFormat()).To(BeEmpty())
+		Expect(a.GetDataImageFileName()).To(Equal(consts.DataErofsFileName))
+	})
+
+	It("fails on erofs without mkfs.erofs", func() {
+		GinkgoT().Setenv("PATH", GinkgoT().TempDir())
+
+		a := &ApplianceConfig{Config: &types.ApplianceConfig{
+			Disk: types.DiskConfig{DataPartitionFormat: swag.String(consts.DataPartitionFormatErofs)},
+		}}
+		errs := a.validateDataPartitionFormat()
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Detail).To(ContainSubstring(mkfsErofsBinary))
+	})
+
+	It("fails on an unsupported format", func() {
+		a := &ApplianceConfig{Config: &types.ApplianceConfig{
+			Disk: types.DiskConfig{DataPartitionFormat: swag.String("ext4")},
+		}}
+		Expect(a.validateDataPartitionFormat()).ToNot(BeEmpty())
+	})
+})
+
+var _ = Describe("ignitionSpecVersion", func() {
+	It("uses the current spec", func() {
+		a := &ApplianceConfig{Config: &types.ApplianceConfig{
+			OcpRelease: types.ReleaseImage{Version: consts.MinOcpVersionForIgnitionSpec},
+		}}
+		Expect(a.GetIgnitionSpecVersion()).To(Equal(consts.IgnitionSpecVersion))
+	})
+
+	It("uses the legacy spec for older releases", func() {
+		a := &ApplianceConfig{Config: &types.ApplianceConfig{
+			OcpRelease: types.ReleaseImage{Version: "4.13.10"},
+		}}
+		Expect(a.GetIgnitionSpecVersion()).To(Equal(consts.IgnitionSpecVersionLegacy))
+	})
+})
+
+var _ = Describe("boot", func() {
+	It("appends the console to the kernel arguments", func() {
+		a := &ApplianceConfig{Config: &types.ApplianceConfig{
+			Boot: types.BootConfig{
+				KernelArguments: &[]string{"nosmt", "rd.multipath=default"},
+				Console:         swag.String("ttyS0,115200n8"),
+			},
+		}}
+		Expect(a.validateBoot()).To(BeEmpty())
+		Expect(a.GetConsoleArguments()).To(Equal([]string{"console=tty0", "console=ttyS0,115200n8"}))
+		Expect(a.GetBootKernelArguments()).To(Equal([]string{
+			"console=tty0", "console=ttyS0,115200n8", "nosmt", "rd.multipath=default"}))
+	})
+
+	It("uses the default kernel arguments", func() {
+		a := &ApplianceConfig{Config: &types.ApplianceConfig{}}
+		Expect(a.validateBoot()).To(BeEmpty())
+		Expect(a.GetBootKernelArguments()).To(BeEmpty())
+	})
+
+	It("fails on invalid kernel arguments", func() {
+		a := &ApplianceConfig{Config: &types.ApplianceConfig{
+			Boot: types.BootConfig{
+				KernelArguments: &[]string{"nosmt", "", "a b"},
+				Console:         swag.String("console=ttyS0"),
+			},
+		}}
+		fields := []string{}
+		for _, err := range a.validateBoot() {
+			fields = append(fields, err.Field)
+		}
+		Expect(fields).To(ConsistOf("boot.kernelArguments[1]", "boot.kernelArguments[2]", "boot.console"))
+	})
+
+	It("uses the default GRUB menu", func() {
+		a := &ApplianceConfig{Config: &types.ApplianceConfig{}}
+		Expect(a.GetGrubTimeout()).To(Equal(consts.GrubTimeout))
+		Expect(a.GetGrubMenuEntryName()).To(Equal(consts.GrubMenuEntryName))
+		Expect(a.GetGrubDefaultEntry()).To(Equal(consts.GrubMenuEntryName))
+		Expect(a.GetGrubRecoveryMenuEntryName()).To(Equal(consts.GrubMenuEntryNameRecovery))
+	})
+
+	It("customizes the GRUB menu", func() {
+		a := &ApplianceConfig{Config: &types.ApplianceConfig{
+			Boot: types.BootConfig{Grub: types.GrubConfig{
+				Timeout:               swag.Int(0),
+				MenuEntryName:         swag.String("Factory Install"),
+				RecoveryMenuEntryName: swag.String("Factory Reset"),
+			}},
+		}}
+		Expect(a.validateBoot()).To(BeEmpty())
+		Expect(a.GetGrubTimeout()).To(Equal(0))
+		Expect(a.GetGrubDefaultEntry()).To(Equal("Factory Install"))
+		Expect(a.GetGrubRecoveryMenuEntryName()).To(Equal("Factory Reset"))
+	})
+
+	It("fails on an invalid GRUB menu", func() {
+		a := &ApplianceConfig{Config: &types.ApplianceConfig{
+			Boot: types.BootConfig{Grub: types.GrubConfig{
+				Timeout:       swag.Int(-1),
+				DefaultEntry:  swag.String(""),
+				MenuEntryName: swag.String("Appliance's entry"),
+			}},
+		}}
+		fields := []string{}
+		for _, err := range a.validateBoot() {
+			fields = append(fields, err.Field)
+		}
+		Expect(fields).To(ConsistOf("boot.grub.timeout", "boot.grub.defaultEntry", "boot.grub.menuEntryName"))
+	})
+})
+
+var _ = Describe("ocpRelease graph source", func() {
+	It("accepts a graphURL", func() {
+		a := &ApplianceConfig{Config: &types.ApplianceConfig{OcpRelease: types.ReleaseImage{
+			Version:  "4.18",
+			GraphURL: swag.String("https://osus.example.com/api/upgrades_info/graph"),
+		}}}
+		Expect(a.validateOcpRelease()).To(BeEmpty())
+	})
+
+	It("fails on a relative graphURL", func() {
+		a := &ApplianceConfig{Config: &types.ApplianceConfig{OcpRelease: types.ReleaseImage{
+			Version:  "4.18",
+			GraphURL: swag.String("osus.example.com"),
+		}}}
+		Expect(a.validateOcpRelease()).ToNot(BeEmpty())
+	})
+
+	It("fails on a missing graphFile", func() {
+		a := &ApplianceConfig{Config: &types.ApplianceConfig{OcpRelease: types.ReleaseImage{
+			Version:   "4.18",
+			GraphFile: swag.String("/nonexistent/graph.json"),
+		}}}
+		Expect(a.validateOcpRelease()).ToNot(BeEmpty())
+	})
+
+	It("fails when both graphURL and graphFile are set", func() {
+		graphFile := filepath.Join(GinkgoT().TempDir(), "graph.json")
+		Expect(os.WriteFile(graphFile, []byte(`{"nodes":[]}`), 0600)).To(Succeed())
+		a := &ApplianceConfig{Config: &types.ApplianceConfig{OcpRelease: types.ReleaseImage{
+			Version:   "4.18",
+			GraphURL:  swag.String("https://osus.example.com/api/upgrades_info/graph"),
+			GraphFile: swag.String(graphFile),
+		}}}
+		Expect(a.validateOcpRelease()).To(HaveLen(1))
+	})
+})
+
+var _ = Describe("applyLock", func() {
+	var (
+		a    *ApplianceConfig
+		lock *types.ApplianceLock
+	)
+
+	BeforeEach(func() {
+		a = &ApplianceConfig{Config: &types.ApplianceConfig{
+			OcpRelease: types.ReleaseImage{Version: "4.18"},
+			Mirror: types.MirrorConfig{
+				Operators: &[]types.Operator{{
+					Catalog: "registry.redhat.io/redhat/redhat-operator-index:v4.18",
+				}},
+				AdditionalImages: &[]types.Image{
+					{Name: "quay.io/fedora/httpd-24"},
+					{Name: "quay.io/openshift/origin-cli:4.18"},
+				},
+			},
+			Registry: types.RegistryConfig{URI: swag.String("quay.io/libpod/registry:2.8")},
+		}}
+		lock = &types.ApplianceLock{
+			Release: types.LockedRelease{
+				Version: "4.18.12",
+				Image:   "quay.io/openshift-release-dev/ocp-release@sha256:aaa",
+			},
+			RegistryImage: types.LockedImage{
+				Source: "quay.io/libpod/registry:2.8",
+				Image:  "quay.io/libpod/registry@sha256:bbb",
+			},
+			Operators: []types.LockedOperator{{
+				Source:  "registry.redhat.io/redhat/redhat-operator-index:v4.18",
+				Catalog: "registry.redhat.io/redhat/redhat-operator-index@sha256:ccc",
+			}},
+			Images: []types.LockedImage{
+				{Source: "quay.io/fedora/httpd-24:latest", Image: "quay.io/fedora/httpd-24@sha256:ddd"},
+				{Source: "quay.io/openshift/origin-cli:4.18", Image: "quay.io/openshift/origin-cli@sha256:eee"},
+			},
+		}
+	})
+
+	It("pins the config", func() {
+		Expect(a.applyLock(lock)).To(Succeed())
+		Expect(swag.StringValue(a.Config.OcpRelease.URL)).To(Equal(lock.Release.Image))
+		Expect((*a.Config.Mirror.Operators)[0].Catalog).To(Equal(lock.Operators[0].Catalog))
+		Expect(swag.StringValue(a.Config.Registry.URI)).To(Equal(lock.RegistryImage.Image))
+		Expect(*a.Config.Mirror.AdditionalImages).To(Equal([]types.Image{
+			{Name: "quay.io/fedora/httpd-24@sha256:ddd"},
+			{Name: "quay.io/openshift/origin-cli@sha256:eee"},
+		}))
+	})
+
+	It("fails on an additional image that isn't pinned", func() {
+		*a.Config.Mirror.AdditionalImages = append(*a.Config.Mirror.AdditionalImages, types.Image{Name: "quay.io/fedora/nginx:1.26"})
+		Expect(a.applyLock(lock)).ToNot(Succeed())
+	})
+
+	It("fails on a different release version", func() {
+		a.Config.OcpRelease.Version = "4.1"
+		Expect(a.applyLock(lock)).ToNot(Succeed())
+	})
+
+	It("fails on a different operator catalog", func() {
+		(*a.Config.Mirror.Operators)[0].Catalog = "registry.redhat.io/redhat/certified-operator-index:v4.18"
+		Expect(a.applyLock(lock)).ToNot(Succeed())
+	})
+
+	It("fails on a missing operator catalog", func() {
+		a.Config.Mirror.Operators = nil
+		Expect(a.applyLock(lock)).ToNot(Succeed())
+	})
+})
+
+var _ = Describe("apiVersion conversion", func() {
+	const configV1Beta1 = `apiVersion: v1beta1
+kind: ApplianceConfig
+ocpRelease:
+  version: "4.18"
+pullSecret: '{"auths":{}}'
+diskSizeGB: 200
+imageRegistry:
+  port: 5123
+mirrorPath: /path/to/mirror
+enableFips: true
+stopLocalRegistry: true
+skipLocalRegistry: false
+useDefaultSourceNames: true
+operators:
+  - catalog: registry.redhat.io/redhat/redhat-operator-index:v4.18
+`
+
+	It("converts v1beta1 to the current apiVersion", func() {
+		config, apiVersion, err := ParseApplianceConfig([]byte(configV1Beta1))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(apiVersion).To(Equal(types.ApplianceConfigV1Beta1ApiVersion))
+		Expect(config.APIVersion).To(Equal(types.ApplianceConfigApiVersion))
+		Expect(swag.IntValue(config.Disk.SizeGB)).To(Equal(200))
+		Expect(swag.IntValue(config.Registry.Port)).To(Equal(5123))
+		Expect(swag.BoolValue(config.Registry.StopPostInstall)).To(BeTrue())
+		Expect(config.Registry.SkipPostInstall).ToNot(BeNil())
+		Expect(swag.StringValue(config.Mirror.Path)).To(Equal("/path/to/mirror"))
+		Expect(*config.Mirror.Operators).To(HaveLen(1))
+		Expect(swag.BoolValue(config.Cluster.EnableFips)).To(BeTrue())
+		Expect(swag.BoolValue(config.Cluster.UseDefaultSourceNames)).To(BeTrue())
+		// v1beta1 always fell back to the latest supported version
+		Expect(swag.BoolValue(config.OcpRelease.AllowVersionFallback)).To(BeTrue())
+	})
+
+	It("fails on a v1beta2 field in a v1beta1 config", func() {
+		_, _, err := ParseApplianceConfig([]byte(configV1Beta1 + "cluster:\n  enableFips: true\n"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails on a field that isn't in the released v1beta1 schema", func() {
+		for _, newField := range []string{"dataPartitionFormat: squashfs\n", "buildSeed: seed\n"} {
+			_, _, err := ParseApplianceConfig([]byte(configV1Beta1 + newField))
+			Expect(err).To(HaveOccurred())
+		}
+		_, _, err := ParseApplianceConfig([]byte(strings.Replace(configV1Beta1, `version: "4.18"`, "version: \"4.18\"\n  graphFile: graph.json", 1)))
+		Expect(err).To(HaveOccurred())
+		_, _, err = ParseApplianceConfig([]byte(configV1Beta1 + "    install: true\n"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("reports validation errors of a v1beta1 config with the v1beta1 field paths", func() {
+		errs := field.ErrorList{
+			field.Invalid(field.NewPath("mirror", "additionalImages").Index(0).Child("name"), "", ""),
+			field.Invalid(field.NewPath("registry", "port"), 0, ""),
+			field.Invalid(field.NewPath("registry", "portNumber"), 0, ""),
+			field.Invalid(field.NewPath("ocpRelease", "version"), "", ""),
+		}
+		toFileFieldPaths(errs, types.ApplianceConfigV1Beta1ApiVersion)
+		Expect(errs[0].Field).To(Equal("additionalImages[0].name"))
+		Expect(errs[1].Field).To(Equal("imageRegistry.port"))
+		Expect(errs[2].Field).To(Equal("registry.portNumber"))
+		Expect(errs[3].Field).To(Equal("ocpRelease.version"))
+	})
+
+	It("keeps the field paths of a current apiVersion config", func() {
+		errs := field.ErrorList{field.Invalid(field.NewPath("registry", "port"), 0, "")}
+		toFileFieldPaths(errs, types.ApplianceConfigApiVersion)
+		Expect(errs[0].Field).To(Equal("registry.port"))
+	})
+
+	It("fails on a v1beta1 field in a v1beta2 config", func() {
+		_, _, err := ParseApplianceConfig([]byte("apiVersion: v1beta2\nkind: ApplianceConfig\nenableFips: true\n"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails migrating an apiVersion other than v1beta1", func() {
+		_, err := MigrateApplianceConfig([]byte("apiVersion: v1alpha1\nkind: ApplianceConfig\n"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("migrates v1beta1 to the current apiVersion", func() {
+		migrated, err := MigrateApplianceConfig([]byte(configV1Beta1))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(migrated)).To(HavePrefix("apiVersion: v1beta2\nkind: ApplianceConfig\n"))
+		Expect(string(migrated)).ToNot(ContainSubstring("metadata"))
+
+		config, apiVersion, err := ParseApplianceConfig(migrated)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(apiVersion).To(Equal(types.ApplianceConfigApiVersion))
+		converted, _, err := ParseApplianceConfig([]byte(configV1Beta1))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config).To(Equal(converted))
+	})
+})
+
+var _ = Describe("MergeApplianceConfig", func() {
+	base := &asset.File{Filename: ApplianceConfigFilename, Data: []byte(`apiVersion: v1beta2
+kind: ApplianceConfig
+ocpRelease:
+  version: "4.18"
+pullSecret: '{"auths":{}}'
+userCorePass: pass
+registry:
+  port: 5123
+  useBinary: true
+mirror:
+  additionalImages:
+    - name: quay.io/base/image:1
+disk:
+  sizeGB: 200
+`)}
+
+	overlay := func(name, data string) *asset.File {
+		return &asset.File{Filename: filepath.Join(ApplianceConfigOverlaysDir, name), Data: []byte(data)}
+	}
+
+	It("merges overlays in order", func() {
+		merged, err := MergeApplianceConfig(base, []*asset.File{
+			overlay("10-site.yaml", "sshKey: key-a\ndisk:\n  sizeGB: 300\n"),
+			overlay("20-site.yaml", "sshKey: key-b\nregistry:\n  port: 5005\n"),
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		config, _, err := ParseApplianceConfig(merged)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(swag.StringValue(config.SshKey)).To(Equal("key-b"))
+		Expect(swag.IntValue(config.Disk.SizeGB)).To(Equal(300))
+		Expect(swag.IntValue(config.Registry.Port)).To(Equal(5005))
+		Expect(swag.BoolValue(config.Registry.UseBinary)).To(BeTrue())
+		Expect(config.OcpRelease.Version).To(Equal("4.18"))
+	})
+
+	It("replaces lists and removes null fields", func() {
+		merged, err := MergeApplianceConfig(base, []*asset.File{
+			overlay("site.yaml", "userCorePass: null\nmirror:\n  additionalImages:\n    - name: quay.io/site/image:1\n"),
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		config, _, err := ParseApplianceConfig(merged)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.UserCorePass).To(BeNil())
+		Expect(*config.Mirror.AdditionalImages).To(Equal([]types.Image{{Name: "quay.io/site/image:1"}}))
+	})
+
+	It("ignores an empty overlay", func() {
+		merged, err := MergeApplianceConfig(base, []*asset.File{overlay("empty.yaml", "")})
+		Expect(err).ToNot(HaveOccurred())
+		config, _, err := ParseApplianceConfig(merged)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(swag.IntValue(config.Disk.SizeGB)).To(Equal(200))
+	})
+
+	It("fails on an overlay that isn't an object", func() {
+		_, err := MergeApplianceConfig(base, []*asset.File{overlay("list.yaml", "- sshKey: key\n")})
+		Expect(err).To(MatchError(ContainSubstring("list.yaml")))
+	})
+
+	It("fails on an overlay with a different apiVersion", func() {
+		_, err := MergeApplianceConfig(base, []*asset.File{overlay("site.yaml", "apiVersion: v1beta1\nsshKey: key\n")})
+		Expect(err).To(MatchError(ContainSubstring("site.yaml apiVersion (v1beta1)")))
+	})
+
+	It("fails on an overlay with a different kind", func() {
+		_, err := MergeApplianceConfig(base, []*asset.File{overlay("site.yaml", "kind: Other\n")})
+		Expect(err).To(MatchError(ContainSubstring("site.yaml kind (Other)")))
+	})
+
+	It("accepts an overlay with the same apiVersion and kind", func() {
+		_, err := MergeApplianceConfig(base, []*asset.File{overlay("site.yaml", "apiVersion: v1beta2\nkind: ApplianceConfig\n")})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("reads the overlays of the assets directory in order", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.MkdirAll(filepath.Join(dir, ApplianceConfigOverlaysDir), 0755)).To(Succeed())
+		for _, name := range []string{"20-site.yml", "10-site.yaml", "readme.txt"} {
+			Expect(os.WriteFile(filepath.Join(dir, ApplianceConfigOverlaysDir, name), []byte("sshKey: key\n"), 0600)).To(Succeed())
+		}
+
+		overlays, err := ReadOverlays(dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(overlays).To(HaveLen(2))
+		Expect(overlays[0].Filename).To(Equal(filepath.Join(ApplianceConfigOverlaysDir, "10-site.yaml")))
+		Expect(overlays[1].Filename).To(Equal(filepath.Join(ApplianceConfigOverlaysDir, "20-site.yml")))
+	})
+
+	It("renders the effective config with redacted secrets", func() {
+		merged, err := MergeApplianceConfig(base, []*asset.File{overlay("site.yaml", "sshKey: key-a\n")})
+		Expect(err).ToNot(HaveOccurred())
+		rendered, err := RenderApplianceConfig(merged)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(rendered)).To(HavePrefix("apiVersion: v1beta2\nkind: ApplianceConfig\n"))
+		Expect(string(rendered)).To(ContainSubstring("sshKey: key-a"))
+		Expect(string(rendered)).To(ContainSubstring("pullSecret: <redacted>"))
+		Expect(string(rendered)).To(ContainSubstring("userCorePass: <redacted>"))
+		Expect(string(rendered)).ToNot(ContainSubstring("auths"))
+	})
+})
+
+var _ = Describe("resolveSecretRefs", func() {
+	var (
+		tmpDir          string
+		applianceConfig *ApplianceConfig
+	)
+
+	BeforeEach(func() {
+		tmpDir = GinkgoT().TempDir()
+		applianceConfig = &ApplianceConfig{Config: &types.ApplianceConfig{}}
+	})
+
+	It("reads the secret files", func() {
+		pullSecretFile := filepath.Join(tmpDir, "pull-secret.json")
+		Expect(os.WriteFile(pullSecretFile, []byte("{\"auths\":{}}\n"), 0600)).To(Succeed())
+		sshKeyFile := filepath.Join(tmpDir, "id_rsa.pub")
+		Expect(os.WriteFile(sshKeyFile, []byte("ssh-rsa AAAA\n"), 0600)).To(Succeed())
+		applianceConfig.Config.PullSecretFile = swag.String(pullSecretFile)
+		applianceConfig.Config.SshKeyFile = swag.String(sshKeyFile)
+
+		Expect(applianceConfig.resolveSecretRefs(nil)).To(BeEmpty())
+		Expect(applianceConfig.Config.PullSecret).To(Equal("{\"auths\":{}}"))
+		Expect(swag.StringValue(applianceConfig.Config.SshKey)).To(Equal("ssh-rsa AAAA"))
+	})
+
+	It("interpolates environment variables", func() {
+		GinkgoT().Setenv("TEST_CORE_PASS", "pass")
+		GinkgoT().Setenv("TEST_SECRETS_DIR", tmpDir)
+		Expect(os.WriteFile(filepath.Join(tmpDir, "pull-secret.json"), []byte("{}"), 0600)).To(Succeed())
+		applianceConfig.Config.UserCorePass = swag.String("${TEST_CORE_PASS}")
+		applianceConfig.Config.PullSecretFile = swag.String("${TEST_SECRETS_DIR}/pull-secret.json")
+
+		Expect(applianceConfig.resolveSecretRefs(nil)).To(BeEmpty())
+		Expect(swag.StringValue(applianceConfig.Config.UserCorePass)).To(Equal("pass"))
+		Expect(applianceConfig.Config.PullSecret).To(Equal("{}"))
+	})
+
+	It("fails on a missing environment variable", func() {
+		applianceConfig.Config.PullSecret = "${TEST_MISSING_PULL_SECRET}"
+		errs := applianceConfig.resolveSecretRefs(nil)
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Error()).To(ContainSubstring("TEST_MISSING_PULL_SECRET is not set"))
+	})
+
+	It("fails on both an inline secret and a reference", func() {
+		applianceConfig.Config.UserCorePass = swag.String("pass")
+		applianceConfig.Config.UserCorePassHash = swag.String("$2a$10$hash")
+		errs := applianceConfig.resolveSecretRefs(nil)
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Field).To(Equal("userCorePassHash"))
+	})
+
+	It("uses a pre-hashed password as is", func() {
+		applianceConfig.Config.UserCorePassHash = swag.String("$6$salt$hash")
+		Expect(applianceConfig.resolveSecretRefs(nil)).To(BeEmpty())
+		Expect(applianceConfig.GetCorePassHash("")).To(Equal("$6$salt$hash"))
+	})
+
+	It("hashes the password with a salt derived from the seed", func() {
+		applianceConfig.Config.UserCorePass = swag.String("pass")
+		hash, err := applianceConfig.GetCorePassHash("seed")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(applianceConfig.GetCorePassHash("seed")).To(Equal(hash))
+		Expect(applianceConfig.GetCorePassHash("other-seed")).ToNot(Equal(hash))
+	})
+
+	It("fails on an invalid password hash", func() {
+		applianceConfig.Config.UserCorePassHash = swag.String("pass")
+		Expect(applianceConfig.resolveSecretRefs(nil)).To(HaveLen(1))
+	})
+
+	It("validates the GRUB password hash", func() {
+		applianceConfig.Config.Boot.Grub.RecoveryPasswordHash = swag.String("grub.pbkdf2.sha512.10000.A1B2.C3D4")
+		Expect(applianceConfig.resolveSecretRefs(nil)).To(BeEmpty())
+
+		applianceConfig.Config.Boot.Grub.RecoveryPasswordHash = swag.String("$6$salt$hash")
+		errs := applianceConfig.resolveSecretRefs(nil)
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Field).To(Equal("boot.grub.recoveryPasswordHash"))
+	})
+})
+
+var _ = Describe("validateUsers", func() {
+	const sshKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIJ8i0x3f6sLXl7u6a1B5zY0x2Fq3mFv8Y3ZJfG5Vq1kB user@host"
+
+	var applianceConfig *ApplianceConfig
+
+	BeforeEach(func() {
+		applianceConfig = &ApplianceConfig{Config: &types.ApplianceConfig{}}
+	})
+
+	It("accepts valid users", func() {
+		applianceConfig.Config.SshKeys = &[]string{sshKey}
+		applianceConfig.Config.Users = &[]types.User{
+			{Name: "breakglass", Groups: []string{"wheel"}, PasswordHash: swag.String("$6$salt$hash")},
+			{Name: "support", SshKeys: []string{sshKey}},
+		}
+		Expect(applianceConfig.validateUsers()).To(BeEmpty())
+		Expect(applianceConfig.GetUsers()).To(HaveLen(2))
+	})
+
+	It("combines sshKey and sshKeys", func() {
+		applianceConfig.Config.SshKey = swag.String("key-a")
+		applianceConfig.Config.SshKeys = &[]string{"key-b", "key-c"}
+		Expect(applianceConfig.GetSshKeys()).To(Equal([]string{"key-a", "key-b", "key-c"}))
+	})
+
+	It("fails on invalid users", func() {
+		applianceConfig.Config.SshKeys = &[]string{"invalid-key"}
+		applianceConfig.Config.Users = &[]types.User{
+			{Name: "core", SshKeys: []string{sshKey}},
+			{Name: "Invalid User", SshKeys: []string{sshKey}},
+			{Name: "support", SshKeys: []string{sshKey}},
+			{Name: "support", SshKeys: []string{sshKey}},
+			{Name: "nocreds"},
+			{Name: "badhash", PasswordHash: swag.String("plain")},
+		}
+		fields := []string{}
+		for _, err := range applianceConfig.validateUsers() {
+			fields = append(fields, err.Field)
+		}
+		Expect(fields).To(ConsistOf("sshKeys[0]", "users[0].name", "users[1].name", "users[3].name",
+			"users[4]", "users[5].passwordHash"))
+	})
+})
+
+var _ = Describe("validateOperatorsInstall", func() {
+	It("validates the installation of the packages", func() {
+		applianceConfig := &ApplianceConfig{Config: &types.ApplianceConfig{
+			Mirror: types.MirrorConfig{
+				Operators: &[]types.Operator{{
+					Catalog: "registry.redhat.io/redhat/redhat-operator-index:v4.19",
+					IncludeConfig: types.IncludeConfig{
+						Packages: []types.IncludePackage{
+							{Name: "lvms-operator", InstallConfig: types.InstallConfig{
+								Install: swag.Bool(true), InstallPlanApproval: swag.String(InstallPlanApprovalManual)}},
+							{Name: "local-storage-operator", InstallConfig: types.InstallConfig{
+								Install: swag.Bool(true), InstallPlanApproval: swag.String("Always")}},
+							{Name: "kubevirt-hyperconverged", InstallConfig: types.InstallConfig{
+								Install: swag.Bool(true), Namespace: swag.String("Invalid_Namespace")}},
+							{Name: "cluster-logging", InstallConfig: types.InstallConfig{
+								Install: swag.Bool(true), TargetNamespaces: &[]string{}}},
+							{Name: "elasticsearch-operator", InstallConfig: types.InstallConfig{
+								Install: swag.Bool(true), TargetNamespaces: &[]string{"operators", "Invalid_Namespace"}}},
+							{Name: "sriov-network-operator", InstallConfig: types.InstallConfig{
+								Install: swag.Bool(true), Namespace: swag.String(consts.GlobalOperatorsNamespace),
+								TargetNamespaces: &[]string{}}},
+						},
+					},
+				}},
+			},
+		}}
+		fields := []string{}
+		for _, err := range applianceConfig.validateOperatorsInstall() {
+			fields = append(fields, err.Field)
+		}
+		Expect(fields).To(ConsistOf("mirror.operators[0].packages[1].installPlanApproval",
+			"mirror.operators[0].packages[2].namespace",
+			"mirror.operators[0].packages[4].targetNamespaces[1]",
+			"mirror.operators[0].packages[5].targetNamespaces"))
+	})
+})
+
+var _ = Describe("validateCatalogSourceNames", func() {
+	It("validates the CatalogSources config", func() {
+		applianceConfig := &ApplianceConfig{Config: &types.ApplianceConfig{
+			Mirror: types.MirrorConfig{
+				Operators: &[]types.Operator{
+					{Catalog: "registry.redhat.io/redhat/redhat-operator-index:v4.19"},
+					{Catalog: "registry.redhat.io/redhat/certified-operator-index:v4.19"},
+					{Catalog: "registry.redhat.io/redhat/community-operator-index:v4.19"},
+				},
+			},
+			Cluster: types.ClusterConfig{
+				CatalogSourceNames: map[string]types.CatalogSourceConfig{
+					"registry.redhat.io/redhat/redhat-operator-index:v4.19": {
+						Name: swag.String("operators"), Priority: swag.Int(10), PollInterval: swag.String("30m")},
+					"registry.redhat.io/redhat/certified-operator-index:v4.19": {
+						Name: swag.String("Certified_Operators"), PollInterval: swag.String("often")},
+					"registry.redhat.io/redhat/community-operator-index:v4.19": {Name: swag.String("operators")},
+					"quay.io/my-org/my-operator-index:v1":                      {Name: swag.String("my-operators")},
+				},
+			},
+		}}
+		fields := []string{}
+		for _, err := range applianceConfig.validateCatalogSourceNames() {
+			fields = append(fields, err.Field)
+		}
+		Expect(fields).To(ConsistOf(
+			"cluster.catalogSourceNames[quay.io/my-org/my-operator-index:v1]",
+			"cluster.catalogSourceNames[registry.redhat.io/redhat/certified-operator-index:v4.19].name",
+			"cluster.catalogSourceNames[registry.redhat.io/redhat/certified-operator-index:v4.19].pollInterval",
+			"cluster.catalogSourceNames[registry.redhat.io/redhat/redhat-operator-index:v4.19].name"))
+	})
+})
+
+// fakeFileFetcher fetches the files of the map (by name)
+type fakeFileFetcher map[string][]byte
+
+func (f fakeFileFetcher) FetchByName(name string) (*asset.File, error) {
+	data, ok := f[name]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return &asset.File{Filename: name, Data: data}, nil
+}
+
+func (f fakeFileFetcher) FetchByPattern(pattern string) ([]*asset.File, error) {
+	return nil, nil
+}
+
+var _ = Describe("validateHelm", func() {
+	It("validates the helm charts config", func() {
+		applianceConfig := &ApplianceConfig{Config: &types.ApplianceConfig{
+			Mirror: types.MirrorConfig{
+				Helm: &types.Helm{
+					Repositories: []types.HelmRepository{
+						{
+							Name:   "podinfo",
+							URL:    "https://stefanprodan.github.io/podinfo",
+							Charts: []types.HelmChart{{Name: "podinfo", Version: "5.0.0"}, {Version: "6.0.0"}},
+						},
+						{URL: "stefanprodan.github.io/podinfo"},
+					},
+					Local: []types.HelmChart{
+						{Name: "my-chart", Path: "charts/my-chart-0.1.0.tgz"},
+						{Name: "missing", Path: "charts/missing-0.1.0.tgz"},
+						{Path: "/nonexistent/chart-0.1.0.tgz"},
+						{Name: "no-path"},
+					},
+				},
+			},
+		}}
+		f := fakeFileFetcher{"charts/my-chart-0.1.0.tgz": []byte("chart")}
+		fields := []string{}
+		for _, err := range applianceConfig.validateHelm(f) {
+			fields = append(fields, err.Field)
+		}
+		Expect(fields).To(ConsistOf(
+			"mirror.helm.repositories[0].charts[1].name",
+			"mirror.helm.repositories[1].name",
+			"mirror.helm.repositories[1].url",
+			"mirror.helm.local[1].path",
+			"mirror.helm.local[2].name",
+			"mirror.helm.local[2].path",
+			"mirror.helm.local[3].path"))
+	})
+})
+
+var _ = Describe("MarshalJSON", func() {
+	var applianceConfig *ApplianceConfig
+
+	BeforeEach(func() {
+		data := []byte(`apiVersion: v1beta2
+kind: ApplianceConfig
+ocpRelease:
+  version: "4.18"
+pullSecret: '{"auths":{"quay.io":{"auth":"dXNlcjpzZWNyZXQtdG9rZW4="}}}'
+userCorePass: core-password
+`)
+		config, _, err := ParseApplianceConfig(data)
+		Expect(err).ToNot(HaveOccurred())
+		applianceConfig = &ApplianceConfig{
+			File:   &asset.File{Filename: ApplianceConfigFilename, Data: data},
+			Config: config,
+		}
+	})
+
+	AfterEach(func() {
+		loadedApplianceConfig = nil
+	})
+
+	It("excludes the secrets from the persisted asset", func() {
+		state, err := json.Marshal(applianceConfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(state)).ToNot(ContainSubstring("dXNlcjpzZWNyZXQtdG9rZW4="))
+		Expect(string(state)).ToNot(ContainSubstring("core-password"))
+		Expect(applianceConfig.Config.PullSecret).To(ContainSubstring("dXNlcjpzZWNyZXQtdG9rZW4="))
+		Expect(swag.StringValue(applianceConfig.Config.UserCorePass)).To(Equal("core-password"))
+	})
+
+	It("restores the secrets of an unchanged asset from the loaded config", func() {
+		state, err := json.Marshal(applianceConfig)
+		Expect(err).ToNot(HaveOccurred())
+
+		loadedApplianceConfig = applianceConfig
+		persisted := &ApplianceConfig{}
+		Expect(json.Unmarshal(state, persisted)).To(Succeed())
+		Expect(persisted).To(Equal(applianceConfig))
+	})
+
+	It("restores the secrets of an unchanged asset from an indented state file", func() {
+		state, err := json.MarshalIndent(applianceConfig, "", "    ")
+		Expect(err).ToNot(HaveOccurred())
+
+		loadedApplianceConfig = applianceConfig
+		persisted := &ApplianceConfig{}
+		Expect(json.Unmarshal(state, persisted)).To(Succeed())
+		Expect(persisted).To(Equal(applianceConfig))
+	})
+
+	It("keeps the secrets redacted when the loaded config has changed", func() {
+		state, err := json.Marshal(applianceConfig)
+		Expect(err).ToNot(HaveOccurred())
+
+		config := *applianceConfig.Config
+		config.SshKey = swag.String("ssh-ed25519 AAAA")
+		loadedApplianceConfig = &ApplianceConfig{File: applianceConfig.File, Config: &config}
+		persisted := &ApplianceConfig{}
+		Expect(json.Unmarshal(state, persisted)).To(Succeed())
+		Expect(swag.StringValue(persisted.Config.UserCorePass)).To(Equal(redact.Value))
+		Expect(persisted).ToNot(Equal(loadedApplianceConfig))
+	})
+
+	It("persists no form of the secrets", func() {
+		state, err := json.Marshal(applianceConfig)
+		Expect(err).ToNot(HaveOccurred())
+
+		config := *applianceConfig.Config
+		config.UserCorePass = swag.String("new-password")
+		changed, err := json.Marshal(&ApplianceConfig{File: applianceConfig.File, Config: &config})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changed).To(Equal(state))
+	})
+
+	It("fails to restore the secrets without a loaded config", func() {
+		state, err := json.Marshal(applianceConfig)
+		Expect(err).ToNot(HaveOccurred())
+
+		persisted := &ApplianceConfig{}
+		Expect(json.Unmarshal(state, persisted)).To(MatchError(ContainSubstring(ApplianceConfigFilename)))
+	})
+})
+
+var _ = Describe("ApplianceConfigSchema", func() {
+	var schema *JSONSchema
+
+	BeforeEach(func() {
+		var err error
+		schema, err = ApplianceConfigSchema()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("documents only existing fields", func() {
+		var paths []string
+		var collect func(s *JSONSchema, prefix string)
+		collect = func(s *JSONSchema, prefix string) {
+			s = s.elem()
+			for name, field := range s.Properties {
+				path := name
+				if prefix != "" {
+					path = prefix + "." + name
+				}
+				paths = append(paths, path)
+				collect(field, path)
+			}
+		}
+		collect(schema, "")
+
+		for path := range applianceConfigDocs {
+			Expect(paths).To(ContainElement(path))
+		}
+	})
+
+	It("takes defaults and limits from consts", func() {
+		port, err := schema.Lookup("registry.port")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(port.Type).To(Equal("integer"))
+		Expect(port.Default).To(Equal(consts.RegistryPort))
+		Expect(*port.Minimum).To(Equal(RegistryMinPort))
+		Expect(*port.Maximum).To(Equal(RegistryMaxPort))
+
+		format, err := schema.Lookup("disk.dataPartitionFormat")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(format.Default).To(Equal(consts.DataPartitionFormat))
+		Expect(format.Enum).To(ContainElement(consts.DataPartitionFormatErofs))
+	})
+
+	It("marks mandatory fields as required", func() {
+		Expect(schema.Required).To(ConsistOf("apiVersion", "kind", "ocpRelease"))
+		operators, err := schema.Lookup("mirror.operators")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(operators.Items.Required).To(ConsistOf("catalog", "packages"))
+	})
+
+	It("looks up fields through arrays", func() {
+		field, err := schema.Lookup("mirror.operators.packages.channels.minVersion")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(field.Type).To(Equal("string"))
+
+		_, err = schema.Lookup("registry.foo")
+		Expect(err).To(MatchError("field registry.foo does not exist"))
+	})
+
+	It("explains a field", func() {
+		var out strings.Builder
+		Expect(schema.Explain(&out, "registry")).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("FIELD:    registry <Object>"))
+		Expect(out.String()).To(ContainSubstring("skipPostInstall\t<boolean>"))
+
+		out.Reset()
+		Expect(schema.Explain(&out, "registry.port")).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("DEFAULT:  5005"))
+	})
+
+	It("generates the template from the documentation", func() {
+		template := schema.Template()
+		Expect(template).To(ContainSubstring("  # Default: 5005\n  # Minimum: 1024\n  # Maximum: 65535\n  # [Optional]\n  # port: 5005\n"))
+		Expect(template).To(ContainSubstring("# users:\n  # Name of the user.\n  # - name: user-name\n"))
+		Expect(template).To(ContainSubstring("    # registry.example.com/my-org/my-operator-index:v1:\n"))
+		Expect(template).ToNot(ContainSubstring("metadata:"))
+
+		// Only the required (and uncommented) fields are set
+		config := types.ApplianceConfig{}
+		Expect(yaml.UnmarshalStrict([]byte(template), &config)).To(Succeed())
+		Expect(config.APIVersion).To(Equal(types.ApplianceConfigApiVersion))
+		Expect(config.Kind).To(Equal(applianceConfigKind))
+		Expect(config.OcpRelease.Version).To(Equal("ocp-release-version"))
+		Expect(*config.OcpRelease.AllowVersionFallback).To(Equal(consts.AllowVersionFallback))
+		Expect(config.PullSecret).To(Equal("pull-secret"))
+		Expect(config.Registry.Port).To(BeNil())
+	})
+
+	It("quotes the template values when needed", func() {
+		Expect(templateScalar(consts.GrubMenuEntryNameRecovery)).To(Equal("'" + consts.GrubMenuEntryNameRecovery + "'"))
+		Expect(templateScalar("")).To(Equal(`""`))
+		Expect(templateScalar(10)).To(Equal("10"))
+	})
+})
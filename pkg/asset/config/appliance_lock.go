@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-openapi/swag"
+	"github.com/openshift/installer/pkg/asset"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/appliance/pkg/types"
+)
+
+const (
+	ApplianceLockFilename = "appliance-lock.yaml"
+
+	applianceLockKind   = "ApplianceLock"
+	applianceLockHeader = "# Generated by openshift-appliance, do not edit.\n# Run 'lock update' to refresh the pinned content.\n"
+)
+
+// loadLock reads appliance-lock.yaml (if exists) and pins the config to the locked content
+func (a *ApplianceConfig) loadLock(f asset.FileFetcher) error {
+	file, err := f.FetchByName(ApplianceLockFilename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to load %s file", ApplianceLockFilename)
+	}
+
+	lock := &types.ApplianceLock{}
+	if err = yaml.UnmarshalStrict(file.Data, lock); err != nil {
+		return errors.Wrapf(err, "can't parse %s", ApplianceLockFilename)
+	}
+	if lock.APIVersion != types.ApplianceLockApiVersion || lock.Kind != applianceLockKind {
+		return errors.Errorf("unsupported %s (apiVersion: %q, kind: %q)", ApplianceLockFilename, lock.APIVersion, lock.Kind)
+	}
+
+	if err = a.applyLock(lock); err != nil {
+		return errors.Wrapf(err, "%s doesn't match %s, run 'lock update' to refresh it", ApplianceLockFilename, a.GetConfigFilename())
+	}
+
+	logrus.Infof("Using content pinned in %s (OCP %s)", ApplianceLockFilename, lock.Release.Version)
+	a.Lock = lock
+	return nil
+}
+
+// applyLock replaces the floating references in the config (release version, operator catalogs,
+// additional images and registry image) with the references pinned in the lock
+func (a *ApplianceConfig) applyLock(lock *types.ApplianceLock) error {
+	// Release
+	requestedVersion := a.Config.OcpRelease.Version
+	if lock.Release.Version != requestedVersion && !strings.HasPrefix(lock.Release.Version, requestedVersion+".") {
+		return errors.Errorf("locked OCP release %s doesn't match ocpRelease.version %s", lock.Release.Version, requestedVersion)
+	}
+	if !strings.Contains(lock.Release.Image, "@") {
+		return errors.Errorf("locked OCP release image isn't pinned by digest: %s", lock.Release.Image)
+	}
+	a.Config.OcpRelease.URL = swag.String(lock.Release.Image)
+
+	// Operator catalogs
+	var operators []types.Operator
+	if a.Config.Mirror.Operators != nil {
+		operators = *a.Config.Mirror.Operators
+	}
+	if len(operators) != len(lock.Operators) {
+		return errors.Errorf("found %d locked operator catalogs, expected %d", len(lock.Operators), len(operators))
+	}
+	for i := range operators {
+		lockedOperator := lock.Operators[i]
+		if lockedOperator.Source != operators[i].Catalog {
+			return errors.Errorf("locked operator catalog %s doesn't match %s", lockedOperator.Source, operators[i].Catalog)
+		}
+		operators[i].Catalog = lockedOperator.Catalog
+	}
+
+	// Additional images
+	if a.Config.Mirror.AdditionalImages != nil {
+		for i, image := range *a.Config.Mirror.AdditionalImages {
+			if strings.Contains(image.Name, "@") {
+				continue
+			}
+			pinned := getLockedImage(lock, image.Name)
+			if pinned == "" {
+				return errors.Errorf("additional image %s isn't pinned", image.Name)
+			}
+			(*a.Config.Mirror.AdditionalImages)[i].Name = pinned
+		}
+	}
+
+	// Registry image (only when configured explicitly, otherwise it's taken from the release)
+	if swag.StringValue(a.Config.Registry.URI) != "" {
+		if lock.RegistryImage.Source != swag.StringValue(a.Config.Registry.URI) {
+			return errors.Errorf("locked registry image %s doesn't match registry.uri %s",
+				lock.RegistryImage.Source, swag.StringValue(a.Config.Registry.URI))
+		}
+		a.Config.Registry.URI = swag.String(lock.RegistryImage.Image)
+	}
+
+	return nil
+}
+
+// getLockedImage returns the pinned reference of a locked image (or empty if not locked)
+func getLockedImage(lock *types.ApplianceLock, source string) string {
+	sources := []string{source}
+	if !strings.Contains(source[strings.LastIndex(source, "/")+1:], ":") {
+		// Mirrored as the latest tag
+		sources = append(sources, source+":latest")
+	}
+	for _, image := range lock.Images {
+		for _, s := range sources {
+			if image.Source == s {
+				return image.Image
+			}
+		}
+	}
+	return ""
+}
+
+// WriteLock writes the appliance-lock.yaml file to the assets folder
+func WriteLock(directory string, lock *types.ApplianceLock) error {
+	lock.APIVersion = types.ApplianceLockApiVersion
+	lock.Kind = applianceLockKind
+
+	data, err := yaml.Marshal(lock)
+	if err != nil {
+		return err
+	}
+
+	lockPath := filepath.Join(directory, ApplianceLockFilename)
+	return os.WriteFile(lockPath, []byte(fmt.Sprintf("%s%s", applianceLockHeader, data)), 0644) // #nosec G306
+}
@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/hashicorp/go-version"
 	"github.com/openshift/appliance/pkg/consts"
+	"github.com/openshift/appliance/pkg/reproducible"
 	"github.com/openshift/installer/pkg/asset"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -15,6 +18,9 @@ import (
 const (
 	CacheDir = "cache"
 	TempDir  = "temp"
+
+	// renderImageSeed is the seed of the rendered ignition configs when no seed is specified
+	renderImageSeed = "ignition-render"
 )
 
 type EnvConfig struct {
@@ -24,6 +30,21 @@ type EnvConfig struct {
 
 	IsLiveISO bool
 
+	// StreamData writes the data ISO directly into the appliance disk image
+	// (instead of storing it in the cache dir first)
+	StreamData bool
+
+	// SourceDateEpoch is the fixed timestamp of the generated images
+	// (reproducible builds, from the SOURCE_DATE_EPOCH env var)
+	SourceDateEpoch *time.Time
+	// ImageSeed is the seed of the images identifiers (e.g. partitions GUIDs)
+	// when SourceDateEpoch is set (buildSeed, or SOURCE_DATE_EPOCH when not configured)
+	ImageSeed string
+
+	// RenderIgnition derives the values that are otherwise random (e.g. the password hash salt)
+	// from a seed also without SourceDateEpoch, so rendered ignition configs can be compared
+	RenderIgnition bool
+
 	DebugBootstrap    bool
 	DebugBaseIgnition bool
 }
@@ -55,6 +76,24 @@ func (e *EnvConfig) Generate(dependencies asset.Parents) error {
 	cacheDirPattern := fmt.Sprintf("%s-%s",
 		applianceConfig.Config.OcpRelease.Version, applianceConfig.GetCpuArchitecture())
 
+	sourceDateEpoch, err := reproducible.GetSourceDateEpoch()
+	if err != nil {
+		return err
+	}
+	e.SourceDateEpoch = sourceDateEpoch
+	if e.SourceDateEpoch != nil {
+		logrus.Infof("Using %s for reproducible output: %s", reproducible.SourceDateEpochEnv, e.SourceDateEpoch.Format(time.RFC3339))
+		e.ImageSeed = applianceConfig.GetBuildSeed()
+		if e.ImageSeed == "" {
+			e.ImageSeed = strconv.FormatInt(e.SourceDateEpoch.Unix(), 10)
+		}
+	} else if e.RenderIgnition {
+		e.ImageSeed = applianceConfig.GetBuildSeed()
+		if e.ImageSeed == "" {
+			e.ImageSeed = renderImageSeed
+		}
+	}
+
 	e.CacheDir = filepath.Join(e.AssetsDir, CacheDir, cacheDirPattern)
 	e.TempDir = filepath.Join(e.AssetsDir, TempDir)
 
@@ -138,7 +177,7 @@ func (e *EnvConfig) FindInAssets(filePattern string) string {
 }
 
 // FindFilesInCache returns the files from cache whose name match the given regexp.
-func (e *EnvConfig) FindFilesInCache(pattern string) (files []*asset.File, err error) {	
+func (e *EnvConfig) FindFilesInCache(pattern string) (files []*asset.File, err error) {
 	matches, err := filepath.Glob(filepath.Join(e.CacheDir, pattern))
 	if err != nil {
 		return nil, err
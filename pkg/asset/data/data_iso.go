@@ -2,33 +2,45 @@ package data
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
+	"github.com/dustin/go-humanize"
 	"github.com/go-openapi/swag"
 	"github.com/openshift/appliance/pkg/asset/config"
 	"github.com/openshift/appliance/pkg/consts"
 	"github.com/openshift/appliance/pkg/executer"
+	"github.com/openshift/appliance/pkg/fileutil"
 	"github.com/openshift/appliance/pkg/genisoimage"
+	"github.com/openshift/appliance/pkg/lock"
 	"github.com/openshift/appliance/pkg/log"
+	"github.com/openshift/appliance/pkg/mkfs"
 	"github.com/openshift/appliance/pkg/registry"
 	"github.com/openshift/appliance/pkg/release"
 	"github.com/openshift/appliance/pkg/releasebundle"
+	"github.com/openshift/appliance/pkg/reproducible"
 	"github.com/openshift/installer/pkg/asset"
 	"github.com/sirupsen/logrus"
 )
 
 const (
 	dataDir        = "data"
-	dataIsoName    = "data.iso"
 	dataVolumeName = "agentdata"
 )
 
 // DataISO is an asset that contains registry images
 // to a recovery partition in the OpenShift-based appliance.
+// Depending on the configured data partition format, the image is either
+// an ISO9660 image or a compressed (squashfs/EROFS) filesystem image.
 type DataISO struct {
-	File *asset.File
-	Size int64
+	File   *asset.File
+	Size   int64
+	Format string
+
+	// DataDirPath is set (instead of File) when the data ISO is streamed
+	// directly into the appliance disk image (see EnvConfig.StreamData).
+	DataDirPath string
 }
 
 var _ asset.Asset = (*DataISO)(nil)
@@ -47,15 +59,19 @@ func (a *DataISO) Generate(dependencies asset.Parents) error {
 	applianceConfig := &config.ApplianceConfig{}
 	dependencies.Get(envConfig, applianceConfig)
 
+	a.Format = applianceConfig.GetDataPartitionFormat()
+	dataImageName := applianceConfig.GetDataImageFileName()
+
 	// Search for ISO in cache dir
-	if fileName := envConfig.FindInCache(consts.DataIsoFileName); fileName != "" {
+	if fileName := envConfig.FindInCache(dataImageName); fileName != "" {
 		logrus.Info("Reusing data ISO from cache")
-		return a.updateAsset(envConfig)
+		return a.updateAsset(envConfig, dataImageName)
 	}
 
 	releaseConfig := release.ReleaseConfig{
 		ApplianceConfig: applianceConfig,
 		EnvConfig:       envConfig,
+		CopyHelmCharts:  true,
 	}
 	r := release.NewRelease(releaseConfig)
 
@@ -102,8 +118,8 @@ func (a *DataISO) Generate(dependencies asset.Parents) error {
 	// When mirror-path is provided, pre-populate the registry data directory before
 	// starting the registry so that bundle.Push() adds release-bundles on top of the
 	// mirrored data rather than overwriting it afterwards.
-	if applianceConfig.Config.MirrorPath != nil && swag.StringValue(applianceConfig.Config.MirrorPath) != "" {
-		if err := copyMirrorRegistryData(swag.StringValue(applianceConfig.Config.MirrorPath), dataDirPath); err != nil {
+	if applianceConfig.Config.Mirror.Path != nil && swag.StringValue(applianceConfig.Config.Mirror.Path) != "" {
+		if err := copyMirrorRegistryData(swag.StringValue(applianceConfig.Config.Mirror.Path), dataDirPath); err != nil {
 			return log.StopSpinner(spinner, err)
 		}
 	}
@@ -112,8 +128,8 @@ func (a *DataISO) Generate(dependencies asset.Parents) error {
 		registry.RegistryConfig{
 			DataDirPath:    dataDirPath,
 			URI:            registryUri,
-			Port:           swag.IntValue(applianceConfig.Config.ImageRegistry.Port),
-			UseBinary:      swag.BoolValue(applianceConfig.Config.ImageRegistry.UseBinary),
+			Port:           swag.IntValue(applianceConfig.Config.Registry.Port),
+			UseBinary:      swag.BoolValue(applianceConfig.Config.Registry.UseBinary),
 			UseOcpRegistry: registry.ShouldUseOcpRegistry(envConfig, applianceConfig),
 		})
 
@@ -124,9 +140,18 @@ func (a *DataISO) Generate(dependencies asset.Parents) error {
 		return log.StopSpinner(spinner, err)
 	}
 
+	// Ensure the mirrored content matches appliance-lock.yaml (if locked)
+	if err = lock.NewLock(lock.LockConfig{
+		EnvConfig:       envConfig,
+		ApplianceConfig: applianceConfig,
+		Release:         r,
+	}).Verify(); err != nil {
+		return log.StopSpinner(spinner, err)
+	}
+
 	// Build and push release bundle image
 	bundle := releasebundle.NewBundle(releasebundle.BundleConfig{
-		Port:           swag.IntValue(applianceConfig.Config.ImageRegistry.Port),
+		Port:           swag.IntValue(applianceConfig.Config.Registry.Port),
 		ReleaseVersion: releaseVersion,
 	})
 	if err = bundle.Push(); err != nil {
@@ -140,18 +165,142 @@ func (a *DataISO) Generate(dependencies asset.Parents) error {
 		return err
 	}
 
+	// Remove blobs left unreferenced by the mirror-path data, the release bundle
+	// push or changes in blockedImages, so they don't end up in the data ISO
 	spinner = log.NewSpinner(
-		"Generating data ISO...",
-		"Successfully generated data ISO",
-		"Failed to generate data ISO",
+		"Running registry garbage collection...",
+		"Successfully completed registry garbage collection",
+		"Failed to run registry garbage collection",
 		envConfig,
 	)
-	spinner.FileToMonitor = dataIsoName
-	imageGen := genisoimage.NewGenIsoImage(nil)
-	if err = imageGen.GenerateImage(envConfig.CacheDir, dataIsoName, dataDirPath, dataVolumeName); err != nil {
+	if err = garbageCollectRegistry(releaseImageRegistry, dataDirPath); err != nil {
 		return log.StopSpinner(spinner, err)
 	}
-	return log.StopSpinner(spinner, a.updateAsset(envConfig))
+	if err = log.StopSpinner(spinner, nil); err != nil {
+		return err
+	}
+
+	// Add the helm charts archives (for installing the charts offline)
+	if err = copyHelmCharts(envConfig, dataDirPath); err != nil {
+		return err
+	}
+
+	if a.isStreamed(envConfig) {
+		// Only calculate the image size, the image itself is written
+		// directly into the appliance disk image
+		a.Size, err = genisoimage.NewGenIsoImage(nil, envConfig.SourceDateEpoch).GetImageSize(dataDirPath, dataVolumeName)
+		if err != nil {
+			return err
+		}
+		a.DataDirPath = dataDirPath
+		logrus.Infof("Data ISO (%s) will be streamed into the appliance disk image", humanize.IBytes(uint64(a.Size)))
+		return nil
+	}
+
+	spinner = log.NewSpinner(
+		fmt.Sprintf("Generating data ISO (%s)...", a.Format),
+		fmt.Sprintf("Successfully generated data ISO (%s)", a.Format),
+		fmt.Sprintf("Failed to generate data ISO (%s)", a.Format),
+		envConfig,
+	)
+	spinner.FileToMonitor = dataImageName
+	if err = generateDataImage(a.Format, envConfig, dataImageName, dataDirPath); err != nil {
+		return log.StopSpinner(spinner, err)
+	}
+	if err = a.updateAsset(envConfig, dataImageName); err != nil {
+		return log.StopSpinner(spinner, err)
+	}
+
+	// The data dir is packaged in the data ISO (cached), so it's no longer needed
+	return log.StopSpinner(spinner, cleanupDataDir(dataDirPath))
+}
+
+// isStreamed returns whether the data ISO should be written directly into the
+// appliance disk image rather than to the cache dir.
+// Streaming is supported only for the ISO9660 format of the disk image flow.
+func (a *DataISO) isStreamed(envConfig *config.EnvConfig) bool {
+	if !envConfig.StreamData || envConfig.IsLiveISO {
+		return false
+	}
+	if a.Format != consts.DataPartitionFormatISO9660 {
+		logrus.Warnf("Streaming the data ISO is not supported for %s format, generating it in the cache dir", a.Format)
+		return false
+	}
+	return true
+}
+
+// Stream writes the data ISO generated from the data dir to w,
+// and removes the data dir once done.
+func (a *DataISO) Stream(envConfig *config.EnvConfig, w io.Writer) error {
+	if err := genisoimage.NewGenIsoImage(nil, envConfig.SourceDateEpoch).StreamImage(a.DataDirPath, dataVolumeName, w); err != nil {
+		return err
+	}
+	return cleanupDataDir(a.DataDirPath)
+}
+
+// cleanupDataDir removes the data dir from the temp dir
+func cleanupDataDir(dataDirPath string) error {
+	logrus.Debugf("Removing data dir: %s", dataDirPath)
+	return os.RemoveAll(dataDirPath)
+}
+
+// generateDataImage packages the data dir into an image of the specified filesystem format
+func generateDataImage(format string, envConfig *config.EnvConfig, imageName, dataDirPath string) error {
+	imagePath := envConfig.CacheDir
+	switch format {
+	case consts.DataPartitionFormatSquashfs:
+		return mkfs.NewMkFs(nil).GenerateSquashfsImage(imagePath, imageName, dataDirPath)
+	case consts.DataPartitionFormatErofs:
+		var uuid string
+		if envConfig.SourceDateEpoch != nil {
+			uuid = reproducible.UUID(envConfig.ImageSeed, dataVolumeName)
+		}
+		return mkfs.NewMkFs(nil).GenerateErofsImage(imagePath, imageName, dataDirPath, dataVolumeName, uuid)
+	default:
+		return genisoimage.NewGenIsoImage(nil, envConfig.SourceDateEpoch).GenerateImage(imagePath, imageName, dataDirPath, dataVolumeName)
+	}
+}
+
+// garbageCollectRegistry runs the registry garbage collector against the data dir
+// and reports the reclaimed space.
+func garbageCollectRegistry(r registry.Registry, dataDirPath string) error {
+	sizeBefore, err := fileutil.GetDirSize(dataDirPath)
+	if err != nil {
+		return err
+	}
+	if err = r.GarbageCollect(); err != nil {
+		return err
+	}
+	sizeAfter, err := fileutil.GetDirSize(dataDirPath)
+	if err != nil {
+		return err
+	}
+
+	reclaimed := sizeBefore - sizeAfter
+	if reclaimed < 0 {
+		reclaimed = 0
+	}
+	logrus.Infof("Registry garbage collection reclaimed %s (%s -> %s)",
+		humanize.IBytes(uint64(reclaimed)), humanize.IBytes(uint64(sizeBefore)), humanize.IBytes(uint64(sizeAfter)))
+	return nil
+}
+
+// copyHelmCharts copies the helm charts archives mirrored by oc-mirror (in the cache dir)
+// into the data dir, i.e. to the 'helm-charts' dir of the data partition.
+func copyHelmCharts(envConfig *config.EnvConfig, dataDirPath string) error {
+	charts, err := filepath.Glob(filepath.Join(envConfig.CacheDir, consts.HelmChartsDir, "*.tgz"))
+	if err != nil {
+		return err
+	}
+	for _, chart := range charts {
+		if err = fileutil.CopyFile(chart, filepath.Join(dataDirPath, consts.HelmChartsDir, filepath.Base(chart))); err != nil {
+			return err
+		}
+	}
+	if len(charts) > 0 {
+		logrus.Infof("Added %d helm charts to the data partition", len(charts))
+	}
+	return nil
 }
 
 // copyMirrorRegistryData copies the Docker registry data from a mirror-path
@@ -184,8 +333,8 @@ func (a *DataISO) Name() string {
 	return "Data ISO"
 }
 
-func (a *DataISO) updateAsset(envConfig *config.EnvConfig) error {
-	dataIsoPath := filepath.Join(envConfig.CacheDir, consts.DataIsoFileName)
+func (a *DataISO) updateAsset(envConfig *config.EnvConfig, dataImageName string) error {
+	dataIsoPath := filepath.Join(envConfig.CacheDir, dataImageName)
 	a.File = &asset.File{Filename: dataIsoPath}
 	f, err := os.Stat(dataIsoPath)
 	if err != nil {
@@ -1,7 +1,6 @@
 package deploy
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,9 +11,12 @@ import (
 	"github.com/openshift/appliance/pkg/consts"
 	"github.com/openshift/appliance/pkg/coreos"
 	"github.com/openshift/appliance/pkg/fileutil"
+	ignitionutil "github.com/openshift/appliance/pkg/ignition"
 	"github.com/openshift/appliance/pkg/log"
+	"github.com/openshift/appliance/pkg/reproducible"
 	"github.com/openshift/appliance/pkg/skopeo"
 	"github.com/openshift/appliance/pkg/syslinux"
+	"github.com/openshift/appliance/pkg/xorriso"
 	"github.com/openshift/assisted-image-service/pkg/isoeditor"
 	"github.com/openshift/installer/pkg/asset"
 	"github.com/sirupsen/logrus"
@@ -64,7 +66,7 @@ func (i *DeployISO) Generate(dependencies asset.Parents) error {
 		EnvConfig:       envConfig,
 	}
 	c := coreos.NewCoreOS(coreOSConfig)
-	ignitionBytes, err := json.Marshal(deployIgnition.Config)
+	ignitionBytes, err := ignitionutil.MarshalIgnitionConfig(&deployIgnition.Config, applianceConfig.GetIgnitionSpecVersion())
 	if err != nil {
 		logrus.Errorf("Failed to marshal deploy ignition to json: %s", err.Error())
 		return err
@@ -139,6 +141,11 @@ func (i *DeployISO) buildDeploymentIso(envConfig *config.EnvConfig, applianceCon
 		return err
 	}
 
+	// Append the kernel arguments (boot.kernelArguments and boot.console)
+	if err = coreos.AppendKargs(deployIsoTempDir, applianceConfig.GetBootKernelArguments()); err != nil {
+		return log.StopSpinner(spinner, err)
+	}
+
 	if err = log.StopSpinner(spinner, nil); err != nil {
 		return err
 	}
@@ -162,9 +169,26 @@ func (i *DeployISO) buildDeploymentIso(envConfig *config.EnvConfig, applianceCon
 		return err
 	}
 
+	// Set fixed timestamps (reproducible builds), before isohybrid writes the partition tables
+	if envConfig.SourceDateEpoch != nil {
+		if err = xorriso.NewXorriso(nil).SetDates(deployIsoFileName, *envConfig.SourceDateEpoch); err != nil {
+			return log.StopSpinner(spinner, err)
+		}
+	}
+
 	hybrid := syslinux.NewIsoHybrid(nil)
-	if err = hybrid.Convert(deployIsoFileName); err != nil {
-		logrus.Errorf("Error creating isohybrid: %s", err)
+	if envConfig.SourceDateEpoch == nil {
+		if err = hybrid.Convert(deployIsoFileName); err != nil {
+			logrus.Errorf("Error creating isohybrid: %s", err)
+		}
+	} else {
+		// Replace the random MBR disk signature and GPT GUIDs as well
+		if err = hybrid.ConvertWithID(deployIsoFileName, reproducible.MBRID(envConfig.ImageSeed)); err != nil {
+			return log.StopSpinner(spinner, err)
+		}
+		if err = reproducible.SetPartitionTableGUIDs(deployIsoFileName, envConfig.ImageSeed); err != nil {
+			return log.StopSpinner(spinner, err)
+		}
 	}
 
 	return log.StopSpinner(spinner, nil)
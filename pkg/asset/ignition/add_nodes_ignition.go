@@ -0,0 +1,129 @@
+package ignition
+
+import (
+	"path/filepath"
+
+	igntypes "github.com/coreos/ignition/v2/config/v3_4/types"
+	"github.com/go-openapi/swag"
+	"github.com/openshift/appliance/pkg/asset/config"
+	"github.com/openshift/appliance/pkg/asset/registry"
+	"github.com/openshift/appliance/pkg/consts"
+	ignitionutil "github.com/openshift/appliance/pkg/ignition"
+	reg "github.com/openshift/appliance/pkg/registry"
+	"github.com/openshift/appliance/pkg/templates"
+	"github.com/openshift/installer/pkg/asset"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	liveIsoUdevRulePath = "/etc/udev/rules.d/99-live-iso.rules"
+	liveIsoUdevRuleURI  = "udev/rules.d/99-live-iso.rules"
+)
+
+// AddNodesIgnition generates the ignition configs for adding nodes to an appliance cluster.
+// Both configs run the local registry from the registry data of the node ISO.
+type AddNodesIgnition struct {
+	// Live is the ignition of the node ISO (merged into the node-joiner ignition)
+	Live igntypes.Config
+	// Install is the ignition of the installed node (set by the live config via assisted-service)
+	Install igntypes.Config
+}
+
+var _ asset.Asset = (*AddNodesIgnition)(nil)
+
+// Name returns the human-friendly name of the asset.
+func (i *AddNodesIgnition) Name() string {
+	return "Add nodes ignition"
+}
+
+// Dependencies returns dependencies used by the asset.
+func (i *AddNodesIgnition) Dependencies() []asset.Asset {
+	return []asset.Asset{
+		&config.EnvConfig{},
+		&config.ApplianceConfig{},
+		&registry.RegistriesConf{},
+	}
+}
+
+// Generate the add nodes ignition configs.
+func (i *AddNodesIgnition) Generate(dependencies asset.Parents) error {
+	envConfig := &config.EnvConfig{}
+	applianceConfig := &config.ApplianceConfig{}
+	registriesConf := &registry.RegistriesConf{}
+	dependencies.Get(envConfig, applianceConfig, registriesConf)
+
+	enableInteractiveFlow := swag.BoolValue(applianceConfig.Config.Cluster.EnableInteractiveFlow)
+	specVersion := applianceConfig.GetIgnitionSpecVersion()
+
+	// Add users (core password, public ssh keys and additional users)
+	pwdHash, err := applianceConfig.GetCorePassHash(envConfig.ImageSeed)
+	if err != nil {
+		return err
+	}
+	users := getPasswdUsers(applianceConfig, pwdHash)
+
+	// Add registry.env file
+	registryImageURI := reg.GetRegistryImageURI(envConfig, applianceConfig)
+	registryEnvFile := ignitionutil.FileFromString(consts.RegistryEnvPath,
+		"root", 0644, templates.GetRegistryEnv(registryImageURI, consts.RegistryDataInstall, ""))
+
+	// Generate the installed node ignition
+	i.Install = igntypes.Config{
+		Ignition: igntypes.Ignition{
+			Version: igntypes.MaxVersion.String(),
+		},
+	}
+	i.Install.Passwd.Users = users
+	if err = addCatalogEntries(&i.Install, AddNodesInstallPhase, &catalogContext{
+		envConfig:       envConfig,
+		applianceConfig: applianceConfig,
+		templateData: templates.GetAddNodesIgnitionTemplateData(
+			false, enableInteractiveFlow, "", applianceConfig.GetDataPartitionFormat()),
+	}); err != nil {
+		return err
+	}
+
+	// Add udev rule for mounting the node ISO
+	if err = addStorageFiles(&i.Install, liveIsoUdevRulePath, liveIsoUdevRuleURI, nil); err != nil {
+		return err
+	}
+	i.Install.Storage.Files = append(i.Install.Storage.Files, registryEnvFile)
+	if err = applyIgnitionOverlays(&i.Install, envConfig.AssetsDir, AddNodesInstallPhase); err != nil {
+		return err
+	}
+
+	installIgnitionConfig, err := ignitionutil.MarshalIgnitionConfig(&i.Install, specVersion)
+	if err != nil {
+		return err
+	}
+
+	// Generate the node ISO ignition
+	i.Live = igntypes.Config{
+		Ignition: igntypes.Ignition{
+			Version: igntypes.MaxVersion.String(),
+		},
+	}
+	i.Live.Passwd.Users = users
+	if err = addCatalogEntries(&i.Live, AddNodesPhase, &catalogContext{
+		envConfig:       envConfig,
+		applianceConfig: applianceConfig,
+		templateData: templates.GetAddNodesIgnitionTemplateData(
+			true, enableInteractiveFlow, string(installIgnitionConfig), applianceConfig.GetDataPartitionFormat()),
+	}); err != nil {
+		return err
+	}
+	i.Live.Storage.Files = append(i.Live.Storage.Files, registryEnvFile)
+
+	// Add registries.conf file (pulling the release images from the local registry)
+	registriesConfFile := ignitionutil.FileFromBytes(filepath.Join(registriesConfFilePath, registriesConfFilename),
+		"root", 0644, registriesConf.File.Data)
+	i.Live.Storage.Files = append(i.Live.Storage.Files, registriesConfFile)
+
+	if err = applyIgnitionOverlays(&i.Live, envConfig.AssetsDir, AddNodesPhase); err != nil {
+		return err
+	}
+
+	logrus.Debug("Successfully generated add nodes ignition")
+
+	return nil
+}
@@ -1,21 +1,17 @@
 package ignition
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 
 	"github.com/coreos/ignition/v2/config/util"
 	"github.com/go-openapi/swag"
 	"github.com/pkg/errors"
 
-	igntypes "github.com/coreos/ignition/v2/config/v3_2/types"
+	igntypes "github.com/coreos/ignition/v2/config/v3_4/types"
 	agentManifests "github.com/openshift/installer/pkg/asset/agent/manifests"
-	ignasset "github.com/openshift/installer/pkg/asset/ignition"
-	"golang.org/x/crypto/bcrypt"
 	"sigs.k8s.io/yaml"
 
 	configv1 "github.com/openshift/api/config/v1"
@@ -23,11 +19,11 @@ import (
 	"github.com/openshift/appliance/pkg/asset/manifests"
 	"github.com/openshift/appliance/pkg/asset/registry"
 	"github.com/openshift/appliance/pkg/consts"
+	ignitionutil "github.com/openshift/appliance/pkg/ignition"
 	reg "github.com/openshift/appliance/pkg/registry"
 	"github.com/openshift/appliance/pkg/release"
 	"github.com/openshift/appliance/pkg/templates"
 	"github.com/openshift/installer/pkg/asset"
-	"github.com/openshift/installer/pkg/asset/ignition/bootstrap"
 	"github.com/openshift/installer/pkg/asset/password"
 	mcfgv1 "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
 	"github.com/sirupsen/logrus"
@@ -52,32 +48,6 @@ type CatalogSourceSpec struct {
 }
 type SourceType string
 
-var (
-	bootstrapServices = []string{
-		"start-local-registry.service",
-		"assisted-service.service",
-		"create-cluster-and-infraenv.service",
-		"pre-install.service",
-		"pre-install-node-zero.service",
-		"update-hosts.service",
-		"watch-iri-tls-certs.path",
-	}
-
-	bootstrapScripts = []string{
-		"load-registry-image.sh",
-		"setup-local-registry.sh",
-		"set-env-files.sh",
-		"pre-install.sh",
-		"pre-install-node-zero.sh",
-		"release-image-download.sh",
-		"release-image.sh",
-		"update-hosts.sh",
-		"create-virtual-device.sh",
-		"mount-agent-data.sh",
-		"reconfigure-local-registry-iri-tls.sh",
-	}
-)
-
 // BootstrapIgnition generates the bootstrap ignition file for the recovery ISO
 type BootstrapIgnition struct {
 	Config igntypes.Config
@@ -124,27 +94,8 @@ func (i *BootstrapIgnition) Generate(dependencies asset.Parents) error {
 		},
 	}
 
-	if envConfig.DebugBootstrap {
-		// Avoid machine reboot after bootstrap to debug install ignition
-		bootstrapServices = append(bootstrapServices, "ironic-agent.service")
-	}
-
-	// Add registry service from appropriate directory (OCP or default)
-	registryServiceDir := "services/local-registry-default"
-	if useOcpRegistry {
-		registryServiceDir = "services/local-registry-ocp"
-	}
-	if err := bootstrap.AddSystemdUnits(&i.Config, registryServiceDir, nil, bootstrapServices); err != nil {
-		return err
-	}
-
-	// Add services exclusive for bootstrap
-	if err := bootstrap.AddSystemdUnits(&i.Config, "services/bootstrap", nil, bootstrapServices); err != nil {
-		return err
-	}
-
 	// Fetch install ignition config
-	installIgnitionConfig, err := json.Marshal(installIgnition.Config)
+	installIgnitionConfig, err := ignitionutil.MarshalIgnitionConfig(&installIgnition.Config, applianceConfig.GetIgnitionSpecVersion())
 	if err != nil {
 		return err
 	}
@@ -153,76 +104,66 @@ func (i *BootstrapIgnition) Generate(dependencies asset.Parents) error {
 	coreosImagePattern := fmt.Sprintf(consts.CoreosImagePattern, applianceConfig.GetCpuArchitecture())
 	coreosImagePath := envConfig.FindInCache(coreosImagePattern)
 
-	// Add bootstrap scripts to ignition
+	// Add bootstrap services and scripts to ignition
 	templateData := templates.GetBootstrapIgnitionTemplateData(
 		envConfig.IsLiveISO,
-		swag.BoolValue(applianceConfig.Config.EnableInteractiveFlow),
+		swag.BoolValue(applianceConfig.Config.Cluster.EnableInteractiveFlow),
 		applianceConfig.Config.OcpRelease,
 		string(installIgnitionConfig),
 		coreosImagePath,
-		rendezvousHostEnvPlaceholder)
-	for _, script := range bootstrapScripts {
-		if err = bootstrap.AddStorageFiles(&i.Config,
-			"/usr/local/bin/"+script,
-			"scripts/bin/"+script+".template",
-			templateData); err != nil {
-			return err
-		}
+		rendezvousHostEnvPlaceholder,
+		applianceConfig.GetDataPartitionFormat())
+	if err = addCatalogEntries(&i.Config, BootstrapPhase, &catalogContext{
+		envConfig:       envConfig,
+		applianceConfig: applianceConfig,
+		templateData:    templateData,
+	}); err != nil {
+		return err
 	}
 
-	passwdUser := igntypes.PasswdUser{
-		Name: "core",
-	}
 	// Add user 'core' password
-	if applianceConfig.Config.UserCorePass != nil {
-		var passBytes []byte
-		passBytes, err = bcrypt.GenerateFromPassword([]byte(*applianceConfig.Config.UserCorePass), bcrypt.DefaultCost)
-		if err != nil {
-			return err
-		}
-		pwdHash := string(passBytes)
-		passwdUser.PasswordHash = &pwdHash
-
+	pwdHash, err := applianceConfig.GetCorePassHash(envConfig.ImageSeed)
+	if err != nil {
+		return err
+	}
+	if pwdHash != "" {
 		// Add 'appliance-override-password-set' file
 		// (needed as an indication that the appliance override the core pass)
-		overridePassFile := ignasset.FileFromString(
+		overridePassFile := ignitionutil.FileFromString(
 			corePassOverrideFilePath, "root", 0644, "")
 		i.Config.Storage.Files = append(i.Config.Storage.Files, overridePassFile)
+	}
 
-		// Add MachineConfigs to set core password post installation
-		if err = i.setCoreUserPass("master", pwdHash); err != nil {
+	// Add MachineConfigs to set the users and the kernel arguments post installation
+	for _, role := range []string{"master", "worker"} {
+		if err = i.setUsers(role, envConfig, applianceConfig, pwdHash); err != nil {
 			return err
 		}
-		if err = i.setCoreUserPass("worker", pwdHash); err != nil {
+		if err = i.setKernelArguments(role, applianceConfig); err != nil {
 			return err
 		}
 	}
 
 	// Add registry.env file
 	registryImageURI := reg.GetRegistryImageURI(envConfig, applianceConfig)
-	registryEnvFile := ignasset.FileFromString(consts.RegistryEnvPath,
+	registryEnvFile := ignitionutil.FileFromString(consts.RegistryEnvPath,
 		"root", 0644, templates.GetRegistryEnv(registryImageURI, consts.RegistryDataInstall, ""))
 	i.Config.Storage.Files = append(i.Config.Storage.Files, registryEnvFile)
 
-	// Add public ssh key
-	if applianceConfig.Config.SshKey != nil {
-		passwdUser.SSHAuthorizedKeys = []igntypes.SSHAuthorizedKey{
-			igntypes.SSHAuthorizedKey(*applianceConfig.Config.SshKey),
-		}
-	}
-	i.Config.Passwd.Users = append(i.Config.Passwd.Users, passwdUser)
+	// Add users (core password, public ssh keys and additional users)
+	i.Config.Passwd.Users = append(i.Config.Passwd.Users, getPasswdUsers(applianceConfig, pwdHash)...)
 
 	// Add cluster-image-set file
 	clusterImageSet := &manifests.ClusterImageSet{}
 	if err = clusterImageSet.Generate(dependencies); err != nil {
 		return err
 	}
-	clusterImageSetFile := ignasset.FileFromBytes(filepath.Join("/etc/assisted", filepath.Base(clusterImageSet.File.Filename)),
+	clusterImageSetFile := ignitionutil.FileFromBytes(filepath.Join("/etc/assisted", filepath.Base(clusterImageSet.File.Filename)),
 		"root", 0644, clusterImageSet.File.Data)
 	i.Config.Storage.Files = append(i.Config.Storage.Files, clusterImageSetFile)
 
 	// Add registries.conf file
-	registriesConfFile := ignasset.FileFromBytes(filepath.Join(registriesConfFilePath, registriesConfFilename),
+	registriesConfFile := ignitionutil.FileFromBytes(filepath.Join(registriesConfFilePath, registriesConfFilename),
 		"root", 0644, registriesConf.File.Data)
 	i.Config.Storage.Files = append(i.Config.Storage.Files, registriesConfFile)
 
@@ -243,29 +184,37 @@ func (i *BootstrapIgnition) Generate(dependencies asset.Parents) error {
 		}
 	}
 
-	// Add extra manifests files (from 'openshift' dir and 'cluster-resources')
+	// Generate the manifests of the operator packages to install
+	operatorManifests, err := generateOperatorManifests(applianceConfig, mirrorResources)
+	if err != nil {
+		return err
+	}
+
+	// Add extra manifests files (from 'openshift' dir, 'cluster-resources' and the operators to install)
 	fileList := append(extraManifests.FileList, mirrorResources...)
-	if err = addExtraManifests(
-		&i.Config,
-		fileList,
-		extraManifestsPath,
-		applianceConfig.Config.UseDefaultSourceNames); err != nil {
+	fileList = append(fileList, operatorManifests...)
+	if err = addExtraManifests(&i.Config, fileList, extraManifestsPath, applianceConfig); err != nil {
 		return err
 	}
 
 	// Disable all default CatalogSources to avoid failure on disconnected envs
-	if !swag.BoolValue(applianceConfig.Config.EnableDefaultSources) {
+	if !swag.BoolValue(applianceConfig.Config.Cluster.EnableDefaultSources) {
 		if err := i.disableDefaultCatalogSources(); err != nil {
 			return err
 		}
 	}
 
-	if swag.BoolValue(applianceConfig.Config.CreatePinnedImageSets) {
+	if swag.BoolValue(applianceConfig.Config.Cluster.CreatePinnedImageSets) {
 		if err := i.addPinnedImageSetConfigFiles(envConfig, applianceConfig); err != nil {
 			return err
 		}
 	}
 
+	// Merge user-supplied ignition overlays
+	if err := applyIgnitionOverlays(&i.Config, envConfig.AssetsDir, BootstrapPhase); err != nil {
+		return err
+	}
+
 	logrus.Debug("Successfully generated bootstrap ignition")
 
 	return nil
@@ -276,7 +225,7 @@ func addExtraManifests(
 	config *igntypes.Config,
 	fileList []*asset.File,
 	destPath string,
-	useDefaultSourceNames *bool) error {
+	applianceConfig *config.ApplianceConfig) error {
 
 	user := "root"
 	mode := 0644
@@ -313,27 +262,21 @@ func addExtraManifests(
 				// We use CatalogSource instead
 				continue
 			}
-			if strings.Contains(fileString, "CatalogSource") {
-				// Convert to default source naming (e.g. redhat-operators)
-				if swag.BoolValue(useDefaultSourceNames) {
-					fileBytes, err = convertToDefaultSourceNaming(fileBytes)
-					if err != nil {
-						return err
-					}
+			if strings.Contains(fileString, "CatalogSource") && applianceConfig != nil {
+				// Apply the CatalogSource config (e.g. the default source naming, i.e. redhat-operators)
+				fileBytes, err = applyCatalogSourceConfig(fileBytes, applianceConfig)
+				if err != nil {
+					return err
 				}
 			}
 
-			base := filepath.Base(file.Filename)
-			ext := filepath.Ext(file.Filename)
-			baseWithoutExt := strings.TrimSuffix(base, ext)
-			baseFileName := filepath.Join(destPath, baseWithoutExt)
-			fileName := fmt.Sprintf("%s-%d%s", baseFileName, n, ext)
+			fileName := extraManifestFileName(destPath, file.Filename, n)
 
-			if strings.Contains(base, "signature-configmap") {
+			if strings.Contains(filepath.Base(file.Filename), "signature-configmap") {
 				logrus.Infof("Adding signature-configmap to extra manifests: %s", fileName)
 			}
 
-			extraFile := ignasset.FileFromBytes(fileName, user, mode, fileBytes)
+			extraFile := ignitionutil.FileFromBytes(fileName, user, mode, fileBytes)
 			config.Storage.Files = append(config.Storage.Files, extraFile)
 		}
 	}
@@ -341,21 +284,11 @@ func addExtraManifests(
 	return nil
 }
 
-func convertToDefaultSourceNaming(fileBytes []byte) ([]byte, error) {
-	var cs CatalogSource
-	if err := yaml.Unmarshal(fileBytes, &cs); err != nil {
-		return nil, err
-	}
-
-	re := regexp.MustCompile(`.*?(\w+-operator)-index.*`)
-	name := re.ReplaceAllString(cs.Name, "${1}")
-	cs.Name = strings.Replace(name, "operator", "operators", 1)
-
-	fileBytes, err := yaml.Marshal(cs)
-	if err != nil {
-		return nil, err
-	}
-	return fileBytes, nil
+// extraManifestFileName returns the path of the n-th YAML document of an extra manifest file
+func extraManifestFileName(destPath, filename string, n int) string {
+	ext := filepath.Ext(filename)
+	baseWithoutExt := strings.TrimSuffix(filepath.Base(filename), ext)
+	return fmt.Sprintf("%s-%d%s", filepath.Join(destPath, baseWithoutExt), n, ext)
 }
 
 func (i *BootstrapIgnition) disableDefaultCatalogSources() error {
@@ -376,53 +309,7 @@ func (i *BootstrapIgnition) disableDefaultCatalogSources() error {
 		return err
 	}
 	manifestPath := fmt.Sprintf("%s/operatorhub-%s.yaml", extraManifestsPath, operatorHub.Name)
-	manifestFile := ignasset.FileFromBytes(manifestPath, "root", 0644, manifestBytes)
-	i.Config.Storage.Files = append(i.Config.Storage.Files, manifestFile)
-
-	return nil
-}
-
-func (i *BootstrapIgnition) setCoreUserPass(role, pwdHash string) error {
-	// Generate ignition config with user core pass
-	ignConfig := igntypes.Config{
-		Ignition: igntypes.Ignition{
-			Version: igntypes.MaxVersion.String(),
-		},
-		Passwd: igntypes.Passwd{
-			Users: []igntypes.PasswdUser{{
-				Name: "core", PasswordHash: &pwdHash,
-			}},
-		},
-	}
-	ignitionRawExt, err := ignasset.ConvertToRawExtension(ignConfig)
-	if err != nil {
-		return err
-	}
-
-	// Generate the MachineConfig with ignition config
-	machineConfig := &mcfgv1.MachineConfig{
-		TypeMeta: metav1.TypeMeta{
-			APIVersion: mcfgv1.SchemeGroupVersion.String(),
-			Kind:       "MachineConfig",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name: fmt.Sprintf("99-%s-set-core-pass", role),
-			Labels: map[string]string{
-				"machineconfiguration.openshift.io/role": role,
-			},
-		},
-		Spec: mcfgv1.MachineConfigSpec{
-			Config: ignitionRawExt,
-		},
-	}
-
-	// Add the MachineConfig manifest to extra-manifests dir
-	manifestBytes, err := yaml.Marshal(machineConfig)
-	if err != nil {
-		return err
-	}
-	manifestPath := fmt.Sprintf("%s/%s.yaml", extraManifestsPath, machineConfig.Name)
-	manifestFile := ignasset.FileFromBytes(manifestPath, "root", 0644, manifestBytes)
+	manifestFile := ignitionutil.FileFromBytes(manifestPath, "root", 0644, manifestBytes)
 	i.Config.Storage.Files = append(i.Config.Storage.Files, manifestFile)
 
 	return nil
@@ -465,7 +352,7 @@ func (i *BootstrapIgnition) addPinnedImageSetConfigFiles(envConfig *config.EnvCo
 		}
 
 		manifestPath := fmt.Sprintf("%s/%s.yaml", extraManifestsPath, fmt.Sprintf(consts.PinnedImageSetPattern, role))
-		fileIgnitionConfig := ignasset.FileFromBytes(manifestPath, "root", 0644, fileBytes)
+		fileIgnitionConfig := ignitionutil.FileFromBytes(manifestPath, "root", 0644, fileBytes)
 		i.Config.Storage.Files = append(i.Config.Storage.Files, fileIgnitionConfig)
 	}
 
@@ -0,0 +1,262 @@
+package ignition
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/coreos/ignition/v2/config/util"
+	igntypes "github.com/coreos/ignition/v2/config/v3_4/types"
+	"github.com/go-openapi/swag"
+	"github.com/openshift/appliance/pkg/asset/config"
+	ignitionutil "github.com/openshift/appliance/pkg/ignition"
+	"github.com/openshift/appliance/pkg/registry"
+	"github.com/openshift/appliance/pkg/templates"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/thoas/go-funk"
+)
+
+const (
+	scriptsDir  = "scripts/bin"
+	scriptsPath = "/usr/local/bin"
+
+	// userUnitsDir contains user-supplied systemd units and scripts (*.sh),
+	// in a subdir per phase (e.g. deploy, bootstrap and install)
+	userUnitsDir = "openshift/units"
+)
+
+// userUnitExts are the file extensions of the user-supplied systemd units
+var userUnitExts = []string{".service", ".path", ".timer", ".socket", ".target", ".mount"}
+
+// catalogContext is the context for evaluating the catalog entries of a phase
+type catalogContext struct {
+	envConfig       *config.EnvConfig
+	applianceConfig *config.ApplianceConfig
+
+	// templateData of the phase (used by default for rendering the entries)
+	templateData interface{}
+
+	// operatorCRSteps of applying the post-installation CRs (install phase)
+	operatorCRSteps []templates.OperatorCRStep
+}
+
+// condition of a catalog entry
+type condition func(c *catalogContext) bool
+
+func not(cond condition) condition {
+	return func(c *catalogContext) bool {
+		return !cond(c)
+	}
+}
+
+var (
+	never condition = func(c *catalogContext) bool {
+		return false
+	}
+	isLiveISO condition = func(c *catalogContext) bool {
+		return c.envConfig.IsLiveISO
+	}
+	isDebugBootstrap condition = func(c *catalogContext) bool {
+		return c.envConfig.DebugBootstrap
+	}
+	useOcpRegistry condition = func(c *catalogContext) bool {
+		return registry.ShouldUseOcpRegistry(c.envConfig, c.applianceConfig)
+	}
+	skipRegistryPostInstall condition = func(c *catalogContext) bool {
+		return swag.BoolValue(c.applianceConfig.Config.Registry.SkipPostInstall)
+	}
+	stopRegistryPostInstall condition = func(c *catalogContext) bool {
+		return swag.BoolValue(c.applianceConfig.Config.Registry.StopPostInstall)
+	}
+	createPinnedImageSets condition = func(c *catalogContext) bool {
+		return swag.BoolValue(c.applianceConfig.Config.Cluster.CreatePinnedImageSets)
+	}
+)
+
+// noTemplateData is used for entries that aren't rendered with the phase template data
+func noTemplateData(c *catalogContext) interface{} {
+	return nil
+}
+
+func applyOperatorCRsTemplateData(c *catalogContext) interface{} {
+	return templates.GetApplyOperatorCRsTemplateData(c.operatorCRSteps)
+}
+
+// catalogEntry is a systemd unit or a script (in the data dir) of the appliance ignition configs
+type catalogEntry struct {
+	// name of the unit (e.g. 'pre-install.service') or the script (e.g. 'pre-install.sh')
+	name string
+	// dir of the entry in the data dir (scripts are under 'scripts/bin')
+	dir string
+	// phases of the ignition configs that include the entry
+	phases []string
+	// condition for including the entry (default: always)
+	condition condition
+	// condition for enabling the unit (default: always),
+	// i.e. a unit that is started by another unit, a path unit or a udev rule
+	enabled condition
+	// templateData for rendering the entry (default: the phase template data)
+	templateData func(c *catalogContext) interface{}
+}
+
+func (e *catalogEntry) isScript() bool {
+	return e.dir == scriptsDir
+}
+
+// catalog of the systemd units and scripts of the appliance ignition configs
+var catalog = []catalogEntry{
+	// Deploy
+	{name: "deploy.service", dir: "services/deploy", phases: []string{DeployPhase}},
+	{name: "deploy.sh", dir: scriptsDir, phases: []string{DeployPhase}},
+
+	// Local registry
+	{name: "start-local-registry.service", dir: "services/local-registry-default", phases: []string{BootstrapPhase, AddNodesPhase, AddNodesInstallPhase},
+		condition: not(useOcpRegistry), templateData: noTemplateData},
+	{name: "start-local-registry.service", dir: "services/local-registry-ocp", phases: []string{BootstrapPhase, AddNodesPhase, AddNodesInstallPhase},
+		condition: useOcpRegistry, templateData: noTemplateData},
+	{name: "start-local-registry.service", dir: "services/local-registry-default", phases: []string{InstallPhase},
+		condition: not(useOcpRegistry), enabled: not(skipRegistryPostInstall)},
+	{name: "start-local-registry.service", dir: "services/local-registry-ocp", phases: []string{InstallPhase},
+		condition: useOcpRegistry, enabled: not(skipRegistryPostInstall)},
+	{name: "load-registry-image.sh", dir: scriptsDir, phases: []string{BootstrapPhase, AddNodesPhase, AddNodesInstallPhase}},
+	{name: "load-registry-image.sh", dir: scriptsDir, phases: []string{InstallPhase}, condition: not(skipRegistryPostInstall)},
+	{name: "setup-local-registry.sh", dir: scriptsDir, phases: []string{BootstrapPhase, AddNodesPhase, AddNodesInstallPhase}},
+	{name: "setup-local-registry.sh", dir: scriptsDir, phases: []string{InstallPhase}, condition: not(skipRegistryPostInstall)},
+	{name: "stop-local-registry.service", dir: "services/install", phases: []string{InstallPhase}, enabled: stopRegistryPostInstall},
+	{name: "stop-local-registry.sh", dir: scriptsDir, phases: []string{InstallPhase}},
+	{name: "reconfigure-local-registry-iri-tls.service", dir: "services/bootstrap", phases: []string{BootstrapPhase},
+		enabled: never, templateData: noTemplateData},
+	{name: "watch-iri-tls-certs.path", dir: "services/bootstrap", phases: []string{BootstrapPhase}, templateData: noTemplateData},
+	{name: "reconfigure-local-registry-iri-tls.sh", dir: scriptsDir, phases: []string{BootstrapPhase}},
+
+	// Bootstrap
+	{name: "pre-install.service", dir: "services/bootstrap", phases: []string{BootstrapPhase}, templateData: noTemplateData},
+	{name: "pre-install-node-zero.service", dir: "services/bootstrap", phases: []string{BootstrapPhase}, templateData: noTemplateData},
+	{name: "update-hosts.service", dir: "services/bootstrap", phases: []string{BootstrapPhase}, templateData: noTemplateData},
+	{name: "ironic-agent.service", dir: "services/bootstrap", phases: []string{BootstrapPhase},
+		// Avoid machine reboot after bootstrap to debug install ignition
+		enabled: isDebugBootstrap, templateData: noTemplateData},
+	{name: "set-env-files.sh", dir: scriptsDir, phases: []string{BootstrapPhase}},
+	{name: "pre-install.sh", dir: scriptsDir, phases: []string{BootstrapPhase}},
+	{name: "pre-install-node-zero.sh", dir: scriptsDir, phases: []string{BootstrapPhase}},
+	{name: "release-image-download.sh", dir: scriptsDir, phases: []string{BootstrapPhase}},
+	{name: "release-image.sh", dir: scriptsDir, phases: []string{BootstrapPhase}},
+	{name: "update-hosts.sh", dir: scriptsDir, phases: []string{BootstrapPhase}},
+	{name: "create-virtual-device.sh", dir: scriptsDir, phases: []string{BootstrapPhase}},
+	{name: "mount-agent-data.sh", dir: scriptsDir, phases: []string{BootstrapPhase, InstallPhase, AddNodesPhase, AddNodesInstallPhase}},
+
+	// Install
+	{name: "set-node-zero.service", dir: "services/install", phases: []string{InstallPhase}},
+	{name: "set-node-zero.sh", dir: scriptsDir, phases: []string{InstallPhase}},
+	{name: "apply-operator-crs.service", dir: "services/install", phases: []string{InstallPhase}},
+	{name: "apply-operator-crs.sh", dir: scriptsDir, phases: []string{InstallPhase}, templateData: applyOperatorCRsTemplateData},
+	{name: "create-pinned-image-sets.service", dir: "services/install", phases: []string{InstallPhase}, enabled: createPinnedImageSets},
+	{name: "create-pinned-image-sets.sh", dir: scriptsDir, phases: []string{InstallPhase}, condition: createPinnedImageSets},
+	{name: "add-grub-menuitem.service", dir: "services/install", phases: []string{InstallPhase}, enabled: not(isLiveISO)},
+	{name: "add-grub-menuitem.sh", dir: scriptsDir, phases: []string{InstallPhase}, condition: not(isLiveISO)},
+	{name: "mount-live-iso@.service", dir: "services/install", phases: []string{InstallPhase, AddNodesInstallPhase}, enabled: never},
+
+	// Add nodes
+	{name: "update-add-nodes-host.service", dir: "services/add-nodes", phases: []string{AddNodesPhase}, templateData: noTemplateData},
+	{name: "update-add-nodes-host.sh", dir: scriptsDir, phases: []string{AddNodesPhase}},
+
+	// Upgrade (started by udev rules)
+	{name: "start-local-registry-upgrade@.service", dir: "services/install", phases: []string{InstallPhase}, enabled: never},
+	{name: "setup-local-registry-upgrade.sh", dir: scriptsDir, phases: []string{InstallPhase}},
+	{name: "start-cluster-upgrade@.service", dir: "services/install", phases: []string{InstallPhase}, enabled: never},
+	{name: "start-cluster-upgrade.sh", dir: scriptsDir, phases: []string{InstallPhase}},
+}
+
+// addCatalogEntries adds the systemd units and scripts of the phase (from the catalog and the user-supplied ones)
+func addCatalogEntries(ignConfig *igntypes.Config, phase string, c *catalogContext) error {
+	for _, entry := range catalog {
+		if !funk.ContainsString(entry.phases, phase) || (entry.condition != nil && !entry.condition(c)) {
+			continue
+		}
+		templateData := c.templateData
+		if entry.templateData != nil {
+			templateData = entry.templateData(c)
+		}
+
+		if entry.isScript() {
+			if err := addStorageFiles(ignConfig,
+				filepath.Join(scriptsPath, entry.name),
+				filepath.Join(entry.dir, entry.name+".template"),
+				templateData); err != nil {
+				return err
+			}
+			continue
+		}
+
+		unit, err := renderSystemdUnit(entry.dir, entry.name, templateData)
+		if err != nil {
+			return err
+		}
+		if entry.enabled == nil || entry.enabled(c) {
+			unit.Enabled = util.BoolToPtr(true)
+		}
+		ignConfig.Systemd.Units = append(ignConfig.Systemd.Units, unit)
+	}
+
+	return addUserUnits(ignConfig, c.envConfig.AssetsDir, phase)
+}
+
+// addUserUnits adds the user-supplied systemd units (enabled, except for template units) and scripts of the phase.
+// A user-supplied entry overrides a catalog entry with the same name.
+func addUserUnits(ignConfig *igntypes.Config, assetsDir, phase string) error {
+	unitsDir := filepath.Join(assetsDir, userUnitsDir, phase)
+	entries, err := os.ReadDir(unitsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		data, err := os.ReadFile(filepath.Join(unitsDir, name))
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case filepath.Ext(name) == ".sh":
+			script := ignitionutil.FileFromBytes(filepath.Join(scriptsPath, name), "root", 0755, data)
+			ignConfig.Storage.Files = funk.Filter(ignConfig.Storage.Files, func(f igntypes.File) bool {
+				if f.Path == script.Path {
+					logrus.Warnf("User script %s overrides the %s script", name, phase)
+					return false
+				}
+				return true
+			}).([]igntypes.File)
+			ignConfig.Storage.Files = append(ignConfig.Storage.Files, script)
+		case funk.ContainsString(userUnitExts, filepath.Ext(name)):
+			unit := igntypes.Unit{
+				Name:     name,
+				Contents: util.StrToPtr(string(data)),
+			}
+			if !strings.Contains(name, "@") {
+				unit.Enabled = util.BoolToPtr(true)
+			}
+			ignConfig.Systemd.Units = funk.Filter(ignConfig.Systemd.Units, func(u igntypes.Unit) bool {
+				if u.Name == unit.Name {
+					logrus.Warnf("User unit %s overrides the %s unit", name, phase)
+					return false
+				}
+				return true
+			}).([]igntypes.Unit)
+			ignConfig.Systemd.Units = append(ignConfig.Systemd.Units, unit)
+		default:
+			return errors.Errorf("unsupported file %s in %s (expected systemd units or *.sh scripts)",
+				name, filepath.Join(userUnitsDir, phase))
+		}
+		logrus.Infof("Added user %s unit: %s", phase, name)
+	}
+
+	return nil
+}
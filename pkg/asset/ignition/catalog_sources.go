@@ -0,0 +1,126 @@
+package ignition
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-openapi/swag"
+	"github.com/openshift/appliance/pkg/asset/config"
+	"github.com/openshift/appliance/pkg/types"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// defaultSourceNameRegex matches the CatalogSources generated by oc-mirror for the '*-operator-index' catalogs
+var defaultSourceNameRegex = regexp.MustCompile(`.*?(\w+-operator)-index.*`)
+
+// getCatalogSourceConfig returns the config of the CatalogSource of a (mirrored) catalog image:
+// the 'cluster.catalogSourceNames' entry of the catalog, merged over the 'useDefaultSourceNames' naming.
+// Returns nil if the CatalogSource isn't configured.
+func getCatalogSourceConfig(applianceConfig *config.ApplianceConfig, name, image string) *types.CatalogSourceConfig {
+	repository := imageRepositoryPath(image)
+
+	var csConfig *types.CatalogSourceConfig
+	if swag.BoolValue(applianceConfig.Config.Cluster.UseDefaultSourceNames) {
+		csConfig = &types.CatalogSourceConfig{Name: swag.String(defaultSourceName(name))}
+	}
+
+	// Find the catalog entry (preferring the one with the same tag)
+	var catalogs []string
+	for catalog := range applianceConfig.Config.Cluster.CatalogSourceNames {
+		catalogs = append(catalogs, catalog)
+	}
+	sort.Strings(catalogs)
+	var match string
+	for _, catalog := range catalogs {
+		if imageRepositoryPath(catalog) != repository {
+			continue
+		}
+		if match == "" || imageTag(catalog) == imageTag(image) {
+			match = catalog
+		}
+	}
+	if match == "" {
+		return csConfig
+	}
+
+	entry := applianceConfig.Config.Cluster.CatalogSourceNames[match]
+	if csConfig == nil {
+		csConfig = &types.CatalogSourceConfig{}
+	}
+	if entry.Name != nil {
+		csConfig.Name = entry.Name
+	}
+	if entry.DisplayName != nil {
+		csConfig.DisplayName = entry.DisplayName
+	}
+	if entry.Priority != nil {
+		csConfig.Priority = entry.Priority
+	}
+	if entry.PollInterval != nil {
+		csConfig.PollInterval = entry.PollInterval
+	}
+	return csConfig
+}
+
+// applyCatalogSourceConfig sets the configured name, display name, priority and polling
+// of a CatalogSource manifest (other manifests are returned as is)
+func applyCatalogSourceConfig(fileBytes []byte, applianceConfig *config.ApplianceConfig) ([]byte, error) {
+	var cs unstructured.Unstructured
+	if err := yaml.Unmarshal(fileBytes, &cs.Object); err != nil {
+		return nil, err
+	}
+	if cs.GetKind() != "CatalogSource" {
+		return fileBytes, nil
+	}
+
+	image, _, err := unstructured.NestedString(cs.Object, "spec", "image")
+	if err != nil {
+		return nil, err
+	}
+	csConfig := getCatalogSourceConfig(applianceConfig, cs.GetName(), image)
+	if csConfig == nil {
+		return fileBytes, nil
+	}
+
+	if csConfig.Name != nil {
+		cs.SetName(*csConfig.Name)
+	}
+	if csConfig.DisplayName != nil {
+		if err = unstructured.SetNestedField(cs.Object, *csConfig.DisplayName, "spec", "displayName"); err != nil {
+			return nil, err
+		}
+	}
+	if csConfig.Priority != nil {
+		if err = unstructured.SetNestedField(cs.Object, int64(*csConfig.Priority), "spec", "priority"); err != nil {
+			return nil, err
+		}
+	}
+	if csConfig.PollInterval != nil {
+		if err = unstructured.SetNestedField(cs.Object, *csConfig.PollInterval,
+			"spec", "updateStrategy", "registryPoll", "interval"); err != nil {
+			return nil, err
+		}
+	}
+
+	return yaml.Marshal(cs.Object)
+}
+
+// defaultSourceName returns the default naming of a CatalogSource generated by oc-mirror
+// (e.g. 'redhat-operators' for 'cs-redhat-operator-index-v4-19')
+func defaultSourceName(name string) string {
+	name = defaultSourceNameRegex.ReplaceAllString(name, "${1}")
+	return strings.Replace(name, "operator", "operators", 1)
+}
+
+// imageTag returns the tag of the image (empty for a digest or without a tag)
+func imageTag(image string) string {
+	if strings.Contains(image, "@") {
+		return ""
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[i+1:]
+	}
+	return ""
+}
@@ -0,0 +1,102 @@
+package ignition
+
+import (
+	"github.com/go-openapi/swag"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/appliance/pkg/asset/config"
+	"github.com/openshift/appliance/pkg/types"
+)
+
+const testCommunityCatalogSource = `apiVersion: operators.coreos.com/v1alpha1
+kind: CatalogSource
+metadata:
+  name: cs-community-operator-index-v4-19
+  namespace: openshift-marketplace
+spec:
+  image: registry.appliance.openshift.com:22625/redhat/community-operator-index:v4.19
+  sourceType: grpc
+`
+
+var _ = Describe("Test CatalogSource config", func() {
+	var applianceConfig *config.ApplianceConfig
+
+	BeforeEach(func() {
+		applianceConfig = &config.ApplianceConfig{Config: &types.ApplianceConfig{}}
+	})
+
+	It("Keeps the oc-mirror naming by default", func() {
+		data, err := applyCatalogSourceConfig([]byte(testCatalogSource), applianceConfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal(testCatalogSource))
+	})
+
+	It("Uses the default source names", func() {
+		applianceConfig.Config.Cluster.UseDefaultSourceNames = swag.Bool(true)
+
+		data, err := applyCatalogSourceConfig([]byte(testCatalogSource), applianceConfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("name: redhat-operators\n"))
+		Expect(string(data)).NotTo(ContainSubstring("displayName"))
+
+		data, err = applyCatalogSourceConfig([]byte(testCommunityCatalogSource), applianceConfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("name: community-operators\n"))
+	})
+
+	It("Renames any operator index by the default source naming", func() {
+		Expect(defaultSourceName("cs-redhat-operator-index-v4-19")).To(Equal("redhat-operators"))
+		Expect(defaultSourceName("cs-certified-operator-index-v4-19")).To(Equal("certified-operators"))
+		Expect(defaultSourceName("cs-my-custom-operator-index-v1")).To(Equal("custom-operators"))
+		Expect(defaultSourceName("cs-redhat-marketplace-index-v4-19")).To(Equal("cs-redhat-marketplace-index-v4-19"))
+	})
+
+	It("Overrides the default source names by catalogSourceNames", func() {
+		applianceConfig.Config.Cluster.UseDefaultSourceNames = swag.Bool(true)
+		applianceConfig.Config.Cluster.CatalogSourceNames = map[string]types.CatalogSourceConfig{
+			"registry.redhat.io/redhat/redhat-operator-index:v4.18": {Name: swag.String("redhat-operators-418")},
+			"registry.redhat.io/redhat/redhat-operator-index:v4.19": {
+				Priority:     swag.Int(10),
+				PollInterval: swag.String("30m"),
+			},
+		}
+
+		data, err := applyCatalogSourceConfig([]byte(testCatalogSource), applianceConfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("name: redhat-operators\n"))
+		Expect(string(data)).NotTo(ContainSubstring("displayName"))
+		Expect(string(data)).To(ContainSubstring("priority: 10\n"))
+		Expect(string(data)).To(ContainSubstring("interval: 30m\n"))
+		Expect(string(data)).To(ContainSubstring("sourceType: grpc\n"))
+	})
+
+	It("Renames custom catalogs", func() {
+		applianceConfig.Config.Cluster.CatalogSourceNames = map[string]types.CatalogSourceConfig{
+			"quay.io/my-org/community-operator-index:v4.19": {Name: swag.String("wrong-org")},
+			"registry.redhat.io/redhat/community-operator-index:v4.19": {
+				Name:        swag.String("community"),
+				DisplayName: swag.String("Community"),
+			},
+		}
+
+		data, err := applyCatalogSourceConfig([]byte(testCommunityCatalogSource), applianceConfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("name: community\n"))
+		Expect(string(data)).To(ContainSubstring("displayName: Community\n"))
+	})
+
+	It("Ignores other manifests", func() {
+		applianceConfig.Config.Cluster.UseDefaultSourceNames = swag.Bool(true)
+		manifest := "kind: ImageDigestMirrorSet\nmetadata:\n  name: idms-redhat-operator-index\n"
+
+		data, err := applyCatalogSourceConfig([]byte(manifest), applianceConfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal(manifest))
+	})
+
+	It("Parses the image tag", func() {
+		Expect(imageTag("registry.redhat.io/redhat/redhat-operator-index:v4.19")).To(Equal("v4.19"))
+		Expect(imageTag("registry.example.com:5000/catalog")).To(BeEmpty())
+		Expect(imageTag("registry.example.com/catalog@sha256:abc")).To(BeEmpty())
+	})
+})
@@ -0,0 +1,62 @@
+package ignition
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/coreos/ignition/v2/config/v3_4/types"
+	"github.com/go-openapi/swag"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test unit catalog", func() {
+	It("Catalog entries exist in the data dir", func() {
+		for _, entry := range catalog {
+			path := filepath.Join("../../../data", entry.dir, entry.name)
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				path += ".template"
+			}
+			Expect(path).To(BeAnExistingFile(), entry.name)
+			Expect(entry.phases).NotTo(BeEmpty(), entry.name)
+		}
+	})
+
+	It("Add user units and scripts", func() {
+		assetsDir := GinkgoT().TempDir()
+		unitsDir := filepath.Join(assetsDir, userUnitsDir, InstallPhase)
+		Expect(os.MkdirAll(unitsDir, os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(unitsDir, "custom.service"), []byte("[Unit]\n"), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(unitsDir, "custom@.service"), []byte("[Unit]\n"), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(unitsDir, "custom.sh"), []byte("#!/bin/bash\n"), 0600)).To(Succeed())
+
+		ign := &types.Config{
+			Systemd: types.Systemd{
+				Units: []types.Unit{{Name: "custom.service", Contents: swag.String("[Unit]\nDescription=Catalog\n")}},
+			},
+		}
+		Expect(addUserUnits(ign, assetsDir, InstallPhase)).To(Succeed())
+
+		Expect(ign.Systemd.Units).To(HaveLen(2))
+		Expect(ign.Systemd.Units[0].Name).To(Equal("custom.service"))
+		Expect(swag.StringValue(ign.Systemd.Units[0].Contents)).To(Equal("[Unit]\n"))
+		Expect(swag.BoolValue(ign.Systemd.Units[0].Enabled)).To(BeTrue())
+		Expect(ign.Systemd.Units[1].Name).To(Equal("custom@.service"))
+		Expect(ign.Systemd.Units[1].Enabled).To(BeNil())
+		Expect(hasStorageFile(*ign, "/usr/local/bin/custom.sh")).To(BeTrue())
+
+		// Other phases are unaffected
+		ign = &types.Config{}
+		Expect(addUserUnits(ign, assetsDir, BootstrapPhase)).To(Succeed())
+		Expect(ign.Systemd.Units).To(BeEmpty())
+	})
+
+	It("Unsupported user unit", func() {
+		assetsDir := GinkgoT().TempDir()
+		unitsDir := filepath.Join(assetsDir, userUnitsDir, DeployPhase)
+		Expect(os.MkdirAll(unitsDir, os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(unitsDir, "custom.conf"), []byte(""), 0600)).To(Succeed())
+
+		Expect(addUserUnits(&types.Config{}, assetsDir, DeployPhase)).To(MatchError(ContainSubstring("unsupported file custom.conf")))
+	})
+})
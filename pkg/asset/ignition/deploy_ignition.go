@@ -4,26 +4,14 @@ import (
 	"os"
 	"path/filepath"
 
-	igntypes "github.com/coreos/ignition/v2/config/v3_2/types"
-	"github.com/openshift/installer/pkg/asset/ignition"
-	"github.com/openshift/installer/pkg/asset/ignition/bootstrap"
-	"golang.org/x/crypto/bcrypt"
+	igntypes "github.com/coreos/ignition/v2/config/v3_4/types"
 
 	"github.com/openshift/appliance/pkg/asset/config"
+	ignitionutil "github.com/openshift/appliance/pkg/ignition"
 	"github.com/openshift/appliance/pkg/templates"
 	"github.com/openshift/installer/pkg/asset"
 )
 
-var (
-	deployServices = []string{
-		"deploy.service",
-	}
-
-	deployScripts = []string{
-		"deploy.sh",
-	}
-)
-
 type DeployIgnition struct {
 	Config igntypes.Config
 }
@@ -57,47 +45,26 @@ func (i *DeployIgnition) Generate(dependencies asset.Parents) error {
 		},
 	}
 
-	passwdUser := igntypes.PasswdUser{
-		Name: "core",
-	}
-
-	if applianceConfig.Config.UserCorePass != nil {
-		// Add user 'core' password
-		passBytes, err := bcrypt.GenerateFromPassword([]byte(*applianceConfig.Config.UserCorePass), bcrypt.DefaultCost)
-		if err != nil {
-			return err
-		}
-		pwdHash := string(passBytes)
-		passwdUser.PasswordHash = &pwdHash
-	}
-
-	// Add public ssh key
-	if applianceConfig.Config.SshKey != nil {
-		passwdUser.SSHAuthorizedKeys = []igntypes.SSHAuthorizedKey{
-			igntypes.SSHAuthorizedKey(*applianceConfig.Config.SshKey),
-		}
+	// Add users (core password, public ssh keys and additional users)
+	pwdHash, err := applianceConfig.GetCorePassHash(envConfig.ImageSeed)
+	if err != nil {
+		return err
 	}
-	i.Config.Passwd.Users = append(i.Config.Passwd.Users, passwdUser)
+	i.Config.Passwd.Users = append(i.Config.Passwd.Users, getPasswdUsers(applianceConfig, pwdHash)...)
 
 	// Create template data
 	templateData := templates.GetDeployIgnitionTemplateData(
 		deployConfig.TargetDevice, deployConfig.PostScript, deployConfig.SparseClone, deployConfig.DryRun)
 
-	// Add deploy services
-	if err := bootstrap.AddSystemdUnits(&i.Config, "services/deploy", templateData, deployServices); err != nil {
+	// Add deploy services and scripts
+	if err := addCatalogEntries(&i.Config, DeployPhase, &catalogContext{
+		envConfig:       envConfig,
+		applianceConfig: applianceConfig,
+		templateData:    templateData,
+	}); err != nil {
 		return err
 	}
 
-	// Add deploy scripts to ignition
-	for _, script := range deployScripts {
-		if err := bootstrap.AddStorageFiles(&i.Config,
-			filepath.Join("/usr/local/bin/", script),
-			"scripts/bin/"+script+".template",
-			templateData); err != nil {
-			return err
-		}
-	}
-
 	// Add post script if specified
 	if deployConfig.PostScript != "" {
 		data, err := os.ReadFile(filepath.Join(envConfig.AssetsDir, deployConfig.PostScript))
@@ -105,9 +72,14 @@ func (i *DeployIgnition) Generate(dependencies asset.Parents) error {
 			return err
 		}
 		postScript := filepath.Join("/usr/local/bin/", deployConfig.PostScript)
-		file := ignition.FileFromBytes(postScript, "root", 0755, data)
+		file := ignitionutil.FileFromBytes(postScript, "root", 0755, data)
 		i.Config.Storage.Files = append(i.Config.Storage.Files, file)
 	}
 
+	// Merge user-supplied ignition overlays
+	if err := applyIgnitionOverlays(&i.Config, envConfig.AssetsDir, DeployPhase); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -4,7 +4,7 @@ import (
 	"os"
 	"path/filepath"
 
-	igntypes "github.com/coreos/ignition/v2/config/v3_2/types"
+	igntypes "github.com/coreos/ignition/v2/config/v3_4/types"
 	"github.com/go-openapi/swag"
 	"github.com/openshift/appliance/pkg/asset/config"
 	"github.com/openshift/appliance/pkg/asset/manifests"
@@ -13,10 +13,9 @@ import (
 	"github.com/openshift/appliance/pkg/registry"
 	"github.com/openshift/appliance/pkg/templates"
 	"github.com/openshift/installer/pkg/asset"
-	ignasset "github.com/openshift/installer/pkg/asset/ignition"
-	"github.com/openshift/installer/pkg/asset/ignition/bootstrap"
+	agentManifests "github.com/openshift/installer/pkg/asset/agent/manifests"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/crypto/bcrypt"
 )
 
 const (
@@ -30,26 +29,15 @@ const (
 )
 
 var (
-	installServices = []string{
-		"set-node-zero.service",
-		"apply-operator-crs.service",
-	}
-
-	installScripts = []string{
-		"set-node-zero.sh",
-		"setup-local-registry-upgrade.sh",
-		"start-cluster-upgrade.sh",
-		"stop-local-registry.sh",
-		"mount-agent-data.sh",
-		"apply-operator-crs.sh",
-	}
-
 	corePassHash string
 )
 
 // InstallIgnition generates the ignition file for cluster installation phase
 type InstallIgnition struct {
 	Config igntypes.Config
+
+	// SpecVersion is the ignition spec version of the persisted config
+	SpecVersion string
 }
 
 var _ asset.Asset = (*InstallIgnition)(nil)
@@ -86,36 +74,21 @@ func (i *InstallIgnition) Generate(dependencies asset.Parents) error {
 			Version: igntypes.MaxVersion.String(),
 		},
 	}
+	i.SpecVersion = applianceConfig.GetIgnitionSpecVersion()
 
-	if applianceConfig.Config.UserCorePass != nil {
-		// Generate core pass hash
-		passBytes, err := bcrypt.GenerateFromPassword([]byte(*applianceConfig.Config.UserCorePass), bcrypt.DefaultCost)
-		if err != nil {
-			return err
-		}
-		corePassHash = string(passBytes)
-	}
-
-	if !swag.BoolValue(applianceConfig.Config.SkipLocalRegistry) {
-		installServices = append(installServices, "start-local-registry.service")
-		installScripts = append(installScripts, "load-registry-image.sh", "setup-local-registry.sh")
-	}
-
-	if swag.BoolValue(applianceConfig.Config.StopLocalRegistry) {
-		installServices = append(installServices, "stop-local-registry.service")
+	// Generate core pass hash
+	passHash, err := applianceConfig.GetCorePassHash(envConfig.ImageSeed)
+	if err != nil {
+		return err
 	}
+	corePassHash = passHash
 
-	if swag.BoolValue(applianceConfig.Config.CreatePinnedImageSets) {
-		installServices = append(installServices, "create-pinned-image-sets.service")
-		installScripts = append(installScripts, "create-pinned-image-sets.sh")
-	}
+	// Add users (core password, public ssh keys and additional users)
+	i.Config.Passwd.Users = getPasswdUsers(applianceConfig, corePassHash)
 
 	if !envConfig.IsLiveISO {
-		installServices = append(installServices, "add-grub-menuitem.service")
-		installScripts = append(installScripts, "add-grub-menuitem.sh")
-
 		// Add user.cfg file
-		if err := i.addRecoveryGrubConfigFile(envConfig.TempDir, applianceConfig.Config.EnableFips); err != nil {
+		if err := i.addRecoveryGrubConfigFile(envConfig.TempDir, applianceConfig); err != nil {
 			return err
 		}
 	}
@@ -123,56 +96,51 @@ func (i *InstallIgnition) Generate(dependencies asset.Parents) error {
 	// Create install template data
 	templateData := templates.GetInstallIgnitionTemplateData(
 		envConfig.IsLiveISO,
-		swag.BoolValue(applianceConfig.Config.EnableInteractiveFlow),
-		corePassHash)
+		swag.BoolValue(applianceConfig.Config.Cluster.EnableInteractiveFlow),
+		corePassHash,
+		applianceConfig.GetDataPartitionFormat())
 
-	// Add registry service from appropriate directory (OCP or default)
-	registryServiceDir := "services/local-registry-default"
-	if useOcpRegistry {
-		registryServiceDir = "services/local-registry-ocp"
-	}
-	if err := bootstrap.AddSystemdUnits(&i.Config, registryServiceDir, templateData, installServices); err != nil {
+	// Resolve the steps of applying the operators CRs
+	crsPath := filepath.Join(extraManifestsPath, postInstallationCrsDir)
+	operatorCRSteps, err := getOperatorCRSteps(operatorCRs, crsPath)
+	if err != nil {
 		return err
 	}
 
-	// Add services exclusive for install
-	if err := bootstrap.AddSystemdUnits(&i.Config, "services/install", templateData, installServices); err != nil {
+	// Add install services and scripts
+	if err := addCatalogEntries(&i.Config, InstallPhase, &catalogContext{
+		envConfig:       envConfig,
+		applianceConfig: applianceConfig,
+		templateData:    templateData,
+		operatorCRSteps: operatorCRSteps,
+	}); err != nil {
 		return err
 	}
 
-	// Add install scripts to ignition
-	for _, script := range installScripts {
-		if err := bootstrap.AddStorageFiles(&i.Config,
-			"/usr/local/bin/"+script,
-			"scripts/bin/"+script+".template",
-			templateData); err != nil {
-			return err
-		}
-	}
-
 	// Add udev file
-	err := bootstrap.AddStorageFiles(&i.Config, "/etc/udev", "udev", nil)
+	err = addStorageFiles(&i.Config, "/etc/udev", "udev", nil)
 	if err != nil {
 		return err
 	}
 
 	// Add registry.env file
 	registryImageURI := registry.GetRegistryImageURI(envConfig, applianceConfig)
-	registryEnvFile := ignasset.FileFromString(consts.RegistryEnvPath,
+	registryEnvFile := ignitionutil.FileFromString(consts.RegistryEnvPath,
 		"root", 0644, templates.GetRegistryEnv(registryImageURI, consts.RegistryDataInstall, consts.RegistryDataUpgrade))
 	i.Config.Storage.Files = append(i.Config.Storage.Files, registryEnvFile)
 
 	// Add a placeholder for rendezvous-host.env file
-	rendezvousHostEnvFile := ignasset.FileFromString(rendezvousHostEnvFilePath,
+	rendezvousHostEnvFile := ignitionutil.FileFromString(rendezvousHostEnvFilePath,
 		"root", 0644, rendezvousHostEnvPlaceholder)
 	i.Config.Storage.Files = append(i.Config.Storage.Files, rendezvousHostEnvFile)
 
 	// Add operators CR manifests from 'openshift/crs' dir
-	if err := addExtraManifests(
-		&i.Config,
-		operatorCRs.FileList,
-		filepath.Join(extraManifestsPath, postInstallationCrsDir),
-		swag.Bool(false)); err != nil {
+	if err := addExtraManifests(&i.Config, operatorCRs.FileList, crsPath, nil); err != nil {
+		return err
+	}
+
+	// Merge user-supplied ignition overlays
+	if err := applyIgnitionOverlays(&i.Config, envConfig.AssetsDir, InstallPhase); err != nil {
 		return err
 	}
 
@@ -181,11 +149,47 @@ func (i *InstallIgnition) Generate(dependencies asset.Parents) error {
 	return nil
 }
 
-func (i *InstallIgnition) addRecoveryGrubConfigFile(tempDir string, enableFips *bool) error {
+// getOperatorCRSteps returns the steps of applying the operators CRs
+// (with the paths of the CR manifests, as added by addExtraManifests)
+func getOperatorCRSteps(operatorCRs *manifests.OperatorCRs, crsPath string) ([]templates.OperatorCRStep, error) {
+	files := map[string]*asset.File{}
+	for _, file := range operatorCRs.FileList {
+		files[filepath.Base(file.Filename)] = file
+	}
+
+	var steps []templates.OperatorCRStep
+	for _, s := range operatorCRs.Steps {
+		file, ok := files[s.File]
+		if !ok {
+			return nil, errors.Errorf("missing CR file: %s", s.File)
+		}
+		docs, err := agentManifests.GetMultipleYamls[map[string]interface{}](file.Data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not decode YAML for %s", file.Filename)
+		}
+
+		step := templates.OperatorCRStep{CRStep: s}
+		for n := range docs {
+			step.Files = append(step.Files, extraManifestFileName(crsPath, file.Filename, n))
+		}
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+func (i *InstallIgnition) addRecoveryGrubConfigFile(tempDir string, applianceConfig *config.ApplianceConfig) error {
 	// Generate user.cfg
 	if err := templates.RenderTemplateFile(
 		consts.UserCfgTemplateFile,
-		templates.GetUserCfgTemplateData(consts.GrubMenuEntryNameRecovery, swag.BoolValue(enableFips), false),
+		templates.GetUserCfgTemplateData(
+			applianceConfig.GetGrubRecoveryMenuEntryName(),
+			"",
+			applianceConfig.GetGrubTimeout(),
+			swag.StringValue(applianceConfig.Config.Boot.Grub.RecoveryPasswordHash),
+			swag.BoolValue(applianceConfig.Config.Cluster.EnableFips),
+			applianceConfig.GetConsoleArguments(),
+			applianceConfig.GetKernelArguments()),
 		tempDir); err != nil {
 		return err
 	}
@@ -194,7 +198,7 @@ func (i *InstallIgnition) addRecoveryGrubConfigFile(tempDir string, enableFips *
 	if err != nil {
 		return err
 	}
-	cfgFile := ignasset.FileFromBytes(consts.UserCfgFilePath, "root", 0644, cfgFileBytes)
+	cfgFile := ignitionutil.FileFromBytes(consts.UserCfgFilePath, "root", 0644, cfgFileBytes)
 
 	// Append user.cfg to Files
 	i.Config.Storage.Files = append(i.Config.Storage.Files, cfgFile)
@@ -221,5 +225,5 @@ func (i *InstallIgnition) PersistToFile(directory string) error {
 	if err = os.MkdirAll(filepath.Dir(configPath), os.ModePerm); err != nil {
 		return err
 	}
-	return ignition.WriteIgnitionFile(configPath, config)
+	return ignition.WriteIgnitionFile(configPath, config, i.SpecVersion)
 }
@@ -3,9 +3,9 @@ package ignition
 import (
 	"fmt"
 
-	igntypes "github.com/coreos/ignition/v2/config/v3_2/types"
+	igntypes "github.com/coreos/ignition/v2/config/v3_4/types"
+	ignitionutil "github.com/openshift/appliance/pkg/ignition"
 	"github.com/openshift/appliance/pkg/releasebundle"
-	"github.com/openshift/installer/pkg/asset/ignition"
 )
 
 // interactiveFlowIgnition takes care of generating the additional
@@ -28,12 +28,12 @@ func (i *interactiveFlowIgnition) AppendToIgnition(ign *igntypes.Config) {
 }
 
 func (i *interactiveFlowIgnition) appendControlFiles(ign *igntypes.Config) {
-	interactiveUIFile := ignition.FileFromString("/etc/assisted/interactive-ui", "root", 0644, "")
+	interactiveUIFile := ignitionutil.FileFromString("/etc/assisted/interactive-ui", "root", 0644, "")
 	ign.Storage.Files = append(ign.Storage.Files, interactiveUIFile)
 
 	// Explicitly disable the load-config-iso service, not required in the OVE flow
 	// (even though disabled by default, the udev rule may require it).
-	noConfigImageFile := ignition.FileFromString("/etc/assisted/no-config-image", "root", 0644, "")
+	noConfigImageFile := ignitionutil.FileFromString("/etc/assisted/no-config-image", "root", 0644, "")
 	ign.Storage.Files = append(ign.Storage.Files, noConfigImageFile)
 }
 
@@ -57,6 +57,6 @@ spec:
 `, ocpBundleStr)
 
 	// Keep the filepath in sync with openshift/installer#10176 until the installer min storage will be more robust.
-	iriFile := ignition.FileFromString("/etc/assisted/extra-manifests/internalreleaseimage.yaml", "root", 0644, iriContent)
+	iriFile := ignitionutil.FileFromString("/etc/assisted/extra-manifests/internalreleaseimage.yaml", "root", 0644, iriContent)
 	ign.Storage.Files = append(ign.Storage.Files, iriFile)
 }
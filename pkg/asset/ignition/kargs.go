@@ -0,0 +1,60 @@
+package ignition
+
+import (
+	"fmt"
+
+	mcigntypes "github.com/coreos/ignition/v2/config/v3_2/types"
+	"github.com/openshift/appliance/pkg/asset/config"
+	ignitionutil "github.com/openshift/appliance/pkg/ignition"
+	ignasset "github.com/openshift/installer/pkg/asset/ignition"
+	mcfgv1 "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// setKernelArguments adds a MachineConfig for applying the kernel arguments
+// (boot.kernelArguments and boot.console) to the cluster nodes.
+func (i *BootstrapIgnition) setKernelArguments(role string, applianceConfig *config.ApplianceConfig) error {
+	kernelArguments := applianceConfig.GetBootKernelArguments()
+	if len(kernelArguments) == 0 {
+		return nil
+	}
+
+	// The MachineConfig uses spec 3.2 (supported by the MCO of all releases)
+	ignitionRawExt, err := ignasset.ConvertToRawExtension(mcigntypes.Config{
+		Ignition: mcigntypes.Ignition{
+			Version: mcigntypes.MaxVersion.String(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	machineConfig := &mcfgv1.MachineConfig{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: mcfgv1.SchemeGroupVersion.String(),
+			Kind:       "MachineConfig",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("99-%s-appliance-kargs", role),
+			Labels: map[string]string{
+				"machineconfiguration.openshift.io/role": role,
+			},
+		},
+		Spec: mcfgv1.MachineConfigSpec{
+			Config:          ignitionRawExt,
+			KernelArguments: kernelArguments,
+		},
+	}
+
+	// Add the MachineConfig manifest to extra-manifests dir
+	manifestBytes, err := yaml.Marshal(machineConfig)
+	if err != nil {
+		return err
+	}
+	manifestPath := fmt.Sprintf("%s/%s.yaml", extraManifestsPath, machineConfig.Name)
+	manifestFile := ignitionutil.FileFromBytes(manifestPath, "root", 0644, manifestBytes)
+	i.Config.Storage.Files = append(i.Config.Storage.Files, manifestFile)
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package ignition
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/go-openapi/swag"
+	"github.com/openshift/appliance/pkg/asset/config"
+	"github.com/openshift/appliance/pkg/consts"
+	"github.com/openshift/installer/pkg/asset"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	defaultCatalogSourceNs     = "openshift-marketplace"
+	operatorManifestNamePrefix = "operator-install"
+)
+
+type OperatorGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              OperatorGroupSpec `json:"spec"`
+}
+type OperatorGroupSpec struct {
+	TargetNamespaces []string `json:"targetNamespaces,omitempty"`
+}
+
+type Subscription struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              SubscriptionSpec `json:"spec"`
+}
+type SubscriptionSpec struct {
+	Channel             string `json:"channel,omitempty"`
+	Name                string `json:"name"`
+	Source              string `json:"source"`
+	SourceNamespace     string `json:"sourceNamespace"`
+	InstallPlanApproval string `json:"installPlanApproval,omitempty"`
+}
+
+// generateOperatorManifests generates the Namespace, OperatorGroup and Subscription manifests
+// of the operator packages to install (i.e. 'install: true'), subscribed to the CatalogSources
+// generated by oc-mirror (the mirrorResources).
+func generateOperatorManifests(applianceConfig *config.ApplianceConfig, mirrorResources []*asset.File) ([]*asset.File, error) {
+	if applianceConfig.Config.Mirror.Operators == nil {
+		return nil, nil
+	}
+
+	var manifests []*asset.File
+	for _, operator := range *applianceConfig.Config.Mirror.Operators {
+		for _, p := range operator.Packages {
+			if !swag.BoolValue(p.Install) {
+				continue
+			}
+
+			catalogSource, err := findCatalogSource(operator.Catalog, mirrorResources)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to subscribe to operator package %s", p.Name)
+			}
+			source := catalogSource.Name
+			if csConfig := getCatalogSourceConfig(applianceConfig, catalogSource.Name, catalogSource.Spec.Image); csConfig != nil && csConfig.Name != nil {
+				source = *csConfig.Name
+			}
+			sourceNamespace := catalogSource.Namespace
+			if sourceNamespace == "" {
+				sourceNamespace = defaultCatalogSourceNs
+			}
+
+			namespace := swag.StringValue(p.Namespace)
+			if namespace == "" {
+				namespace = p.Name
+			}
+			var channel string
+			if len(p.Channels) > 0 {
+				channel = p.Channels[0].Name
+			}
+
+			var docs [][]byte
+			if namespace != consts.GlobalOperatorsNamespace {
+				namespaceManifest := metav1.PartialObjectMetadata{
+					TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+					ObjectMeta: metav1.ObjectMeta{Name: namespace},
+				}
+				// OwnNamespace install mode by default (an empty list is the AllNamespaces install mode)
+				targetNamespaces := []string{namespace}
+				if p.TargetNamespaces != nil {
+					targetNamespaces = *p.TargetNamespaces
+				}
+				operatorGroup := OperatorGroup{
+					TypeMeta:   metav1.TypeMeta{APIVersion: "operators.coreos.com/v1", Kind: "OperatorGroup"},
+					ObjectMeta: metav1.ObjectMeta{Name: namespace, Namespace: namespace},
+					Spec:       OperatorGroupSpec{TargetNamespaces: targetNamespaces},
+				}
+				for _, obj := range []interface{}{namespaceManifest, operatorGroup} {
+					doc, err := yaml.Marshal(obj)
+					if err != nil {
+						return nil, err
+					}
+					docs = append(docs, doc)
+				}
+			}
+			subscription := Subscription{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "operators.coreos.com/v1alpha1", Kind: "Subscription"},
+				ObjectMeta: metav1.ObjectMeta{Name: p.Name, Namespace: namespace},
+				Spec: SubscriptionSpec{
+					Channel:             channel,
+					Name:                p.Name,
+					Source:              source,
+					SourceNamespace:     sourceNamespace,
+					InstallPlanApproval: swag.StringValue(p.InstallPlanApproval),
+				},
+			}
+			doc, err := yaml.Marshal(subscription)
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, doc)
+
+			logrus.Infof("Subscribing to operator package %s (CatalogSource: %s, namespace: %s)", p.Name, source, namespace)
+			manifests = append(manifests, &asset.File{
+				// The CatalogSource name distinguishes same-named packages of different catalogs
+				Filename: fmt.Sprintf("%s-%s-%s.yaml", operatorManifestNamePrefix, source, p.Name),
+				Data:     bytes.Join(docs, []byte("---\n")),
+			})
+		}
+	}
+
+	return manifests, nil
+}
+
+// findCatalogSource returns the CatalogSource generated by oc-mirror for the catalog image
+// (i.e. the CatalogSource of the mirrored catalog repository)
+func findCatalogSource(catalog string, mirrorResources []*asset.File) (*CatalogSource, error) {
+	repository := imageRepositoryPath(catalog)
+	var found *CatalogSource
+	for _, file := range mirrorResources {
+		var cs CatalogSource
+		if err := yaml.Unmarshal(file.Data, &cs); err != nil || cs.Kind != "CatalogSource" {
+			continue
+		}
+		if imageRepositoryPath(cs.Spec.Image) != repository {
+			continue
+		}
+		if found != nil && found.Name != cs.Name {
+			return nil, errors.Errorf("found multiple CatalogSources for catalog %s: %s, %s", catalog, found.Name, cs.Name)
+		}
+		found = &cs
+	}
+	if found == nil {
+		return nil, errors.Errorf("no CatalogSource found for catalog %s", catalog)
+	}
+	return found, nil
+}
+
+// imageRepositoryPath returns the repository path of the image, without the registry host
+// and the tag/digest (e.g. 'redhat/redhat-operator-index' for 'registry.redhat.io/redhat/redhat-operator-index:v4.19')
+func imageRepositoryPath(image string) string {
+	repository := strings.SplitN(image, "@", 2)[0]
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository = repository[:i]
+	}
+	if parts := strings.SplitN(repository, "/", 2); len(parts) == 2 &&
+		(strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		repository = parts[1]
+	}
+	return repository
+}
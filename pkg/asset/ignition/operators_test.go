@@ -0,0 +1,107 @@
+package ignition
+
+import (
+	"github.com/go-openapi/swag"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/appliance/pkg/asset/config"
+	"github.com/openshift/appliance/pkg/consts"
+	"github.com/openshift/appliance/pkg/types"
+	"github.com/openshift/installer/pkg/asset"
+)
+
+const testCatalogSource = `apiVersion: operators.coreos.com/v1alpha1
+kind: CatalogSource
+metadata:
+  name: cs-redhat-operator-index-v4-19
+  namespace: openshift-marketplace
+spec:
+  image: registry.appliance.openshift.com:22625/redhat/redhat-operator-index:v4.19
+  sourceType: grpc
+`
+
+var _ = Describe("Test operator manifests", func() {
+	var (
+		applianceConfig *config.ApplianceConfig
+		mirrorResources []*asset.File
+	)
+
+	BeforeEach(func() {
+		applianceConfig = &config.ApplianceConfig{Config: &types.ApplianceConfig{
+			Mirror: types.MirrorConfig{
+				Operators: &[]types.Operator{{
+					Catalog: "registry.redhat.io/redhat/redhat-operator-index:v4.19",
+					IncludeConfig: types.IncludeConfig{
+						Packages: []types.IncludePackage{
+							{Name: "lvms-operator", Channels: []types.IncludeChannel{{Name: "stable-4.19"}},
+								InstallConfig: types.InstallConfig{
+									Install:             swag.Bool(true),
+									InstallPlanApproval: swag.String(config.InstallPlanApprovalManual),
+								}},
+							{Name: "kubevirt-hyperconverged"},
+						},
+					},
+				}},
+			},
+		}}
+		mirrorResources = []*asset.File{
+			{Filename: "cluster-resources/idms-oc-mirror.yaml", Data: []byte("kind: ImageDigestMirrorSet\n")},
+			{Filename: "cluster-resources/cs-redhat-operator-index-v4-19.yaml", Data: []byte(testCatalogSource)},
+		}
+	})
+
+	It("Generates the manifests of the packages to install", func() {
+		manifests, err := generateOperatorManifests(applianceConfig, mirrorResources)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(manifests).To(HaveLen(1))
+		Expect(manifests[0].Filename).To(Equal("operator-install-cs-redhat-operator-index-v4-19-lvms-operator.yaml"))
+
+		data := string(manifests[0].Data)
+		Expect(data).To(ContainSubstring("kind: Namespace"))
+		Expect(data).To(ContainSubstring("kind: OperatorGroup"))
+		Expect(data).To(ContainSubstring("kind: Subscription"))
+		Expect(data).To(ContainSubstring("namespace: lvms-operator"))
+		Expect(data).To(ContainSubstring("channel: stable-4.19"))
+		Expect(data).To(ContainSubstring("installPlanApproval: Manual"))
+		Expect(data).To(ContainSubstring("source: cs-redhat-operator-index-v4-19"))
+		Expect(data).To(ContainSubstring("sourceNamespace: openshift-marketplace"))
+	})
+
+	It("Subscribes to the default source name", func() {
+		applianceConfig.Config.Cluster.UseDefaultSourceNames = swag.Bool(true)
+		(*applianceConfig.Config.Mirror.Operators)[0].Packages[0].Namespace = swag.String(consts.GlobalOperatorsNamespace)
+
+		manifests, err := generateOperatorManifests(applianceConfig, mirrorResources)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(manifests).To(HaveLen(1))
+
+		data := string(manifests[0].Data)
+		Expect(data).NotTo(ContainSubstring("kind: OperatorGroup"))
+		Expect(data).To(ContainSubstring("namespace: openshift-operators"))
+		Expect(data).To(ContainSubstring("source: redhat-operators"))
+	})
+
+	It("Generates an OperatorGroup of the target namespaces", func() {
+		(*applianceConfig.Config.Mirror.Operators)[0].Packages[0].TargetNamespaces = &[]string{}
+
+		manifests, err := generateOperatorManifests(applianceConfig, mirrorResources)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(manifests).To(HaveLen(1))
+
+		data := string(manifests[0].Data)
+		Expect(data).To(ContainSubstring("kind: OperatorGroup"))
+		Expect(data).NotTo(ContainSubstring("targetNamespaces"))
+	})
+
+	It("Fails on a missing CatalogSource", func() {
+		_, err := generateOperatorManifests(applianceConfig, mirrorResources[:1])
+		Expect(err).To(MatchError(ContainSubstring("no CatalogSource found for catalog")))
+	})
+
+	It("Parses the image repository path", func() {
+		Expect(imageRepositoryPath("registry.redhat.io/redhat/redhat-operator-index:v4.19")).To(Equal("redhat/redhat-operator-index"))
+		Expect(imageRepositoryPath("registry.appliance.openshift.com:22625/redhat/redhat-operator-index@sha256:abc")).To(Equal("redhat/redhat-operator-index"))
+		Expect(imageRepositoryPath("localhost/catalog:latest")).To(Equal("catalog"))
+		Expect(imageRepositoryPath("quay.io/org/catalog")).To(Equal("org/catalog"))
+	})
+})
@@ -0,0 +1,93 @@
+package ignition
+
+import (
+	"path/filepath"
+	"sort"
+
+	igntypes "github.com/coreos/ignition/v2/config/v3_4/types"
+	ignitionutil "github.com/openshift/appliance/pkg/ignition"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ignitionOverlaysDir contains user-supplied ignition configs (*.ign) and Butane configs (*.bu),
+	// in a subdir per phase (e.g. deploy, bootstrap and install), merged (in lexical order)
+	// into the ignition of the corresponding phase.
+	ignitionOverlaysDir = "ignition"
+
+	// Phases of the appliance ignition configs
+	DeployPhase          = "deploy"
+	BootstrapPhase       = "bootstrap"
+	InstallPhase         = "install"
+	RecoveryPhase        = "recovery"
+	UnconfiguredPhase    = "unconfigured"
+	AddNodesPhase        = "add-nodes"
+	AddNodesInstallPhase = "add-nodes-install"
+)
+
+// Phases are the phases of the appliance ignition configs (in boot order)
+var Phases = []string{
+	DeployPhase,
+	RecoveryPhase,
+	UnconfiguredPhase,
+	BootstrapPhase,
+	InstallPhase,
+	AddNodesPhase,
+	AddNodesInstallPhase,
+}
+
+var ignitionOverlaysPatterns = []string{
+	"*.ign",
+	"*.bu",
+}
+
+// applyIgnitionOverlays validates the overlays of the specified phase and merges them into the ignition config.
+// Entries overridden by an overlay (e.g. a file with the same path) are reported.
+func applyIgnitionOverlays(config *igntypes.Config, assetsDir, phase string) error {
+	overlaysDir := filepath.Join(assetsDir, ignitionOverlaysDir, phase)
+	overlayPaths := []string{}
+	for _, pattern := range ignitionOverlaysPatterns {
+		paths, err := filepath.Glob(filepath.Join(overlaysDir, pattern))
+		if err != nil {
+			return err
+		}
+		overlayPaths = append(overlayPaths, paths...)
+	}
+	sort.Strings(overlayPaths)
+
+	ignition := ignitionutil.NewIgnition(ignitionutil.IgnitionConfig{})
+	for _, overlayPath := range overlayPaths {
+		overlayName, err := filepath.Rel(assetsDir, overlayPath)
+		if err != nil {
+			return err
+		}
+		if overlayName == InstallIgnitionPath {
+			// Generated by the 'debug' command (i.e. not an overlay)
+			continue
+		}
+
+		var overlay *igntypes.Config
+		if filepath.Ext(overlayPath) == ".bu" {
+			overlay, err = ignition.ParseButaneFile(overlayPath)
+		} else {
+			overlay, err = ignition.ParseIgnitionFile(overlayPath)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "invalid ignition overlay %s", overlayName)
+		}
+
+		for _, conflict := range ignitionutil.ConflictingEntries(config, overlay) {
+			logrus.Warnf("Ignition overlay %s overrides the %s ignition %s", overlayName, phase, conflict)
+		}
+
+		merged, err := ignition.MergeIgnitionConfig(config, overlay)
+		if err != nil {
+			return errors.Wrapf(err, "failed to merge ignition overlay %s", overlayName)
+		}
+		*config = *merged
+		logrus.Infof("Applied ignition overlay: %s", overlayName)
+	}
+
+	return nil
+}
@@ -4,8 +4,8 @@ import (
 	"os"
 	"path/filepath"
 
-	configv32 "github.com/coreos/ignition/v2/config/v3_2"
-	igntypes "github.com/coreos/ignition/v2/config/v3_2/types"
+	configv34 "github.com/coreos/ignition/v2/config/v3_4"
+	igntypes "github.com/coreos/ignition/v2/config/v3_4/types"
 	"github.com/go-openapi/swag"
 	"github.com/openshift/appliance/pkg/asset/config"
 	"github.com/openshift/appliance/pkg/asset/manifests"
@@ -69,12 +69,12 @@ func (i *RecoveryIgnition) Generate(dependencies asset.Parents) error {
 		return errors.Wrapf(err, "failed to fetch un-configured ignition")
 	}
 
-	unconfiguredIgnition, _, err := configv32.Parse(configBytes)
+	unconfiguredIgnition, _, err := configv34.ParseCompatibleVersion(configBytes)
 	if err != nil {
 		return errors.Wrapf(err, "failed to parse un-configured ignition")
 	}
 
-	if swag.BoolValue(installerConfig.ApplianceConfig.Config.EnableInteractiveFlow) {
+	if swag.BoolValue(installerConfig.ApplianceConfig.Config.Cluster.EnableInteractiveFlow) {
 		_, releaseVersion, err := installerConfig.ApplianceConfig.GetRelease()
 		if err != nil {
 			return err
@@ -110,7 +110,7 @@ func (i *RecoveryIgnition) Generate(dependencies asset.Parents) error {
 
 	i.Unconfigured = unconfiguredIgnition
 	i.Bootstrap = bootstrapIgnition.Config
-	i.Merged = configv32.Merge(i.Unconfigured, i.Bootstrap)
+	i.Merged = configv34.Merge(i.Unconfigured, i.Bootstrap)
 
 	logrus.Debug("Successfully generated recovery ignition")
 
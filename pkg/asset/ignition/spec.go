@@ -0,0 +1,36 @@
+package ignition
+
+import (
+	mcigntypes "github.com/coreos/ignition/v2/config/v3_2/types"
+	igntypes "github.com/coreos/ignition/v2/config/v3_4/types"
+	ignitionutil "github.com/openshift/appliance/pkg/ignition"
+	"github.com/openshift/installer/pkg/asset/ignition/bootstrap"
+	"github.com/pkg/errors"
+)
+
+// renderSystemdUnit renders the named systemd unit of the dir (disabled)
+// (the installer helper generates spec 3.2 units, so it's translated to the current spec)
+func renderSystemdUnit(uri, name string, templateData interface{}) (igntypes.Unit, error) {
+	generated := mcigntypes.Config{}
+	if err := bootstrap.AddSystemdUnits(&generated, uri, templateData, nil); err != nil {
+		return igntypes.Unit{}, err
+	}
+	for _, unit := range ignitionutil.TranslateConfig(generated).Systemd.Units {
+		if unit.Name == name {
+			return unit, nil
+		}
+	}
+	return igntypes.Unit{}, errors.Errorf("systemd unit %s not found in %s", name, uri)
+}
+
+// addStorageFiles adds the files to the config
+// (the installer helper generates spec 3.2 files, so they're translated to the current spec)
+func addStorageFiles(config *igntypes.Config, base string, uri string, templateData interface{}) error {
+	generated := mcigntypes.Config{}
+	if err := bootstrap.AddStorageFiles(&generated, base, uri, templateData); err != nil {
+		return err
+	}
+	translated := ignitionutil.TranslateConfig(generated)
+	config.Storage.Files = append(config.Storage.Files, translated.Storage.Files...)
+	return nil
+}
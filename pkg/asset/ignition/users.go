@@ -0,0 +1,158 @@
+package ignition
+
+import (
+	"fmt"
+	"os"
+
+	mcigntypes "github.com/coreos/ignition/v2/config/v3_2/types"
+	igntypes "github.com/coreos/ignition/v2/config/v3_4/types"
+	"github.com/go-openapi/swag"
+	"github.com/openshift/appliance/pkg/asset/config"
+	"github.com/openshift/appliance/pkg/consts"
+	ignitionutil "github.com/openshift/appliance/pkg/ignition"
+	"github.com/openshift/appliance/pkg/templates"
+	ignasset "github.com/openshift/installer/pkg/asset/ignition"
+	mcfgv1 "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	coreUserName        = "core"
+	addUsersScriptPath  = "/usr/local/bin/appliance-add-users.sh"
+	addUsersScriptMode  = 0700 // The script contains the password hashes
+	addUsersServiceName = "appliance-add-users.service"
+	// The script is managed by the MCO (i.e. kept on the node) and is idempotent (runs on every boot)
+	addUsersService = `[Unit]
+Description=Add the appliance users
+After=network-online.target
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStart=/usr/local/bin/appliance-add-users.sh
+
+[Install]
+WantedBy=multi-user.target
+`
+)
+
+// getPasswdUsers returns user 'core' (with the password hash and the ssh keys) and the additional users
+func getPasswdUsers(applianceConfig *config.ApplianceConfig, corePassHash string) []igntypes.PasswdUser {
+	coreUser := igntypes.PasswdUser{
+		Name: coreUserName,
+	}
+	if corePassHash != "" {
+		coreUser.PasswordHash = &corePassHash
+	}
+	for _, sshKey := range applianceConfig.GetSshKeys() {
+		coreUser.SSHAuthorizedKeys = append(coreUser.SSHAuthorizedKeys, igntypes.SSHAuthorizedKey(sshKey))
+	}
+
+	passwdUsers := []igntypes.PasswdUser{coreUser}
+	for _, user := range applianceConfig.GetUsers() {
+		passwdUser := igntypes.PasswdUser{
+			Name:         user.Name,
+			PasswordHash: user.PasswordHash,
+		}
+		for _, group := range user.Groups {
+			passwdUser.Groups = append(passwdUser.Groups, igntypes.Group(group))
+		}
+		for _, sshKey := range user.SshKeys {
+			passwdUser.SSHAuthorizedKeys = append(passwdUser.SSHAuthorizedKeys, igntypes.SSHAuthorizedKey(sshKey))
+		}
+		passwdUsers = append(passwdUsers, passwdUser)
+	}
+	return passwdUsers
+}
+
+// setUsers adds a MachineConfig for configuring the users post installation.
+// Note: the MCO supports only user 'core' in the passwd section,
+// so the additional users are created by a service.
+func (i *BootstrapIgnition) setUsers(role string, envConfig *config.EnvConfig, applianceConfig *config.ApplianceConfig, corePassHash string) error {
+	ignConfig := mcigntypes.Config{
+		Ignition: mcigntypes.Ignition{
+			Version: mcigntypes.MaxVersion.String(),
+		},
+	}
+
+	// User 'core' password and additional ssh keys
+	// (sshKey is used only for accessing the appliance, as the cluster uses the install-config sshKey)
+	coreUser := mcigntypes.PasswdUser{
+		Name: coreUserName,
+	}
+	if corePassHash != "" {
+		coreUser.PasswordHash = &corePassHash
+	}
+	if applianceConfig.Config.SshKeys != nil {
+		for _, sshKey := range *applianceConfig.Config.SshKeys {
+			coreUser.SSHAuthorizedKeys = append(coreUser.SSHAuthorizedKeys, mcigntypes.SSHAuthorizedKey(sshKey))
+		}
+	}
+	if coreUser.PasswordHash != nil || len(coreUser.SSHAuthorizedKeys) > 0 {
+		ignConfig.Passwd.Users = []mcigntypes.PasswdUser{coreUser}
+	}
+
+	if users := applianceConfig.GetUsers(); len(users) > 0 {
+		outputDir := envConfig.TempDir
+		if err := templates.RenderTemplateFile(
+			consts.AddUsersTemplateFile,
+			templates.GetAddUsersTemplateData(users),
+			outputDir); err != nil {
+			return err
+		}
+		scriptPath := templates.GetFilePathByTemplate(consts.AddUsersTemplateFile, outputDir)
+		scriptBytes, err := os.ReadFile(scriptPath)
+		if err != nil {
+			return err
+		}
+		if err = os.Remove(scriptPath); err != nil {
+			return err
+		}
+		ignConfig.Storage.Files = append(ignConfig.Storage.Files,
+			ignasset.FileFromBytes(addUsersScriptPath, "root", addUsersScriptMode, scriptBytes))
+		ignConfig.Systemd.Units = append(ignConfig.Systemd.Units, mcigntypes.Unit{
+			Name:     addUsersServiceName,
+			Enabled:  swag.Bool(true),
+			Contents: swag.String(addUsersService),
+		})
+	}
+
+	if len(ignConfig.Passwd.Users) == 0 && len(ignConfig.Systemd.Units) == 0 {
+		return nil
+	}
+
+	// The MachineConfig uses spec 3.2 (supported by the MCO of all releases)
+	ignitionRawExt, err := ignasset.ConvertToRawExtension(ignConfig)
+	if err != nil {
+		return err
+	}
+
+	// Generate the MachineConfig with ignition config
+	machineConfig := &mcfgv1.MachineConfig{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: mcfgv1.SchemeGroupVersion.String(),
+			Kind:       "MachineConfig",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("99-%s-appliance-users", role),
+			Labels: map[string]string{
+				"machineconfiguration.openshift.io/role": role,
+			},
+		},
+		Spec: mcfgv1.MachineConfigSpec{
+			Config: ignitionRawExt,
+		},
+	}
+
+	// Add the MachineConfig manifest to extra-manifests dir
+	manifestBytes, err := yaml.Marshal(machineConfig)
+	if err != nil {
+		return err
+	}
+	manifestPath := fmt.Sprintf("%s/%s.yaml", extraManifestsPath, machineConfig.Name)
+	manifestFile := ignitionutil.FileFromBytes(manifestPath, "root", 0644, manifestBytes)
+	i.Config.Storage.Files = append(i.Config.Storage.Files, manifestFile)
+
+	return nil
+}
@@ -15,6 +15,9 @@ var (
 // OperatorCRs manifests required for activating deployed operators
 type OperatorCRs struct {
 	FileList []*asset.File
+
+	// Steps of applying the CRs (by the order manifest, if exists)
+	Steps []CRStep
 }
 
 var (
@@ -53,9 +56,25 @@ func (em *OperatorCRs) Load(f asset.FileFetcher) (found bool, err error) {
 		return false, errors.Wrap(err, "failed to load *.yml files")
 	}
 
-	em.FileList = append(em.FileList, yamlFileList...)
-	em.FileList = append(em.FileList, ymlFileList...)
+	var orderFile *asset.File
+	for _, file := range append(yamlFileList, ymlFileList...) {
+		if filepath.Base(file.Filename) == CRsOrderFileName {
+			orderFile = file
+			continue
+		}
+		em.FileList = append(em.FileList, file)
+	}
 	asset.SortFiles(em.FileList)
 
+	var order *CRsOrder
+	if orderFile != nil {
+		if order, err = parseCRsOrder(orderFile.Data, em.FileList); err != nil {
+			return false, err
+		}
+	}
+	if em.Steps, err = resolveCRSteps(order, em.FileList); err != nil {
+		return false, err
+	}
+
 	return len(em.FileList) > 0, nil
 }
@@ -0,0 +1,189 @@
+package manifests
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openshift/installer/pkg/asset"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// CRsOrderFileName is the manifest (in 'openshift/crs') declaring the order of the CRs
+	CRsOrderFileName = "order.yaml"
+
+	// defaultReadinessTimeout of a CR readiness check
+	defaultReadinessTimeout = "30m"
+
+	// unorderedWaveName is the wave of the CRs that aren't listed in the order manifest
+	unorderedWaveName = "unordered"
+)
+
+// CRsOrder is the order manifest of the post-installation CRs.
+// The waves are applied sequentially, the CRs of a wave by the listed order.
+// CRs that aren't listed are applied last (sorted by file name).
+type CRsOrder struct {
+	Waves []CRsWave `json:"waves"`
+}
+
+// CRsWave is a group of CRs applied together
+type CRsWave struct {
+	Name string   `json:"name"`
+	CRs  []CRSpec `json:"crs"`
+}
+
+// CRSpec declares how to apply a CR file
+type CRSpec struct {
+	// File name of the CR (in 'openshift/crs')
+	File string `json:"file"`
+	// DependsOn the resources available before applying the CR
+	DependsOn *CRDependency `json:"dependsOn,omitempty"`
+	// Readiness check of the CR after applying it
+	Readiness *CRReadiness `json:"readiness,omitempty"`
+}
+
+// CRDependency is a CSV and/or a CRD the CR depends on
+type CRDependency struct {
+	// CSV name (or name prefix, e.g. 'lvms-operator') that should succeed
+	CSV string `json:"csv,omitempty"`
+	// CSVNamespace of the CSV (default: the namespace of the CR)
+	CSVNamespace string `json:"csvNamespace,omitempty"`
+	// CRD name (e.g. 'lvmclusters.lvm.topolvm.io') that should be established
+	CRD string `json:"crd,omitempty"`
+}
+
+// CRReadiness is a jsonpath condition of the applied CR (as in 'oc wait --for=jsonpath=...')
+type CRReadiness struct {
+	// JSONPath condition (e.g. '{.status.state}=Ready')
+	JSONPath string `json:"jsonpath"`
+	// Timeout for the condition (default: 30m)
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// CRStep is a resolved step of applying a CR
+type CRStep struct {
+	Wave, File                          string
+	CSV, CSVNamespace, CRD              string
+	ReadinessJSONPath, ReadinessTimeout string
+}
+
+// parseCRsOrder parses and validates the order manifest against the CR files
+func parseCRsOrder(data []byte, files []*asset.File) (*CRsOrder, error) {
+	order := &CRsOrder{}
+	if err := yaml.UnmarshalStrict(data, order); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", CRsOrderFileName)
+	}
+
+	fileNames := map[string]bool{}
+	for _, f := range files {
+		fileNames[filepath.Base(f.Filename)] = true
+	}
+
+	listed := map[string]bool{}
+	for i, wave := range order.Waves {
+		if wave.Name == "" || strings.ContainsAny(wave.Name, " \t") {
+			return nil, errors.Errorf("%s: waves[%d].name is required (without whitespaces)", CRsOrderFileName, i)
+		}
+		for j, cr := range wave.CRs {
+			field := fmt.Sprintf("%s: waves[%d].crs[%d]", CRsOrderFileName, i, j)
+			if !fileNames[cr.File] {
+				return nil, errors.Errorf("%s.file: %s not found in %s", field, cr.File, crsDir)
+			}
+			if listed[cr.File] {
+				return nil, errors.Errorf("%s.file: %s is listed more than once", field, cr.File)
+			}
+			listed[cr.File] = true
+
+			if cr.Readiness != nil {
+				if !strings.HasPrefix(cr.Readiness.JSONPath, "{") {
+					return nil, errors.Errorf("%s.readiness.jsonpath: invalid condition %q (e.g. '{.status.phase}=Ready')",
+						field, cr.Readiness.JSONPath)
+				}
+				if cr.Readiness.Timeout != "" {
+					if _, err := time.ParseDuration(cr.Readiness.Timeout); err != nil {
+						return nil, errors.Wrapf(err, "%s.readiness.timeout", field)
+					}
+				}
+			}
+		}
+	}
+
+	return order, nil
+}
+
+// resolveCRSteps returns the steps of applying the CR files:
+// the CRs of the order manifest waves, followed by the unlisted CRs
+// (which wait for all the CSVs in their namespace, if namespaced).
+func resolveCRSteps(order *CRsOrder, files []*asset.File) ([]CRStep, error) {
+	filesByName := map[string]*asset.File{}
+	for _, f := range files {
+		filesByName[filepath.Base(f.Filename)] = f
+	}
+
+	var steps []CRStep
+	listed := map[string]bool{}
+	if order != nil {
+		for _, wave := range order.Waves {
+			for _, cr := range wave.CRs {
+				listed[cr.File] = true
+				step := CRStep{Wave: wave.Name, File: cr.File}
+				if cr.DependsOn != nil {
+					step.CSV = cr.DependsOn.CSV
+					step.CRD = cr.DependsOn.CRD
+					step.CSVNamespace = cr.DependsOn.CSVNamespace
+					if step.CSV != "" && step.CSVNamespace == "" {
+						namespace, err := crNamespace(filesByName[cr.File])
+						if err != nil {
+							return nil, err
+						}
+						if namespace == "" {
+							return nil, errors.Errorf("%s: missing dependsOn.csvNamespace of cluster-scoped CR %s",
+								CRsOrderFileName, cr.File)
+						}
+						step.CSVNamespace = namespace
+					}
+				}
+				if cr.Readiness != nil {
+					step.ReadinessJSONPath = cr.Readiness.JSONPath
+					step.ReadinessTimeout = cr.Readiness.Timeout
+					if step.ReadinessTimeout == "" {
+						step.ReadinessTimeout = defaultReadinessTimeout
+					}
+				}
+				steps = append(steps, step)
+			}
+		}
+	}
+
+	var unlisted []string
+	for name := range filesByName {
+		if !listed[name] {
+			unlisted = append(unlisted, name)
+		}
+	}
+	sort.Strings(unlisted)
+	for _, name := range unlisted {
+		namespace, err := crNamespace(filesByName[name])
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, CRStep{Wave: unorderedWaveName, File: name, CSVNamespace: namespace})
+	}
+
+	return steps, nil
+}
+
+// crNamespace returns the namespace of the (first) CR in the file
+func crNamespace(file *asset.File) (string, error) {
+	doc := strings.SplitN(string(file.Data), "\n---", 2)[0]
+	var obj metav1.PartialObjectMetadata
+	if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+		return "", errors.Wrapf(err, "failed to parse CR %s", file.Filename)
+	}
+	return obj.Namespace, nil
+}
@@ -0,0 +1,87 @@
+package manifests
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/ginkgo/v2/dsl/table"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/installer/pkg/asset"
+)
+
+var _ = Describe("Test operator CRs order", func() {
+	var files []*asset.File
+
+	BeforeEach(func() {
+		files = []*asset.File{
+			{Filename: "openshift/crs/cnv_cr.yaml", Data: []byte("kind: HyperConverged\nmetadata:\n  name: kubevirt-hyperconverged\n  namespace: openshift-cnv\n")},
+			{Filename: "openshift/crs/lvmcluster.yaml", Data: []byte("kind: LVMCluster\nmetadata:\n  name: lvmcluster\n  namespace: openshift-storage\n")},
+			{Filename: "openshift/crs/storageclass.yaml", Data: []byte("kind: StorageClass\nmetadata:\n  name: lvms-default\n")},
+		}
+	})
+
+	It("Applies the unlisted CRs by file name", func() {
+		steps, err := resolveCRSteps(nil, files)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(steps).To(Equal([]CRStep{
+			{Wave: unorderedWaveName, File: "cnv_cr.yaml", CSVNamespace: "openshift-cnv"},
+			{Wave: unorderedWaveName, File: "lvmcluster.yaml", CSVNamespace: "openshift-storage"},
+			{Wave: unorderedWaveName, File: "storageclass.yaml"},
+		}))
+	})
+
+	It("Applies the CRs by the waves", func() {
+		order, err := parseCRsOrder([]byte(`
+waves:
+- name: storage
+  crs:
+  - file: lvmcluster.yaml
+    dependsOn:
+      csv: lvms-operator
+      crd: lvmclusters.lvm.topolvm.io
+    readiness:
+      jsonpath: '{.status.state}=Ready'
+- name: storageclass
+  crs:
+  - file: storageclass.yaml
+    readiness:
+      jsonpath: '{.provisioner}'
+      timeout: 5m
+`), files)
+		Expect(err).ToNot(HaveOccurred())
+
+		steps, err := resolveCRSteps(order, files)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(steps).To(Equal([]CRStep{
+			{Wave: "storage", File: "lvmcluster.yaml", CSV: "lvms-operator", CSVNamespace: "openshift-storage",
+				CRD: "lvmclusters.lvm.topolvm.io", ReadinessJSONPath: "{.status.state}=Ready", ReadinessTimeout: "30m"},
+			{Wave: "storageclass", File: "storageclass.yaml", ReadinessJSONPath: "{.provisioner}", ReadinessTimeout: "5m"},
+			{Wave: unorderedWaveName, File: "cnv_cr.yaml", CSVNamespace: "openshift-cnv"},
+		}))
+	})
+
+	It("Requires the CSV namespace of a cluster-scoped CR", func() {
+		order, err := parseCRsOrder([]byte("waves:\n- name: storage\n  crs:\n  - file: storageclass.yaml\n    dependsOn:\n      csv: lvms-operator\n"), files)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = resolveCRSteps(order, files)
+		Expect(err).To(MatchError(ContainSubstring("missing dependsOn.csvNamespace")))
+	})
+
+	DescribeTable("Invalid order manifest",
+		func(order, expectedErr string) {
+			_, err := parseCRsOrder([]byte(order), files)
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+		},
+		Entry("missing file", "waves:\n- name: a\n  crs:\n  - file: missing.yaml\n", "missing.yaml not found"),
+		Entry("duplicate file", "waves:\n- name: a\n  crs:\n  - file: cnv_cr.yaml\n- name: b\n  crs:\n  - file: cnv_cr.yaml\n", "listed more than once"),
+		Entry("missing wave name", "waves:\n- crs:\n  - file: cnv_cr.yaml\n", "waves[0].name is required"),
+		Entry("invalid jsonpath", "waves:\n- name: a\n  crs:\n  - file: cnv_cr.yaml\n    readiness:\n      jsonpath: status\n", "invalid condition"),
+		Entry("invalid timeout", "waves:\n- name: a\n  crs:\n  - file: cnv_cr.yaml\n    readiness:\n      jsonpath: '{.status}'\n      timeout: soon\n", "readiness.timeout"),
+		Entry("unknown field", "waves:\n- name: a\n  crs:\n  - file: cnv_cr.yaml\n    wait: true\n", "unknown field"),
+	)
+})
+
+func TestManifests(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "manifests_test")
+}
@@ -1,7 +1,6 @@
 package recovery
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,7 +9,9 @@ import (
 	"github.com/openshift/appliance/pkg/asset/ignition"
 	"github.com/openshift/appliance/pkg/consts"
 	"github.com/openshift/appliance/pkg/coreos"
+	ignitionutil "github.com/openshift/appliance/pkg/ignition"
 	"github.com/openshift/appliance/pkg/log"
+	"github.com/openshift/appliance/pkg/xorriso"
 	"github.com/openshift/assisted-image-service/pkg/isoeditor"
 	"github.com/openshift/installer/pkg/asset"
 	"github.com/sirupsen/logrus"
@@ -81,6 +82,11 @@ func (a *RecoveryISO) Generate(dependencies asset.Parents) error {
 			logrus.Errorf("Failed to create ISO: %s", err.Error())
 			return err
 		}
+		if envConfig.SourceDateEpoch != nil {
+			if err := xorriso.NewXorriso(nil).SetDates(recoveryIsoPath, *envConfig.SourceDateEpoch); err != nil {
+				return log.StopSpinner(spinner, err)
+			}
+		}
 	}
 
 	// Embed ignition in ISO
@@ -89,7 +95,7 @@ func (a *RecoveryISO) Generate(dependencies asset.Parents) error {
 		EnvConfig:       envConfig,
 	}
 	c := coreos.NewCoreOS(coreOSConfig)
-	ignitionBytes, err := json.Marshal(recoveryIgnition.Merged)
+	ignitionBytes, err := ignitionutil.MarshalIgnitionConfig(&recoveryIgnition.Merged, applianceConfig.GetIgnitionSpecVersion())
 	if err != nil {
 		logrus.Errorf("Failed to marshal recovery ignition to json: %s", err.Error())
 		return log.StopSpinner(spinner, err)
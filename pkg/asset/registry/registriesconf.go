@@ -81,7 +81,7 @@ func (i *RegistriesConf) Generate(dependencies asset.Parents) error {
 		i.fSys = os.DirFS(envConfig.CacheDir)
 	}
 
-	registries, err := i.generateRegistries(applianceConfig.Config.EnableInteractiveFlow)
+	registries, err := i.generateRegistries(applianceConfig.Config.Cluster.EnableInteractiveFlow)
 	if err != nil {
 		return err
 	}
@@ -132,7 +132,7 @@ func (u *UpgradeISO) Generate(dependencies asset.Parents) error {
 		registry.RegistryConfig{
 			DataDirPath:    registryDir,
 			URI:            registryUri,
-			Port:           swag.IntValue(applianceConfig.Config.ImageRegistry.Port),
+			Port:           swag.IntValue(applianceConfig.Config.Registry.Port),
 			UseOcpRegistry: registry.ShouldUseOcpRegistry(envConfig, applianceConfig),
 		})
 
@@ -167,7 +167,7 @@ func (u *UpgradeISO) Generate(dependencies asset.Parents) error {
 		envConfig,
 	)
 	spinner.FileToMonitor = upgradeISOName
-	imageGen := genisoimage.NewGenIsoImage(nil)
+	imageGen := genisoimage.NewGenIsoImage(nil, envConfig.SourceDateEpoch)
 	upgradeVolumeName := fmt.Sprintf(upgradeVolumeNamePattern, releaseVersion)
 	if err = imageGen.GenerateImage(envConfig.AssetsDir, upgradeISOName, filepath.Join(envConfig.TempDir, upgradeDataDir), upgradeVolumeName); err != nil {
 		return log.StopSpinner(spinner, err)
@@ -8,6 +8,8 @@ const (
 	UserCfgTemplateFile = "scripts/grub/user.cfg.template"
 	GrubTimeout         = 10
 	GrubMenuEntryName   = "Agent-Based Installer"
+	GrubConsoleArgs     = "console=tty0 console=ttyS0,115200n8" // Replaced by boot.console
+	GrubSuperuser       = "root"                                // For a password-protected recovery entry
 	// For installation ignition
 	GrubMenuEntryNameRecovery = "Recovery: Agent-Based Installer (Reinstall Cluster)"
 	GrubCfgFilePath           = "/boot/grub2/grub.cfg"
@@ -18,11 +20,18 @@ const (
 	ApplianceFileName           = "appliance.raw"
 	RecoveryIsoFileName         = "recovery.iso"
 	DataIsoFileName             = "data.iso"
+	DataSquashfsFileName        = "data.squashfs"
+	DataErofsFileName           = "data.erofs"
 	CoreosImagePattern          = "rhcos-*%s.raw"
 
 	// Appliance Live ISO
 	ApplianceLiveIsoFileName = "appliance.iso"
 
+	// Add nodes ISO
+	AddNodesIsoFileName   = "appliance-add-nodes.iso"
+	NodesConfigFileName   = "nodes-config.yaml"
+	DefaultKubeconfigPath = "auth/kubeconfig"
+
 	// ImageSetTemplateFile imageset.yaml.template
 	ImageSetTemplateFile = "scripts/mirror/imageset.yaml.template"
 
@@ -30,12 +39,29 @@ const (
 	PinnedImageSetTemplateFile = "scripts/mirror/pinned-image-set.yaml.template"
 	// PinnedImageSetPattern - for installation ignition
 	PinnedImageSetPattern = "%s-pinned-image-set"
+
+	// AddUsersTemplateFile template - for creating the additional users post installation
+	AddUsersTemplateFile = "scripts/users/add-users.sh.template"
 	// OcMirrorMappingFileName - name of the mapping file created by oc mirror
 	OcMirrorMappingFileName = "mapping.txt"
 	// OcMirrorResourcesDir - cluster resources directory created by oc mirror
 	OcMirrorResourcesDir = "cluster-resources"
+	// OcMirrorWorkspaceDir - oc mirror workspace directory (in temp dir)
+	OcMirrorWorkspaceDir = "oc-mirror"
+	// OcMirrorHelmChartsDir - helm charts directory created by oc mirror (in the working dir)
+	OcMirrorHelmChartsDir = "helm/charts"
+	// HelmChartsDir - helm charts directory (in cache dir and in the data partition)
+	HelmChartsDir = "helm-charts"
+	// OcMirrorDryRunWorkspaceDir - oc mirror dry-run workspace directory (in temp dir)
+	OcMirrorDryRunWorkspaceDir = "oc-mirror-dry-run"
 	// MinOcpVersionForPinnedImageSet - minimum version that supports PinnedImageSet
 	MinOcpVersionForPinnedImageSet = "4.16"
+	// IgnitionSpecVersion - ignition spec version of the generated ignition configs
+	IgnitionSpecVersion = "3.4.0"
+	// IgnitionSpecVersionLegacy - ignition spec version for releases older than MinOcpVersionForIgnitionSpec
+	IgnitionSpecVersionLegacy = "3.2.0"
+	// MinOcpVersionForIgnitionSpec - minimum version that supports IgnitionSpecVersion
+	MinOcpVersionForIgnitionSpec = "4.14"
 	// MinOcpVersionContainingDistributionRegistry - minimum version where docker-registry image in OCP release contains distribution binary
 	MinOcpVersionContainingDistributionRegistry = "4.21.0"
 
@@ -43,6 +69,11 @@ const (
 	RecoveryPartitionName = "agentboot"
 	DataPartitionName     = "agentdata"
 
+	// Data partition filesystem formats
+	DataPartitionFormatISO9660  = "iso9660"
+	DataPartitionFormatSquashfs = "squashfs"
+	DataPartitionFormatErofs    = "erofs"
+
 	// ReservedPartitionGUID Set partition as Linux reserved partition: https://en.wikipedia.org/wiki/GUID_Partition_Table
 	ReservedPartitionGUID = "8DA63339-0007-60C0-C436-083AC8230908"
 
@@ -57,6 +88,12 @@ const (
 	RegistryDataInstall   = "/mnt/agentdata/"
 	RegistryDataUpgrade   = "/media/upgrade/oc-mirror/install"
 
+	// GlobalOperatorsNamespace already contains an OperatorGroup (i.e. for AllNamespaces install mode)
+	GlobalOperatorsNamespace = "openshift-operators"
+
+	// Progress of applying the post-installation CRs
+	OperatorCRsStatusPath = "/etc/assisted/operator-crs.status"
+
 	// Deployment ISO
 	CoreosIsoName      = "coreos-%s.iso"
 	Coreos10IsoName    = "coreos10-%s.iso"
@@ -72,9 +109,12 @@ const (
 	// Appliance config flags (default values)
 	EnableDefaultSources  = false
 	StopLocalRegistry     = false
+	SkipLocalRegistry     = false
 	UseRegistryBinary     = false
 	CreatePinnedImageSets = false
 	EnableFips            = false
 	EnableInteractiveFlow = false
 	UseDefaultSourceNames = false
+	DataPartitionFormat   = DataPartitionFormatISO9660
+	AllowVersionFallback  = false
 )
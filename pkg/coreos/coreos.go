@@ -3,6 +3,8 @@ package coreos
 import (
 	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -23,12 +25,14 @@ const (
 	machineOsImageName      = "machine-os-images"
 	coreOsStream            = "coreos/coreos-stream.json"
 	coreOsDiskImageUrlQuery = ".architectures.x86_64.artifacts.metal.formats[\"raw.gz\"].disk.location"
+	coreOsDiskImageShaQuery = ".architectures.x86_64.artifacts.metal.formats[\"raw.gz\"].disk.sha256"
 
 	CoreOsDiskImageGz = "coreos.tar.gz"
 )
 
 type CoreOS interface {
 	DownloadDiskImage() (string, error)
+	GetDiskImageLocation() (string, string, error)
 	DownloadISO() (string, error)
 	EmbedIgnition(ignition []byte, isoPath string) error
 	WrapIgnition(ignition []byte, ignitionPath, imagePath string) error
@@ -64,28 +68,63 @@ func NewCoreOS(config CoreOSConfig) CoreOS {
 }
 
 func (c *coreos) DownloadDiskImage() (string, error) {
-	coreosStream, err := c.FetchCoreOSStream()
-	if err != nil {
-		return "", err
+	var rawGzUrl, rawGzSha256 string
+	if lock := c.ApplianceConfig.Lock; lock != nil && lock.CoreOS.DiskImageURL != "" {
+		// Use the disk image pinned in appliance-lock.yaml
+		rawGzUrl, rawGzSha256 = lock.CoreOS.DiskImageURL, lock.CoreOS.DiskImageSha256
+	} else {
+		var err error
+		rawGzUrl, rawGzSha256, err = c.GetDiskImageLocation()
+		if err != nil {
+			return "", err
+		}
 	}
-	query, err := gojq.Parse(coreOsDiskImageUrlQuery)
+
+	compressed := filepath.Join(c.EnvConfig.TempDir, CoreOsDiskImageGz)
+	req, err := grab.NewRequest(compressed, rawGzUrl)
 	if err != nil {
 		return "", err
 	}
-	iter := query.Run(coreosStream)
-	v, ok := iter.Next()
-	if !ok {
+	if rawGzSha256 != "" {
+		sum, err := hex.DecodeString(rawGzSha256)
+		if err != nil {
+			return "", errors.Wrapf(err, "invalid CoreOS disk image checksum: %s", rawGzSha256)
+		}
+		req.SetChecksum(sha256.New(), sum, true)
+	}
+	if err = grab.DefaultClient.Do(req).Err(); err != nil {
 		return "", err
 	}
 
-	rawGzUrl := v.(string)
-	compressed := filepath.Join(c.EnvConfig.TempDir, CoreOsDiskImageGz)
-	_, err = grab.Get(compressed, rawGzUrl)
+	return compressed, nil
+}
+
+// GetDiskImageLocation returns the URL and sha256 checksum of the CoreOS disk image
+// as specified in the CoreOS stream metadata of the release
+func (c *coreos) GetDiskImageLocation() (string, string, error) {
+	coreosStream, err := c.FetchCoreOSStream()
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	return compressed, nil
+	var values []string
+	for _, q := range []string{coreOsDiskImageUrlQuery, coreOsDiskImageShaQuery} {
+		query, err := gojq.Parse(q)
+		if err != nil {
+			return "", "", err
+		}
+		v, ok := query.Run(coreosStream).Next()
+		if !ok {
+			return "", "", errors.Errorf("failed to query CoreOS stream metadata (%s)", q)
+		}
+		value, ok := v.(string)
+		if !ok {
+			return "", "", errors.Errorf("unexpected value in CoreOS stream metadata (%s): %v", q, v)
+		}
+		values = append(values, value)
+	}
+
+	return values[0], values[1], nil
 }
 
 func (c *coreos) DownloadISO() (string, error) {
@@ -0,0 +1,105 @@
+package coreos
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// KargsConfigFilePath describes the kernel arguments embed areas of an extracted CoreOS ISO
+	KargsConfigFilePath = "coreos/kargs.json"
+
+	defaultKargsPad = "#"
+)
+
+type kargsConfig struct {
+	Default string `json:"default"`
+	Files   []struct {
+		End    string `json:"end"`
+		Offset int64  `json:"offset"`
+		Pad    string `json:"pad"`
+		Path   string `json:"path"`
+	} `json:"files"`
+	Size int64 `json:"size"`
+}
+
+func readKargsConfig(isoDir string) (*kargsConfig, error) {
+	kargsData, err := os.ReadFile(filepath.Join(isoDir, KargsConfigFilePath))
+	if err != nil {
+		return nil, err
+	}
+	var config kargsConfig
+	if err := json.Unmarshal(kargsData, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func writeKargsConfig(isoDir string, config *kargsConfig) error {
+	configContent, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(isoDir, KargsConfigFilePath), configContent, 0600)
+}
+
+// FixKargsOffset shifts the kernel arguments embed area of the specified config file
+// (i.e. following an insertion of offset bytes before the area)
+func FixKargsOffset(isoDir, configPath string, offset int64) error {
+	config, err := readKargsConfig(isoDir)
+	if err != nil {
+		return err
+	}
+	for i, file := range config.Files {
+		if file.Path == configPath {
+			config.Files[i].Offset = file.Offset + offset
+		}
+	}
+	return writeKargsConfig(isoDir, config)
+}
+
+// AppendKargs appends the kernel arguments to the embed areas of an extracted CoreOS ISO
+// (i.e. the boot configs of the ISO), as done by 'coreos-installer iso kargs modify --append'
+func AppendKargs(isoDir string, kargs []string) error {
+	if len(kargs) == 0 {
+		return nil
+	}
+	config, err := readKargsConfig(isoDir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range config.Files {
+		filePath := filepath.Join(isoDir, file.Path)
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		if file.Offset+config.Size > int64(len(content)) {
+			return errors.Errorf("invalid kargs embed area in %s", file.Path)
+		}
+
+		pad := file.Pad
+		if pad == "" {
+			pad = defaultKargsPad
+		}
+		area := string(content[file.Offset : file.Offset+config.Size])
+		current := strings.TrimSpace(strings.TrimRight(area, pad))
+		updated := strings.TrimSpace(current + " " + strings.Join(kargs, " "))
+		if int64(len(updated)) > config.Size {
+			return errors.Errorf("kernel arguments exceed the embed area size of %s (%d bytes)", file.Path, config.Size)
+		}
+		updated += strings.Repeat(pad, int(config.Size)-len(updated))
+		copy(content[file.Offset:], updated)
+
+		if err := os.WriteFile(filePath, content, 0600); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
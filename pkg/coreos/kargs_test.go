@@ -0,0 +1,55 @@
+package coreos
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test kargs", func() {
+	const (
+		grubCfgPath  = "EFI/redhat/grub.cfg"
+		defaultKargs = "coreos.liveiso=rhcos ignition.firstboot"
+		kargsSize    = 64
+	)
+
+	var isoDir string
+
+	BeforeEach(func() {
+		isoDir = GinkgoT().TempDir()
+		prefix := "linux /images/pxeboot/vmlinuz "
+		grubCfg := prefix + defaultKargs + strings.Repeat("#", kargsSize-len(defaultKargs)) + "\ninitrd /images/pxeboot/initrd.img\n"
+		Expect(os.MkdirAll(filepath.Join(isoDir, "EFI/redhat"), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(isoDir, grubCfgPath), []byte(grubCfg), 0600)).To(Succeed())
+
+		kargsConfig := `{"default": "` + defaultKargs + `", "files": [{"path": "` + grubCfgPath + `", "offset": ` +
+			`30, "pad": "#", "end": "\n"}], "size": 64}`
+		Expect(len(prefix)).To(Equal(30))
+		Expect(os.MkdirAll(filepath.Join(isoDir, "coreos"), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(isoDir, KargsConfigFilePath), []byte(kargsConfig), 0600)).To(Succeed())
+	})
+
+	It("AppendKargs", func() {
+		Expect(AppendKargs(isoDir, []string{"console=ttyS0,115200n8", "nosmt"})).To(Succeed())
+
+		grubCfg, err := os.ReadFile(filepath.Join(isoDir, grubCfgPath))
+		Expect(err).ToNot(HaveOccurred())
+		kargs := defaultKargs + " console=ttyS0,115200n8 nosmt"
+		Expect(string(grubCfg)).To(Equal("linux /images/pxeboot/vmlinuz " + kargs +
+			strings.Repeat("#", kargsSize-len(kargs)) + "\ninitrd /images/pxeboot/initrd.img\n"))
+	})
+
+	It("AppendKargs - exceeds the embed area", func() {
+		Expect(AppendKargs(isoDir, []string{strings.Repeat("a", kargsSize)})).To(MatchError(ContainSubstring("exceed")))
+	})
+
+	It("FixKargsOffset", func() {
+		Expect(FixKargsOffset(isoDir, grubCfgPath, 10)).To(Succeed())
+		config, err := readKargsConfig(isoDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Files[0].Offset).To(Equal(int64(40)))
+	})
+})
@@ -0,0 +1,87 @@
+package debugfs
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/openshift/appliance/pkg/executer"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// The filesystem is located at the specified offset of the image (e.g. a disk image partition)
+	debugfsCmd = "debugfs %s-f %s %s?offset=%d"
+	// E2FSPROGS_FAKE_TIME sets the timestamps written by debugfs (instead of the current time)
+	debugfsFakeTimeCmd = "env E2FSPROGS_FAKE_TIME=%d " + debugfsCmd
+	dumpFileRequest    = "dump %s %s"
+)
+
+// Debugfs edits ext2/3/4 filesystems in userspace (i.e. without mounting them)
+type Debugfs interface {
+	DumpFile(imagePath string, offset int64, filePath, localPath string) error
+	Write(imagePath string, offset int64, requests []string, fakeTime *time.Time) error
+}
+
+type debugfs struct {
+	executer executer.Executer
+}
+
+func NewDebugfs(exec executer.Executer) Debugfs {
+	if exec == nil {
+		exec = executer.NewExecuter()
+	}
+
+	return &debugfs{
+		executer: exec,
+	}
+}
+
+// DumpFile copies the content of a file in the filesystem to localPath
+func (d *debugfs) DumpFile(imagePath string, offset int64, filePath, localPath string) error {
+	requestsFile, err := d.writeRequests([]string{fmt.Sprintf(dumpFileRequest, filePath, localPath)})
+	if err != nil {
+		return err
+	}
+	defer d.removeRequests(requestsFile)
+
+	_, err = d.executer.Execute(fmt.Sprintf(debugfsCmd, "", requestsFile, imagePath, offset))
+	return err
+}
+
+// Write runs the requests (e.g. 'rm', 'write') with the filesystem opened read-write.
+// When fakeTime is specified, it's used for all the updated timestamps (reproducible builds).
+func (d *debugfs) Write(imagePath string, offset int64, requests []string, fakeTime *time.Time) error {
+	requestsFile, err := d.writeRequests(requests)
+	if err != nil {
+		return err
+	}
+	defer d.removeRequests(requestsFile)
+
+	cmd := fmt.Sprintf(debugfsCmd, "-w ", requestsFile, imagePath, offset)
+	if fakeTime != nil {
+		cmd = fmt.Sprintf(debugfsFakeTimeCmd, fakeTime.Unix(), "-w ", requestsFile, imagePath, offset)
+	}
+	_, err = d.executer.Execute(cmd)
+	return err
+}
+
+// writeRequests writes the requests to a temp file (one request per line)
+func (d *debugfs) writeRequests(requests []string) (string, error) {
+	f, err := d.executer.TempFile("", "debugfs-requests-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err = f.WriteString(strings.Join(requests, "\n") + "\n"); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+func (d *debugfs) removeRequests(requestsFile string) {
+	if err := os.Remove(requestsFile); err != nil {
+		logrus.Warnf("Failed to remove %s: %s", requestsFile, err.Error())
+	}
+}
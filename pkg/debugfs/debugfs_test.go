@@ -0,0 +1,82 @@
+package debugfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/appliance/pkg/executer"
+)
+
+var _ = Describe("Test Debugfs", func() {
+	var (
+		ctrl          *gomock.Controller
+		mockExecuter  *executer.MockExecuter
+		testDebugfs   Debugfs
+		requestsFile  *os.File
+		fakeImagePath = "/path/to/appliance.raw"
+		fakeOffset    = int64(1048576)
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockExecuter = executer.NewMockExecuter(ctrl)
+		testDebugfs = NewDebugfs(mockExecuter)
+
+		var err error
+		requestsFile, err = os.CreateTemp(GinkgoT().TempDir(), "debugfs-requests-*")
+		Expect(err).ToNot(HaveOccurred())
+		mockExecuter.EXPECT().TempFile(gomock.Any(), gomock.Any()).Return(requestsFile, nil).Times(1)
+	})
+
+	expectRequests := func(requests string) func(string) (string, error) {
+		return func(string) (string, error) {
+			Expect(os.ReadFile(requestsFile.Name())).To(Equal([]byte(requests)))
+			return "", nil
+		}
+	}
+
+	It("debugfs DumpFile - success", func() {
+		cmd := fmt.Sprintf(debugfsCmd, "", requestsFile.Name(), fakeImagePath, fakeOffset)
+		mockExecuter.EXPECT().Execute(cmd).DoAndReturn(expectRequests("dump /grub2/grub.cfg /tmp/grub.cfg\n")).Times(1)
+
+		err := testDebugfs.DumpFile(fakeImagePath, fakeOffset, "/grub2/grub.cfg", "/tmp/grub.cfg")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requestsFile.Name()).ToNot(BeAnExistingFile())
+	})
+
+	It("debugfs Write - success", func() {
+		cmd := fmt.Sprintf(debugfsCmd, "-w ", requestsFile.Name(), fakeImagePath, fakeOffset)
+		mockExecuter.EXPECT().Execute(cmd).DoAndReturn(expectRequests("rm /a\nrm /b\n")).Times(1)
+
+		err := testDebugfs.Write(fakeImagePath, fakeOffset, []string{"rm /a", "rm /b"}, nil)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("debugfs Write - fake time", func() {
+		fakeTime := time.Unix(1700000000, 0)
+		cmd := fmt.Sprintf(debugfsFakeTimeCmd, 1700000000, "-w ", requestsFile.Name(), fakeImagePath, fakeOffset)
+		mockExecuter.EXPECT().Execute(cmd).Return("", nil).Times(1)
+
+		err := testDebugfs.Write(fakeImagePath, fakeOffset, []string{"rm /a"}, &fakeTime)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("debugfs Write - failure", func() {
+		mockExecuter.EXPECT().Execute(gomock.Any()).Return("", errors.New("some error")).Times(1)
+
+		err := testDebugfs.Write(fakeImagePath, fakeOffset, []string{"rm /a"}, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(requestsFile.Name()).ToNot(BeAnExistingFile())
+	})
+})
+
+func TestDebugfs(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "debugfs_test")
+}
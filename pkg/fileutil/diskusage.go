@@ -0,0 +1,86 @@
+package fileutil
+
+import (
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DiskUsageMonitor samples the used space of the filesystem containing a directory,
+// and keeps track of the peak usage relative to the start.
+// Sampling is cheap (statfs), so short-lived peaks (e.g. temporary files) are caught,
+// but other writers on the same filesystem are included as well.
+type DiskUsageMonitor struct {
+	dir      string
+	interval time.Duration
+	baseline uint64
+	peak     uint64
+	done     chan struct{}
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+}
+
+func NewDiskUsageMonitor(dir string, interval time.Duration) *DiskUsageMonitor {
+	return &DiskUsageMonitor{
+		dir:      dir,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start begins sampling the disk usage in the background
+func (m *DiskUsageMonitor) Start() {
+	m.baseline, _ = getUsedSpace(m.dir)
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			m.sample()
+			select {
+			case <-m.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends sampling and returns the peak disk usage (in bytes) since Start was called
+func (m *DiskUsageMonitor) Stop() uint64 {
+	close(m.done)
+	m.wg.Wait()
+	m.sample()
+	return m.Peak()
+}
+
+// Peak returns the peak disk usage (in bytes) sampled so far
+func (m *DiskUsageMonitor) Peak() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.peak
+}
+
+func (m *DiskUsageMonitor) sample() {
+	used, err := getUsedSpace(m.dir)
+	if err != nil || used < m.baseline {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if used-m.baseline > m.peak {
+		m.peak = used - m.baseline
+	}
+}
+
+// getUsedSpace returns the used space of the filesystem containing a directory
+// (the actual usage of sparse files, e.g. the raw disk images)
+func getUsedSpace(dir string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return (stat.Blocks - stat.Bfree) * uint64(stat.Bsize), nil // #nosec G115
+}
@@ -127,3 +127,49 @@ func SplitFile(filePath, destPath, partSize string) error {
 	_, err := exec.Execute(fmt.Sprintf(splitCmd, filePath, destPath, partSize))
 	return err
 }
+
+// GetDirSize returns the total size in bytes of the regular files under dirPath.
+func GetDirSize(dirPath string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+type sparseWriter struct {
+	file   *os.File
+	offset int64
+}
+
+// NewSparseWriter returns a writer that writes into file starting at offset.
+// All-zero chunks are skipped (rather than written) to keep the file sparse,
+// so the target region is expected to be zeroed (e.g. a new sparse file).
+func NewSparseWriter(file *os.File, offset int64) io.Writer {
+	return &sparseWriter{file: file, offset: offset}
+}
+
+func (w *sparseWriter) Write(p []byte) (int, error) {
+	if !isZero(p) {
+		if _, err := w.file.WriteAt(p, w.offset); err != nil {
+			return 0, err
+		}
+	}
+	w.offset += int64(len(p))
+	return len(p), nil
+}
+
+func isZero(p []byte) bool {
+	for _, b := range p {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -2,33 +2,106 @@ package genisoimage
 
 import (
 	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/openshift/appliance/pkg/executer"
+	"github.com/pkg/errors"
 )
 
 const (
-	genDataImageCmd = "genisoimage --iso-level 3 -R -D -V %s -o %s/%s %s"
+	genDataImageCmd     = "genisoimage --iso-level 3 -R -D -V %s -o %s/%s %s"
+	genDataImageSizeCmd = "genisoimage --iso-level 3 -R -D -V %s -quiet -print-size %s"
+	streamDataImageCmd  = "genisoimage --iso-level 3 -R -D -V %s -quiet %s"
+
+	// genisoimage doesn't support fixed timestamps, so the mkisofs emulation of xorriso
+	// is used instead for reproducible images (all the volume and file dates are set to SOURCE_DATE_EPOCH)
+	genReproducibleDataImageCmd     = "xorriso -as mkisofs -iso-level 3 -R -D -V %s %s -o %s/%s %s"
+	genReproducibleDataImageSizeCmd = "xorriso -as mkisofs -iso-level 3 -R -D -V %s %s -quiet -print-size %s"
+	streamReproducibleDataImageCmd  = "xorriso -as mkisofs -iso-level 3 -R -D -V %s %s -quiet %s"
+	reproducibleDatesFlags          = "--modification-date=%s --set_all_file_dates =%d"
+
+	// genisoimage reports sizes in ISO9660 logical blocks
+	isoBlockSize = 2048
 )
 
 type GenIsoImage interface {
 	GenerateImage(imagePath, imageName, dirPath, volumeName string) error
+	GetImageSize(dirPath, volumeName string) (int64, error)
+	StreamImage(dirPath, volumeName string, w io.Writer) error
 }
 
 type genisoimage struct {
-	executer executer.Executer
+	executer        executer.Executer
+	sourceDateEpoch *time.Time
 }
 
-func NewGenIsoImage(exec executer.Executer) GenIsoImage {
+// NewGenIsoImage returns a GenIsoImage that generates ISO9660 images.
+// When sourceDateEpoch is specified, all the timestamps of the images are set to it (reproducible builds).
+func NewGenIsoImage(exec executer.Executer, sourceDateEpoch *time.Time) GenIsoImage {
 	if exec == nil {
 		exec = executer.NewExecuter()
 	}
 
 	return &genisoimage{
-		executer: exec,
+		executer:        exec,
+		sourceDateEpoch: sourceDateEpoch,
 	}
 }
 
 func (s *genisoimage) GenerateImage(imagePath, imageName, dirPath, volumeName string) error {
-	_, err := s.executer.Execute(fmt.Sprintf(genDataImageCmd, volumeName, imagePath, imageName, dirPath))
+	cmd := fmt.Sprintf(genDataImageCmd, volumeName, imagePath, imageName, dirPath)
+	if s.sourceDateEpoch != nil {
+		cmd = fmt.Sprintf(genReproducibleDataImageCmd, volumeName, s.datesFlags(), imagePath, imageName, dirPath)
+	}
+	_, err := s.executer.Execute(cmd)
 	return err
 }
+
+// GetImageSize returns the size in bytes of the image that would be generated
+// from dirPath, without writing it.
+func (s *genisoimage) GetImageSize(dirPath, volumeName string) (int64, error) {
+	cmd := fmt.Sprintf(genDataImageSizeCmd, volumeName, dirPath)
+	if s.sourceDateEpoch != nil {
+		cmd = fmt.Sprintf(genReproducibleDataImageSizeCmd, volumeName, s.datesFlags(), dirPath)
+	}
+	out, err := s.executer.Execute(cmd)
+	if err != nil {
+		return 0, err
+	}
+	// The size is printed on the last line of the output
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	blocks, err := strconv.ParseInt(strings.TrimSpace(lines[len(lines)-1]), 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to parse genisoimage image size")
+	}
+	return blocks * isoBlockSize, nil
+}
+
+// StreamImage writes the image generated from dirPath to w
+// (i.e. without storing an intermediate image file).
+func (s *genisoimage) StreamImage(dirPath, volumeName string, w io.Writer) error {
+	command := fmt.Sprintf(streamDataImageCmd, volumeName, dirPath)
+	if s.sourceDateEpoch != nil {
+		command = fmt.Sprintf(streamReproducibleDataImageCmd, volumeName, s.datesFlags(), dirPath)
+	}
+	args := strings.Split(command, " ")
+	cmd := exec.Command(args[0], args[1:]...) // #nosec G204
+	cmd.Stdout = w
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return errors.Errorf("Failed to execute cmd (%s): %s", cmd, stderr.String())
+	}
+	return nil
+}
+
+// datesFlags returns the xorriso flags for setting the volume and file dates to SOURCE_DATE_EPOCH
+func (s *genisoimage) datesFlags() string {
+	// The modification date is also used as the volume UUID (e.g. by GRUB)
+	modificationDate := s.sourceDateEpoch.UTC().Format("20060102150405") + "00"
+	return fmt.Sprintf(reproducibleDatesFlags, modificationDate, s.sourceDateEpoch.Unix())
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo/v2/dsl/core"
@@ -25,7 +26,7 @@ var _ = Describe("Test GenIsoImage", func() {
 	BeforeEach(func() {
 		ctrl = gomock.NewController(GinkgoT())
 		mockExecuter = executer.NewMockExecuter(ctrl)
-		testGenIsoImage = NewGenIsoImage(mockExecuter)
+		testGenIsoImage = NewGenIsoImage(mockExecuter, nil)
 	})
 
 	It("genisoimage GenerateImage - success", func() {
@@ -47,6 +48,34 @@ var _ = Describe("Test GenIsoImage", func() {
 		err := testGenIsoImage.GenerateImage(fakeCachePath, fakeImageName, fakeDataPath, fakeVolumeName)
 		Expect(err).To(HaveOccurred())
 	})
+
+	It("genisoimage GenerateImage - reproducible", func() {
+		sourceDateEpoch := time.Unix(1700000000, 0)
+		testGenIsoImage = NewGenIsoImage(mockExecuter, &sourceDateEpoch)
+
+		dates := fmt.Sprintf(reproducibleDatesFlags, "2023111422132000", 1700000000)
+		cmd := fmt.Sprintf(genReproducibleDataImageCmd, fakeVolumeName, dates, fakeCachePath, fakeImageName, fakeDataPath)
+		mockExecuter.EXPECT().Execute(cmd).Return("", nil).Times(1)
+
+		err := testGenIsoImage.GenerateImage(fakeCachePath, fakeImageName, fakeDataPath, fakeVolumeName)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("genisoimage GetImageSize - success", func() {
+		cmd := fmt.Sprintf(genDataImageSizeCmd, fakeVolumeName, fakeDataPath)
+		mockExecuter.EXPECT().Execute(cmd).Return("Total extents scheduled to be written = 1000\n1000", nil).Times(1)
+
+		size, err := testGenIsoImage.GetImageSize(fakeDataPath, fakeVolumeName)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(size).To(Equal(int64(1000 * isoBlockSize)))
+	})
+
+	It("genisoimage GetImageSize - invalid output", func() {
+		mockExecuter.EXPECT().Execute(gomock.Any()).Return("unexpected", nil).Times(1)
+
+		_, err := testGenIsoImage.GetImageSize(fakeDataPath, fakeVolumeName)
+		Expect(err).To(HaveOccurred())
+	})
 })
 
 func TestGenIsoImage(t *testing.T) {
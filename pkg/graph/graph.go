@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"regexp"
 	"sort"
 	"strings"
@@ -15,6 +16,7 @@ import (
 	"github.com/go-openapi/swag"
 	"github.com/openshift/appliance/pkg/consts"
 	"github.com/sirupsen/logrus"
+	"github.com/thoas/go-funk"
 )
 
 // Response is what Cincinnati sends us when querying for releases in a channel
@@ -24,8 +26,9 @@ type Response struct {
 
 // Release describes a release payload
 type Release struct {
-	Version string `json:"version"`
-	Payload string `json:"payload"`
+	Version  string            `json:"version"`
+	Payload  string            `json:"payload"`
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 // OcpRelease describes a generally available release payload
@@ -49,7 +52,12 @@ const (
 )
 
 const (
-	cincinnatiAddress = "https://api.openshift.com/api/upgrades_info/graph"
+	// CincinnatiAddress is the default graph URL
+	CincinnatiAddress = "https://api.openshift.com/api/upgrades_info/graph"
+
+	// Metadata keys of the graph nodes
+	channelsMetadataKey     = "io.openshift.upgrades.graph.release.channels"
+	architectureMetadataKey = "release.openshift.io/architecture"
 )
 
 var (
@@ -57,8 +65,10 @@ var (
 )
 
 // Graph is the interface for fetching info from api.openshift.com/api/upgrades_info/graph
+// (or from a custom graph source, e.g. an OpenShift Update Service or a saved graph file)
 type Graph interface {
 	GetReleaseImage() (string, string, error)
+	GetVersions() ([]Release, error)
 }
 
 type HTTPClient interface {
@@ -70,7 +80,12 @@ type GraphConfig struct {
 	Arch              string
 	Version           string
 	CincinnatiAddress *string
-	Channel           *ReleaseChannel
+	// GraphFile is a path to a saved graph JSON (used instead of CincinnatiAddress)
+	GraphFile *string
+	Channel   *ReleaseChannel
+	// AllowVersionFallback resolves the latest supported version (consts.MaxOcpVersion)
+	// when the requested version is not available
+	AllowVersionFallback bool
 }
 
 type graph struct {
@@ -85,7 +100,7 @@ func NewGraph(config GraphConfig) Graph {
 	}
 
 	if config.CincinnatiAddress == nil {
-		config.CincinnatiAddress = swag.String(cincinnatiAddress)
+		config.CincinnatiAddress = swag.String(CincinnatiAddress)
 	}
 
 	if config.Channel == nil {
@@ -106,6 +121,11 @@ func (g *graph) GetReleaseImage() (string, string, error) {
 
 	payload, version, err := g.resolvePullSpec(*g.CincinnatiAddress, release)
 	if err != nil {
+		if !g.AllowVersionFallback {
+			return "", "", fmt.Errorf("OCP %s is not available in channel %s (%s): %w. "+
+				"Set ocpRelease.allowVersionFallback to use the latest supported version (%s) instead",
+				release.Version, release.Channel, release.Architecture, err, consts.MaxOcpVersion)
+		}
 		if g.Version != consts.MaxOcpVersion {
 			// Trying to fallback to latest supported version
 			logrus.Warnf("OCP %s is not available, fallback to latest supported version: %s", release.Version, consts.MaxOcpVersion)
@@ -125,12 +145,6 @@ func (g *graph) GetReleaseImage() (string, string, error) {
 // Copied from ci-tools (https://github.com/openshift/ci-tools/blob/master/pkg/release/official/client.go)
 
 func (g *graph) resolvePullSpec(endpoint string, release OcpRelease) (string, string, error) {
-	req, err := http.NewRequest("GET", endpoint, nil)
-	if err != nil {
-		return "", "", err
-	}
-	req.Header.Set("Accept", "application/json")
-	query := req.URL.Query()
 	explicitVersion, channel, err := g.processVersionChannel(release.Version, release.Channel)
 	if err != nil {
 		return "", "", err
@@ -139,15 +153,81 @@ func (g *graph) resolvePullSpec(endpoint string, release OcpRelease) (string, st
 	if !explicitVersion {
 		targetName = release.Version
 	}
+
+	nodes, source, err := g.getNodes(endpoint, channel, release.Architecture, targetName)
+	if err != nil {
+		return "", "", err
+	}
+
+	if explicitVersion {
+		for _, node := range nodes {
+			if node.Version == release.Version {
+				return node.Payload, node.Version, nil
+			}
+		}
+		return "", "", fmt.Errorf("failed to request %s from %s: version not found in list of releases", release.Version, source)
+	}
+
+	// Channels include releases of previous minor versions (as upgrade sources),
+	// so ensure the resolved release matches the requested major.minor version
+	var matching []Release
+	for _, node := range nodes {
+		if _, majorMinor, err := g.extractMajorMinor(node.Version); err == nil && majorMinor == release.Version {
+			matching = append(matching, node)
+		}
+	}
+	if len(matching) == 0 {
+		return "", "", fmt.Errorf("failed to request %s from %s: no %s releases found in list of releases", targetName, source, release.Version)
+	}
+
+	pullspec, version := g.latestPullSpecAndVersion(matching)
+	return pullspec, version, nil
+}
+
+// GetVersions returns the releases available in the configured channel and architecture,
+// sorted in descending order
+func (g *graph) GetVersions() ([]Release, error) {
+	_, channel, err := g.processVersionChannel(g.Version, *g.Channel)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, _, err := g.getNodes(*g.CincinnatiAddress, channel, g.Arch, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	g.sortReleases(nodes)
+	return nodes, nil
+}
+
+// getNodes returns the release nodes of the specified channel, either from
+// the saved graph file or by querying the graph endpoint.
+// The returned source describes where the nodes were taken from (for error messages).
+func (g *graph) getNodes(endpoint, channel, arch, targetName string) ([]Release, string, error) {
+	if g.GraphFile != nil {
+		nodes, err := g.readGraphFile(*g.GraphFile, channel, arch)
+		if err != nil {
+			return nil, "", err
+		}
+		return nodes, *g.GraphFile, nil
+	}
+
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	query := req.URL.Query()
 	query.Add("channel", channel)
-	query.Add("arch", release.Architecture)
+	query.Add("arch", arch)
 	req.URL.RawQuery = query.Encode()
 	resp, err := g.HTTPClient.Do(req)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to request %s: %w", targetName, err)
+		return nil, "", fmt.Errorf("failed to request %s: %w", targetName, err)
 	}
 	if resp == nil {
-		return "", "", fmt.Errorf("failed to request %s: got a nil response", targetName)
+		return nil, "", fmt.Errorf("failed to request %s: got a nil response", targetName)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -158,31 +238,76 @@ func (g *graph) resolvePullSpec(endpoint string, release OcpRelease) (string, st
 	var buf bytes.Buffer
 	_, readErr := io.Copy(&buf, resp.Body)
 	if resp.StatusCode != http.StatusOK {
-		return "", "", fmt.Errorf("failed to request %s: server responded with %d: %s", targetName, resp.StatusCode, buf.String())
+		return nil, "", fmt.Errorf("failed to request %s: server responded with %d: %s", targetName, resp.StatusCode, buf.String())
 	}
 	if readErr != nil {
-		return "", "", fmt.Errorf("failed to read response body: %w", readErr)
+		return nil, "", fmt.Errorf("failed to read response body: %w", readErr)
 	}
 	response := Response{}
 	err = json.Unmarshal(buf.Bytes(), &response)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to unmarshal response: %w", err)
+		return nil, "", fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 	if len(response.Nodes) == 0 {
-		return "", "", fmt.Errorf("failed to request %s from %s: server returned empty list of releases (despite status code 200)", targetName, req.URL.String())
+		return nil, "", fmt.Errorf("failed to request %s from %s: server returned empty list of releases (despite status code 200)", targetName, req.URL.String())
 	}
 
-	if explicitVersion {
-		for _, node := range response.Nodes {
-			if node.Version == release.Version {
-				return node.Payload, node.Version, nil
-			}
+	// Skip the nodes with an invalid version (can't be sorted)
+	var nodes []Release
+	for _, node := range response.Nodes {
+		if _, err := semver.Parse(node.Version); err != nil {
+			logrus.Warnf("Skipping release %s of %s: invalid version: %v", node.Version, req.URL.String(), err)
+			continue
 		}
-		return "", "", fmt.Errorf("failed to request %s from %s: version not found in list of releases", release.Version, req.URL.String())
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 0 {
+		return nil, "", fmt.Errorf("failed to request %s from %s: server returned no valid releases", targetName, req.URL.String())
 	}
 
-	pullspec, version := g.latestPullSpecAndVersion(response.Nodes)
-	return pullspec, version, nil
+	return nodes, req.URL.String(), nil
+}
+
+// readGraphFile returns the release nodes of a saved graph JSON file
+// (e.g. the output of 'curl -H "Accept: application/json" <graph-url>?channel=stable-4.18&arch=amd64').
+// The nodes are filtered by their channels and architecture metadata (when specified).
+func (g *graph) readGraphFile(graphFile, channel, arch string) ([]Release, error) {
+	data, err := os.ReadFile(graphFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read graph file %s: %w", graphFile, err)
+	}
+	response := Response{}
+	if err = json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal graph file %s: %w", graphFile, err)
+	}
+	if len(response.Nodes) == 0 {
+		return nil, fmt.Errorf("graph file %s contains an empty list of releases", graphFile)
+	}
+
+	var nodes []Release
+	hasChannels := false
+	for _, node := range response.Nodes {
+		if _, err = semver.Parse(node.Version); err != nil {
+			return nil, fmt.Errorf("graph file %s contains an invalid version %q: %w", graphFile, node.Version, err)
+		}
+		if nodeArch := node.Metadata[architectureMetadataKey]; nodeArch != "" && nodeArch != arch {
+			continue
+		}
+		if channels := node.Metadata[channelsMetadataKey]; channels != "" {
+			hasChannels = true
+			if !funk.ContainsString(strings.Split(channels, ","), channel) {
+				continue
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("graph file %s contains no releases of channel %s (%s)", graphFile, channel, arch)
+	}
+	if !hasChannels {
+		logrus.Warnf("Graph file %s doesn't specify the channels of its releases (assuming channel %s, %s)", graphFile, channel, arch)
+	}
+	return nodes, nil
 }
 
 // processVersionChannel takes the configured version and channel and
@@ -206,14 +331,23 @@ func (g *graph) processVersionChannel(version string, channel ReleaseChannel) (e
 
 // latestPullSpecAndVersion returns the pullSpec of the latest release in the list as a payload and version
 func (g *graph) latestPullSpecAndVersion(options []Release) (string, string) {
-	sort.Slice(options, func(i, j int) bool {
-		vi := semver.MustParse(options[i].Version)
-		vj := semver.MustParse(options[j].Version)
-		return vi.GTE(vj) // greater, not less, so we get descending order
-	})
+	g.sortReleases(options)
 	return options[0].Payload, options[0].Version
 }
 
+// sortReleases sorts the releases by version in descending order
+// (releases with an invalid version are sorted last)
+func (g *graph) sortReleases(options []Release) {
+	sort.SliceStable(options, func(i, j int) bool {
+		vi, errI := semver.Parse(options[i].Version)
+		vj, errJ := semver.Parse(options[j].Version)
+		if errI != nil || errJ != nil {
+			return errI == nil
+		}
+		return vi.GT(vj) // greater, not less, so we get descending order
+	})
+}
+
 func (g *graph) extractMajorMinor(version string) (explicitVersion bool, majorMinor string, err error) {
 	majorMinorMatch := majorMinorRegExp.FindStringSubmatch(version)
 	if majorMinorMatch == nil {
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -24,7 +26,7 @@ const (
 type ClientMock struct{}
 
 func (c *ClientMock) Do(req *http.Request) (*http.Response, error) {
-	if strings.Contains(req.URL.String(), cincinnatiAddress) {
+	if strings.Contains(req.URL.String(), CincinnatiAddress) {
 		cincinnatiFakeResponse := Response{
 			Nodes: []Release{
 				{
@@ -99,6 +101,16 @@ var _ = Describe("Test Graph", func() {
 	It("GetReleaseImage - missing version", func() {
 		graphConfig.Version = "4.99"
 
+		testGraph = NewGraph(graphConfig)
+		_, _, err := testGraph.GetReleaseImage()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("allowVersionFallback"))
+	})
+
+	It("GetReleaseImage - missing version with fallback", func() {
+		graphConfig.Version = "4.99"
+		graphConfig.AllowVersionFallback = true
+
 		testGraph = NewGraph(graphConfig)
 		cincinnatiResponse, verResponse, err := testGraph.GetReleaseImage()
 		Expect(err).ToNot(HaveOccurred())
@@ -114,6 +126,95 @@ var _ = Describe("Test Graph", func() {
 		_, _, err := testGraph.GetReleaseImage()
 		Expect(err).To(HaveOccurred())
 	})
+
+	It("GetReleaseImage - graph file", func() {
+		graphFile := filepath.Join(GinkgoT().TempDir(), "graph.json")
+		Expect(os.WriteFile(graphFile, []byte(`{"nodes":[
+			{"version":"4.18.1","payload":"quay.io/openshift-release-dev/ocp-release@sha256:file1"},
+			{"version":"4.18.10","payload":"quay.io/openshift-release-dev/ocp-release@sha256:file10"},
+			{"version":"4.18.2","payload":"quay.io/openshift-release-dev/ocp-release@sha256:file2"}]}`), 0600)).To(Succeed())
+		graphConfig.Version = "4.18"
+		graphConfig.GraphFile = swag.String(graphFile)
+		// The HTTP client must not be used when a graph file is specified
+		graphConfig.CincinnatiAddress = swag.String(fakeCincinnatiAddress)
+
+		testGraph = NewGraph(graphConfig)
+		cincinnatiResponse, verResponse, err := testGraph.GetReleaseImage()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cincinnatiResponse).To(Equal("quay.io/openshift-release-dev/ocp-release@sha256:file10"))
+		Expect(verResponse).To(Equal("4.18.10"))
+	})
+
+	It("GetReleaseImage - invalid graph file", func() {
+		graphFile := filepath.Join(GinkgoT().TempDir(), "graph.json")
+		Expect(os.WriteFile(graphFile, []byte(`{"nodes":[]}`), 0600)).To(Succeed())
+		graphConfig.Version = consts.MaxOcpVersion
+		graphConfig.GraphFile = swag.String(graphFile)
+
+		testGraph = NewGraph(graphConfig)
+		_, _, err := testGraph.GetReleaseImage()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("GetReleaseImage - graph file with an invalid version", func() {
+		graphFile := filepath.Join(GinkgoT().TempDir(), "graph.json")
+		Expect(os.WriteFile(graphFile, []byte(`{"nodes":[
+			{"version":"4.18.1","payload":"quay.io/openshift-release-dev/ocp-release@sha256:file1"},
+			{"version":"4.18","payload":"quay.io/openshift-release-dev/ocp-release@sha256:file2"}]}`), 0600)).To(Succeed())
+		graphConfig.Version = "4.18"
+		graphConfig.GraphFile = swag.String(graphFile)
+
+		testGraph = NewGraph(graphConfig)
+		_, _, err := testGraph.GetReleaseImage()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("invalid version"))
+	})
+
+	It("GetReleaseImage - graph file filtered by channel and architecture", func() {
+		graphFile := filepath.Join(GinkgoT().TempDir(), "graph.json")
+		Expect(os.WriteFile(graphFile, []byte(`{"nodes":[
+			{"version":"4.18.1","payload":"quay.io/openshift-release-dev/ocp-release@sha256:file1",
+			 "metadata":{"io.openshift.upgrades.graph.release.channels":"candidate-4.18,fast-4.18,stable-4.18"}},
+			{"version":"4.18.2","payload":"quay.io/openshift-release-dev/ocp-release@sha256:file2",
+			 "metadata":{"io.openshift.upgrades.graph.release.channels":"candidate-4.18"}},
+			{"version":"4.18.3","payload":"quay.io/openshift-release-dev/ocp-release@sha256:file3",
+			 "metadata":{"io.openshift.upgrades.graph.release.channels":"stable-4.18","release.openshift.io/architecture":"multi"}}]}`), 0600)).To(Succeed())
+		graphConfig.Version = "4.18"
+		graphConfig.GraphFile = swag.String(graphFile)
+
+		testGraph = NewGraph(graphConfig)
+		cincinnatiResponse, verResponse, err := testGraph.GetReleaseImage()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cincinnatiResponse).To(Equal("quay.io/openshift-release-dev/ocp-release@sha256:file1"))
+		Expect(verResponse).To(Equal("4.18.1"))
+
+		channel := ReleaseChannelEUS
+		graphConfig.Channel = &channel
+		testGraph = NewGraph(graphConfig)
+		_, _, err = testGraph.GetReleaseImage()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("no releases of channel eus-4.18"))
+	})
+
+	It("GetVersions - sorted in descending order", func() {
+		graphConfig.Version = "4.13"
+
+		testGraph = NewGraph(graphConfig)
+		releases, err := testGraph.GetVersions()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(releases).To(HaveLen(4))
+		Expect(releases[0].Version).To(Equal(fmt.Sprintf("%s.0", consts.MaxOcpVersion)))
+		Expect(releases[3].Version).To(Equal("4.13.1"))
+	})
+
+	It("GetVersions - Cincinnati returns 404", func() {
+		graphConfig.Version = "4.13"
+		graphConfig.CincinnatiAddress = swag.String(fakeCincinnatiAddress)
+
+		testGraph = NewGraph(graphConfig)
+		_, err := testGraph.GetVersions()
+		Expect(err).To(HaveOccurred())
+	})
 })
 
 func TestGraph(t *testing.T) {
@@ -1,10 +1,12 @@
 package ignitionutil
 
 import (
-	"encoding/json"
+	"path/filepath"
 
-	ignitionConfig "github.com/coreos/ignition/v2/config/v3_2"
-	"github.com/coreos/ignition/v2/config/v3_2/types"
+	butaneConfig "github.com/coreos/butane/config"
+	butaneCommon "github.com/coreos/butane/config/common"
+	ignitionConfig "github.com/coreos/ignition/v2/config/v3_4"
+	"github.com/coreos/ignition/v2/config/v3_4/types"
 	"github.com/coreos/ignition/v2/config/validate"
 	"github.com/openshift/appliance/pkg/fileutil"
 	"github.com/pkg/errors"
@@ -13,7 +15,8 @@ import (
 //go:generate mockgen -source=ignition.go -package=ignitionutil -destination=mock_ignition.go
 type Ignition interface {
 	ParseIgnitionFile(path string) (*types.Config, error)
-	WriteIgnitionFile(path string, config *types.Config) error
+	ParseButaneFile(path string) (*types.Config, error)
+	WriteIgnitionFile(path string, config *types.Config, specVersion string) error
 	MergeIgnitionConfig(base *types.Config, overrides *types.Config) (*types.Config, error)
 }
 
@@ -35,18 +38,43 @@ func NewIgnition(config IgnitionConfig) Ignition {
 }
 
 // ParseIgnitionFile reads an ignition config from a given path on disk
+// (configs of previous spec versions are translated to the current spec)
 func (i *ignition) ParseIgnitionFile(path string) (*types.Config, error) {
 	configBytes, err := i.OSInterface.ReadFile(path)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error reading file %s", path)
 	}
-	configLatest, _, err := ignitionConfig.Parse(configBytes)
+	configLatest, _, err := ignitionConfig.ParseCompatibleVersion(configBytes)
 	return &configLatest, err
 }
 
-// WriteIgnitionFile writes an ignition config to a given path on disk
-func (i *ignition) WriteIgnitionFile(path string, config *types.Config) error {
-	updatedBytes, err := json.Marshal(config)
+// ParseButaneFile translates a Butane config from a given path on disk to an ignition config
+// (local files referenced by the Butane config are relative to its dir)
+func (i *ignition) ParseButaneFile(path string) (*types.Config, error) {
+	butaneBytes, err := i.OSInterface.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading file %s", path)
+	}
+	options := butaneCommon.TranslateBytesOptions{
+		TranslateOptions: butaneCommon.TranslateOptions{
+			FilesDir: filepath.Dir(path),
+		},
+	}
+	configJSON, report, err := butaneConfig.TranslateBytes(butaneBytes, options)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error translating Butane file %s: %s", path, report.String())
+	}
+	// Warnings are treated as errors (same as 'butane --strict')
+	if len(report.Entries) > 0 {
+		return nil, errors.Errorf("error translating Butane file %s: %s", path, report.String())
+	}
+	configLatest, _, err := ignitionConfig.ParseCompatibleVersion(configJSON)
+	return &configLatest, err
+}
+
+// WriteIgnitionFile writes an ignition config (in the specified spec version) to a given path on disk
+func (i *ignition) WriteIgnitionFile(path string, config *types.Config, specVersion string) error {
+	updatedBytes, err := MarshalIgnitionConfig(config, specVersion)
 	if err != nil {
 		return err
 	}
@@ -66,3 +94,41 @@ func (i *ignition) MergeIgnitionConfig(base *types.Config, overrides *types.Conf
 	}
 	return &config, nil
 }
+
+// ConflictingEntries returns the entries of the base config that are overridden by the overrides config
+// (i.e. files, directories, links and systemd units with the same path or name)
+func ConflictingEntries(base *types.Config, overrides *types.Config) []string {
+	entries := map[string]bool{}
+	for _, file := range base.Storage.Files {
+		entries["file "+file.Path] = true
+	}
+	for _, dir := range base.Storage.Directories {
+		entries["directory "+dir.Path] = true
+	}
+	for _, link := range base.Storage.Links {
+		entries["link "+link.Path] = true
+	}
+	for _, unit := range base.Systemd.Units {
+		entries["unit "+unit.Name] = true
+	}
+
+	conflicts := []string{}
+	addConflict := func(entry string) {
+		if entries[entry] {
+			conflicts = append(conflicts, entry)
+		}
+	}
+	for _, file := range overrides.Storage.Files {
+		addConflict("file " + file.Path)
+	}
+	for _, dir := range overrides.Storage.Directories {
+		addConflict("directory " + dir.Path)
+	}
+	for _, link := range overrides.Storage.Links {
+		addConflict("link " + link.Path)
+	}
+	for _, unit := range overrides.Systemd.Units {
+		addConflict("unit " + unit.Name)
+	}
+	return conflicts
+}
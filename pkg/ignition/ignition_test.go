@@ -5,11 +5,10 @@ import (
 	"os"
 	"testing"
 
-	igntypes "github.com/coreos/ignition/v2/config/v3_2/types"
+	igntypes "github.com/coreos/ignition/v2/config/v3_4/types"
 	"github.com/go-openapi/swag"
 	. "github.com/onsi/ginkgo/v2/dsl/core"
 	. "github.com/onsi/gomega"
-	assetignition "github.com/openshift/installer/pkg/asset/ignition"
 )
 
 const (
@@ -23,6 +22,23 @@ const (
 		}
 	  }`
 
+	fakeButane = `
+variant: fcos
+version: 1.4.0
+storage:
+  files:
+    - path: /etc/sysctl.d/99-custom.conf
+      contents:
+        inline: vm.swappiness=10
+`
+
+	fakeButaneUnknownField = `
+variant: fcos
+version: 1.4.0
+storage:
+  filez: []
+`
+
 	fakeIgnition99 = `{
 		"ignition": {
 		  "config": {},
@@ -67,6 +83,12 @@ func (FakeOS) ReadFile(name string) ([]byte, error) {
 	if name == "/path/to/ignition99-file" {
 		return []byte(fakeIgnition99), nil
 	}
+	if name == "/path/to/chrony.bu" {
+		return []byte(fakeButane), nil
+	}
+	if name == "/path/to/unknown-field.bu" {
+		return []byte(fakeButaneUnknownField), nil
+	}
 	return nil, errors.New("file not found")
 }
 
@@ -100,7 +122,54 @@ var _ = Describe("Test Ignition", func() {
 	It("ParseIgnitionFile - ignition v3.2.0 success", func() {
 		config32, err := testIgnition.ParseIgnitionFile("/path/to/ignition32-file")
 		Expect(err).NotTo(HaveOccurred())
-		Expect(config32.Ignition.Version).To(Equal("3.2.0"))
+		Expect(config32.Ignition.Version).To(Equal(igntypes.MaxVersion.String()))
+	})
+
+	It("ParseButaneFile - success", func() {
+		config, err := testIgnition.ParseButaneFile("/path/to/chrony.bu")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Ignition.Version).To(Equal(igntypes.MaxVersion.String()))
+		Expect(config.Storage.Files).To(HaveLen(1))
+		Expect(config.Storage.Files[0].Path).To(Equal("/etc/sysctl.d/99-custom.conf"))
+	})
+
+	It("ParseButaneFile - translation failure", func() {
+		_, err := testIgnition.ParseButaneFile("/path/to/unknown-field.bu")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("/path/to/unknown-field.bu"))
+	})
+
+	It("ParseButaneFile - file not found", func() {
+		_, err := testIgnition.ParseButaneFile("/bad/path/file.bu")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("ConflictingEntries", func() {
+		base := &igntypes.Config{
+			Storage: igntypes.Storage{
+				Files: []igntypes.File{
+					FileFromString("/etc/chrony.conf", "root", 0644, "base"),
+					FileFromString("/etc/hosts", "root", 0644, "base"),
+				},
+			},
+			Systemd: igntypes.Systemd{
+				Units: []igntypes.Unit{{Name: "chronyd.service"}},
+			},
+		}
+		overrides := &igntypes.Config{
+			Storage: igntypes.Storage{
+				Files: []igntypes.File{
+					FileFromString("/etc/chrony.conf", "root", 0644, "overlay"),
+					FileFromString("/etc/sysctl.d/99-custom.conf", "root", 0644, "overlay"),
+				},
+			},
+			Systemd: igntypes.Systemd{
+				Units: []igntypes.Unit{{Name: "chronyd.service"}, {Name: "vendor-agent.service"}},
+			},
+		}
+
+		Expect(ConflictingEntries(base, overrides)).To(Equal([]string{"file /etc/chrony.conf", "unit chronyd.service"}))
+		Expect(ConflictingEntries(base, &igntypes.Config{})).To(BeEmpty())
 	})
 
 	It("MergeIgnitionConfig - success", func() {
@@ -122,7 +191,7 @@ var _ = Describe("Test Ignition", func() {
 				Version: igntypes.MaxVersion.String(),
 			},
 			Storage: igntypes.Storage{
-				Files: []igntypes.File{assetignition.FileFromBytes("/path/to/file",
+				Files: []igntypes.File{FileFromBytes("/path/to/file",
 					"root", 0644, []byte("foobar")),
 				},
 				Filesystems: []igntypes.Filesystem{
@@ -7,7 +7,7 @@ package ignitionutil
 import (
 	reflect "reflect"
 
-	types "github.com/coreos/ignition/v2/config/v3_2/types"
+	types "github.com/coreos/ignition/v2/config/v3_4/types"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -49,6 +49,21 @@ func (mr *MockIgnitionMockRecorder) MergeIgnitionConfig(base, overrides interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeIgnitionConfig", reflect.TypeOf((*MockIgnition)(nil).MergeIgnitionConfig), base, overrides)
 }
 
+// ParseButaneFile mocks base method.
+func (m *MockIgnition) ParseButaneFile(path string) (*types.Config, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseButaneFile", path)
+	ret0, _ := ret[0].(*types.Config)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ParseButaneFile indicates an expected call of ParseButaneFile.
+func (mr *MockIgnitionMockRecorder) ParseButaneFile(path interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseButaneFile", reflect.TypeOf((*MockIgnition)(nil).ParseButaneFile), path)
+}
+
 // ParseIgnitionFile mocks base method.
 func (m *MockIgnition) ParseIgnitionFile(path string) (*types.Config, error) {
 	m.ctrl.T.Helper()
@@ -65,15 +80,15 @@ func (mr *MockIgnitionMockRecorder) ParseIgnitionFile(path interface{}) *gomock.
 }
 
 // WriteIgnitionFile mocks base method.
-func (m *MockIgnition) WriteIgnitionFile(path string, config *types.Config) error {
+func (m *MockIgnition) WriteIgnitionFile(path string, config *types.Config, specVersion string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "WriteIgnitionFile", path, config)
+	ret := m.ctrl.Call(m, "WriteIgnitionFile", path, config, specVersion)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // WriteIgnitionFile indicates an expected call of WriteIgnitionFile.
-func (mr *MockIgnitionMockRecorder) WriteIgnitionFile(path, config interface{}) *gomock.Call {
+func (mr *MockIgnitionMockRecorder) WriteIgnitionFile(path, config, specVersion interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteIgnitionFile", reflect.TypeOf((*MockIgnition)(nil).WriteIgnitionFile), path, config)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteIgnitionFile", reflect.TypeOf((*MockIgnition)(nil).WriteIgnitionFile), path, config, specVersion)
 }
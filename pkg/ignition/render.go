@@ -0,0 +1,316 @@
+package ignitionutil
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/coreos/ignition/v2/config/v3_4/types"
+	"github.com/go-openapi/swag"
+	"github.com/pkg/errors"
+	"github.com/vincent-petithory/dataurl"
+)
+
+const (
+	// RenderedConfigName is the pretty-printed ignition config in a rendered tree
+	RenderedConfigName = "config.ign"
+
+	renderedFilesDir   = "files"
+	renderedSystemdDir = "systemd"
+
+	// maxDiffCells limits the size of the line diff table (a larger change is shown as replaced)
+	maxDiffCells = 2000 * 2000
+)
+
+// RenderTree lays out an ignition config as a tree (relative path -> content) for reviewing:
+// the pretty-printed config (in the specified spec version), the decoded file contents
+// (under 'files') and the systemd units and dropins (under 'systemd').
+func RenderTree(config *types.Config, specVersion string) (map[string][]byte, error) {
+	configBytes, err := MarshalIgnitionConfig(config, specVersion)
+	if err != nil {
+		return nil, err
+	}
+	var prettyConfig bytes.Buffer
+	if err = json.Indent(&prettyConfig, configBytes, "", "  "); err != nil {
+		return nil, err
+	}
+	prettyConfig.WriteString("\n")
+
+	tree := map[string][]byte{
+		RenderedConfigName: prettyConfig.Bytes(),
+	}
+	for _, file := range config.Storage.Files {
+		contents := []byte{}
+		for _, resource := range append([]types.Resource{file.Contents}, file.Append...) {
+			data, err := decodeResource(resource)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to decode file %s", file.Path)
+			}
+			contents = append(contents, data...)
+		}
+		tree[filepath.Join(renderedFilesDir, file.Path)] = contents
+	}
+	for _, link := range config.Storage.Links {
+		tree[filepath.Join(renderedFilesDir, link.Path)+".link"] = []byte(swag.StringValue(link.Target) + "\n")
+	}
+	for _, unit := range config.Systemd.Units {
+		if unit.Contents != nil {
+			tree[filepath.Join(renderedSystemdDir, unit.Name)] = []byte(*unit.Contents)
+		}
+		for _, dropin := range unit.Dropins {
+			if dropin.Contents != nil {
+				tree[filepath.Join(renderedSystemdDir, unit.Name+".d", dropin.Name)] = []byte(*dropin.Contents)
+			}
+		}
+	}
+	return tree, nil
+}
+
+// decodeResource returns the content of a data URL resource
+// (a remote resource is rendered as its URL)
+func decodeResource(resource types.Resource) ([]byte, error) {
+	if resource.Source == nil {
+		return nil, nil
+	}
+	if !strings.HasPrefix(*resource.Source, "data:") {
+		return []byte(fmt.Sprintf("# remote source: %s\n", *resource.Source)), nil
+	}
+	url, err := dataurl.DecodeString(*resource.Source)
+	if err != nil {
+		return nil, err
+	}
+	if resource.Compression == nil || *resource.Compression == "" {
+		return url.Data, nil
+	}
+	if *resource.Compression != "gzip" {
+		return nil, errors.Errorf("unsupported compression: %s", *resource.Compression)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(url.Data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// WriteTree writes a rendered tree to the specified dir (replacing a previous render)
+func WriteTree(tree map[string][]byte, dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	for path, content := range tree {
+		filePath := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filePath, content, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadTree reads a rendered tree from the specified dir
+func ReadTree(dir string) (map[string][]byte, error) {
+	tree := map[string][]byte{}
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		tree[relPath] = content
+		return nil
+	})
+	return tree, err
+}
+
+// DiffTrees returns a unified diff of the rendered trees (empty when identical)
+func DiffTrees(previous, current map[string][]byte) string {
+	paths := []string{}
+	for path := range previous {
+		paths = append(paths, path)
+	}
+	for path := range current {
+		if _, ok := previous[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var diff strings.Builder
+	for _, path := range paths {
+		previousContent, inPrevious := previous[path]
+		currentContent, inCurrent := current[path]
+		if inPrevious && inCurrent && bytes.Equal(previousContent, currentContent) {
+			continue
+		}
+		fromName, toName := "a/"+path, "b/"+path
+		if !inPrevious {
+			fromName = "/dev/null"
+		}
+		if !inCurrent {
+			toName = "/dev/null"
+		}
+		fmt.Fprintf(&diff, "--- %s\n+++ %s\n", fromName, toName)
+		diff.WriteString(diffLines(splitLines(previousContent), splitLines(currentContent)))
+	}
+	return diff.String()
+}
+
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// diffLines returns the unified diff hunks (with 3 context lines) of the lines
+func diffLines(a, b []string) string {
+	// Trim the common prefix and suffix
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := []diffLine{}
+	for _, line := range a[:prefix] {
+		lines = append(lines, diffLine{' ', line})
+	}
+	lines = append(lines, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		lines = append(lines, diffLine{' ', line})
+	}
+
+	return formatHunks(lines, 3)
+}
+
+// diffMiddle returns the line operations of the longest common subsequence
+func diffMiddle(a, b []string) []diffLine {
+	lines := []diffLine{}
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			lines = append(lines, diffLine{'-', line})
+		}
+		for _, line := range b {
+			lines = append(lines, diffLine{'+', line})
+		}
+		return lines
+	}
+
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	return lines
+}
+
+func formatHunks(lines []diffLine, context int) string {
+	var hunks strings.Builder
+	for start := 0; start < len(lines); {
+		// Find the next change
+		for start < len(lines) && lines[start].op == ' ' {
+			start++
+		}
+		if start == len(lines) {
+			break
+		}
+		// Extend the hunk while changes are within the context
+		end := start
+		for last := start; last < len(lines); last++ {
+			if lines[last].op != ' ' {
+				end = last + 1
+			} else if last-end >= 2*context {
+				break
+			}
+		}
+		hunkStart := max(start-context, 0)
+		hunkEnd := min(end+context, len(lines))
+
+		// Line numbers of the hunk
+		fromLine, toLine := 1, 1
+		for _, line := range lines[:hunkStart] {
+			if line.op != '+' {
+				fromLine++
+			}
+			if line.op != '-' {
+				toLine++
+			}
+		}
+		fromCount, toCount := 0, 0
+		for _, line := range lines[hunkStart:hunkEnd] {
+			if line.op != '+' {
+				fromCount++
+			}
+			if line.op != '-' {
+				toCount++
+			}
+		}
+		if fromCount == 0 {
+			fromLine--
+		}
+		if toCount == 0 {
+			toLine--
+		}
+
+		fmt.Fprintf(&hunks, "@@ -%d,%d +%d,%d @@\n", fromLine, fromCount, toLine, toCount)
+		for _, line := range lines[hunkStart:hunkEnd] {
+			hunks.WriteByte(line.op)
+			hunks.WriteString(line.text)
+			if !strings.HasSuffix(line.text, "\n") {
+				hunks.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = hunkEnd
+	}
+	return hunks.String()
+}
@@ -0,0 +1,95 @@
+package ignitionutil
+
+import (
+	"path/filepath"
+
+	igntypes "github.com/coreos/ignition/v2/config/v3_4/types"
+	"github.com/go-openapi/swag"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test Ignition render", func() {
+	var (
+		config igntypes.Config
+	)
+
+	BeforeEach(func() {
+		config = igntypes.Config{
+			Ignition: igntypes.Ignition{
+				Version: igntypes.MaxVersion.String(),
+			},
+			Storage: igntypes.Storage{
+				Files: []igntypes.File{
+					FileFromString("/usr/local/bin/deploy.sh", "root", 0755, "#!/bin/bash\necho deploy\n"),
+				},
+			},
+			Systemd: igntypes.Systemd{
+				Units: []igntypes.Unit{
+					{
+						Name:     "deploy.service",
+						Contents: swag.String("[Unit]\nDescription=Deploy\n"),
+						Dropins: []igntypes.Dropin{
+							{Name: "10-env.conf", Contents: swag.String("[Service]\nEnvironment=A=B\n")},
+						},
+					},
+				},
+			},
+		}
+	})
+
+	It("RenderTree", func() {
+		tree, err := RenderTree(&config, igntypes.MaxVersion.String())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tree).To(HaveLen(4))
+		Expect(string(tree[RenderedConfigName])).To(ContainSubstring("\n  \"ignition\": {\n"))
+		Expect(string(tree["files/usr/local/bin/deploy.sh"])).To(Equal("#!/bin/bash\necho deploy\n"))
+		Expect(string(tree["systemd/deploy.service"])).To(Equal("[Unit]\nDescription=Deploy\n"))
+		Expect(string(tree["systemd/deploy.service.d/10-env.conf"])).To(Equal("[Service]\nEnvironment=A=B\n"))
+	})
+
+	It("WriteTree and ReadTree", func() {
+		tree, err := RenderTree(&config, igntypes.MaxVersion.String())
+		Expect(err).NotTo(HaveOccurred())
+
+		dir := filepath.Join(GinkgoT().TempDir(), "deploy")
+		Expect(WriteTree(tree, dir)).To(Succeed())
+		readTree, err := ReadTree(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(readTree).To(Equal(tree))
+	})
+
+	It("DiffTrees", func() {
+		previous := map[string][]byte{
+			"files/etc/a.conf":  []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"),
+			"files/etc/removed": []byte("x\n"),
+		}
+		current := map[string][]byte{
+			"files/etc/a.conf": []byte("1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n"),
+			"files/etc/added":  []byte("y"),
+		}
+
+		Expect(DiffTrees(previous, previous)).To(BeEmpty())
+		Expect(DiffTrees(previous, current)).To(Equal(`--- a/files/etc/a.conf
++++ b/files/etc/a.conf
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+--- /dev/null
++++ b/files/etc/added
+@@ -0,0 +1,1 @@
++y
+\ No newline at end of file
+--- a/files/etc/removed
++++ /dev/null
+@@ -1,1 +0,0 @@
+-x
+`))
+	})
+})
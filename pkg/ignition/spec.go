@@ -0,0 +1,84 @@
+package ignitionutil
+
+import (
+	"encoding/json"
+
+	"github.com/coreos/ignition/v2/config/util"
+	v32 "github.com/coreos/ignition/v2/config/v3_2"
+	v32types "github.com/coreos/ignition/v2/config/v3_2/types"
+	translate33 "github.com/coreos/ignition/v2/config/v3_3/translate"
+	translate34 "github.com/coreos/ignition/v2/config/v3_4/translate"
+	"github.com/coreos/ignition/v2/config/v3_4/types"
+	"github.com/pkg/errors"
+	"github.com/vincent-petithory/dataurl"
+)
+
+// TranslateConfig translates a spec 3.2 config (e.g. generated by the installer helpers) to the current spec
+func TranslateConfig(config v32types.Config) types.Config {
+	return translate34.Translate(translate33.Translate(config))
+}
+
+// MarshalIgnitionConfig returns the JSON of the config in the specified spec version.
+// A spec 3.2 config can't use features of later specs (e.g. kernelArguments).
+func MarshalIgnitionConfig(config *types.Config, specVersion string) ([]byte, error) {
+	switch specVersion {
+	case types.MaxVersion.String():
+		return json.Marshal(config)
+	case v32types.MaxVersion.String():
+		return marshalV32Config(config)
+	default:
+		return nil, errors.Errorf("unsupported ignition spec version: %s", specVersion)
+	}
+}
+
+func marshalV32Config(config *types.Config) ([]byte, error) {
+	configBytes, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	if err = json.Unmarshal(configBytes, &fields); err != nil {
+		return nil, err
+	}
+	if kernelArguments, ok := fields["kernelArguments"].(map[string]interface{}); ok && len(kernelArguments) == 0 {
+		// An empty struct isn't omitted
+		delete(fields, "kernelArguments")
+	}
+	fields["ignition"].(map[string]interface{})["version"] = v32types.MaxVersion.String()
+	if configBytes, err = json.Marshal(fields); err != nil {
+		return nil, err
+	}
+
+	// Fields unknown to spec 3.2 are reported as warnings (i.e. unused keys)
+	v32Config, report, err := v32.Parse(configBytes)
+	if err != nil || len(report.Entries) > 0 {
+		return nil, errors.Errorf("ignition config is incompatible with spec %s: %s",
+			v32types.MaxVersion.String(), report.String())
+	}
+	return json.Marshal(v32Config)
+}
+
+// FileFromBytes creates an ignition-config file with the given contents
+// (same as the installer helper, for the current spec)
+func FileFromBytes(path string, username string, mode int, contents []byte) types.File {
+	return types.File{
+		Node: types.Node{
+			Path: path,
+			User: types.NodeUser{
+				Name: &username,
+			},
+			Overwrite: util.BoolToPtr(true),
+		},
+		FileEmbedded1: types.FileEmbedded1{
+			Mode: &mode,
+			Contents: types.Resource{
+				Source: util.StrToPtr(dataurl.EncodeBytes(contents)),
+			},
+		},
+	}
+}
+
+// FileFromString creates an ignition-config file with the given contents
+func FileFromString(path string, username string, mode int, contents string) types.File {
+	return FileFromBytes(path, username, mode, []byte(contents))
+}
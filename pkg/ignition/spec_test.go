@@ -0,0 +1,58 @@
+package ignitionutil
+
+import (
+	"encoding/json"
+
+	v32types "github.com/coreos/ignition/v2/config/v3_2/types"
+	igntypes "github.com/coreos/ignition/v2/config/v3_4/types"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test Ignition spec", func() {
+	var (
+		config igntypes.Config
+	)
+
+	BeforeEach(func() {
+		config = TranslateConfig(v32types.Config{
+			Ignition: v32types.Ignition{
+				Version: v32types.MaxVersion.String(),
+			},
+		})
+		config.Storage.Files = append(config.Storage.Files, FileFromString("/etc/chrony.conf", "root", 0644, "server ntp.example.com"))
+	})
+
+	It("TranslateConfig", func() {
+		Expect(config.Ignition.Version).To(Equal(igntypes.MaxVersion.String()))
+	})
+
+	It("MarshalIgnitionConfig - current spec", func() {
+		configBytes, err := MarshalIgnitionConfig(&config, igntypes.MaxVersion.String())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(configBytes)).To(ContainSubstring(`"version":"3.4.0"`))
+	})
+
+	It("MarshalIgnitionConfig - spec 3.2", func() {
+		configBytes, err := MarshalIgnitionConfig(&config, v32types.MaxVersion.String())
+		Expect(err).NotTo(HaveOccurred())
+
+		var config32 v32types.Config
+		Expect(json.Unmarshal(configBytes, &config32)).To(Succeed())
+		Expect(config32.Ignition.Version).To(Equal("3.2.0"))
+		Expect(config32.Storage.Files[0].Path).To(Equal("/etc/chrony.conf"))
+		Expect(string(configBytes)).NotTo(ContainSubstring("kernelArguments"))
+	})
+
+	It("MarshalIgnitionConfig - spec 3.2 with kernelArguments", func() {
+		config.KernelArguments.ShouldExist = []igntypes.KernelArgument{"console=ttyS0"}
+		_, err := MarshalIgnitionConfig(&config, v32types.MaxVersion.String())
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Unused key kernelArguments"))
+	})
+
+	It("MarshalIgnitionConfig - unsupported spec", func() {
+		_, err := MarshalIgnitionConfig(&config, "3.1.0")
+		Expect(err).To(HaveOccurred())
+	})
+})
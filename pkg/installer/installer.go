@@ -21,12 +21,15 @@ const (
 	installerFipsBinaryName            = "openshift-install-fips"
 	installerBinaryGZ                  = "openshift-install-linux.tar.gz"
 	templateUnconfiguredIgnitionBinary = "%s agent create unconfigured-ignition --dir %s"
+	templateNodeImageCreate            = "oc adm node-image create --dir %s --kubeconfig %s"
 	templateInstallerDownloadURL       = "https://mirror.openshift.com/pub/openshift-v%s/%s/clients/%s/%s/openshift-install-linux.tar.gz"
 	unconfiguredIgnitionFileName       = "unconfigured-agent.ign"
+	nodeImageFileNamePattern           = "node.%s.iso"
 )
 
 type Installer interface {
 	CreateUnconfiguredIgnition() (string, error)
+	CreateNodeImage(dir, kubeconfig string) (string, error)
 	GetInstallerDownloadURL() (string, error)
 	GetInstallerBinaryName() string
 }
@@ -65,22 +68,9 @@ func NewInstaller(config InstallerConfig) Installer {
 }
 
 func (i *installer) CreateUnconfiguredIgnition() (string, error) {
-	var openshiftInstallFilePath string
-	var err error
-
-	if !i.EnvConfig.DebugBaseIgnition {
-		if fileName := i.EnvConfig.FindInCache(i.InstallerBinaryName); fileName != "" {
-			logrus.Infof("Reusing %s binary from cache", i.InstallerBinaryName)
-			openshiftInstallFilePath = fileName
-		} else {
-			openshiftInstallFilePath, err = i.downloadInstallerBinary()
-			if err != nil {
-				return "", err
-			}
-		}
-	} else {
-		logrus.Debugf("Using openshift-install binary from assets dir to fetch unconfigured-ignition")
-		openshiftInstallFilePath = filepath.Join(i.EnvConfig.AssetsDir, i.InstallerBinaryName)
+	openshiftInstallFilePath, err := i.getInstallerBinaryPath()
+	if err != nil {
+		return "", err
 	}
 
 	createCmd := fmt.Sprintf(templateUnconfiguredIgnitionBinary, openshiftInstallFilePath, i.EnvConfig.TempDir)
@@ -88,6 +78,31 @@ func (i *installer) CreateUnconfiguredIgnition() (string, error) {
 	return filepath.Join(i.EnvConfig.TempDir, unconfiguredIgnitionFileName), err
 }
 
+// CreateNodeImage creates the ISO for adding nodes to an existing cluster
+// (according to the nodes-config.yaml file in the specified dir).
+// The ISO is created by the node-joiner of the cluster's release ('oc adm node-image create').
+func (i *installer) CreateNodeImage(dir, kubeconfig string) (string, error) {
+	createCmd := fmt.Sprintf(templateNodeImageCreate, dir, kubeconfig)
+	if stdout, err := i.Executer.Execute(createCmd); err != nil {
+		logrus.Errorf("%s", stdout)
+		return "", err
+	}
+	nodeImageFileName := fmt.Sprintf(nodeImageFileNamePattern, i.ApplianceConfig.GetCpuArchitecture())
+	return filepath.Join(dir, nodeImageFileName), nil
+}
+
+func (i *installer) getInstallerBinaryPath() (string, error) {
+	if i.EnvConfig.DebugBaseIgnition {
+		logrus.Debugf("Using %s binary from assets dir", i.InstallerBinaryName)
+		return filepath.Join(i.EnvConfig.AssetsDir, i.InstallerBinaryName), nil
+	}
+	if fileName := i.EnvConfig.FindInCache(i.InstallerBinaryName); fileName != "" {
+		logrus.Infof("Reusing %s binary from cache", i.InstallerBinaryName)
+		return fileName, nil
+	}
+	return i.downloadInstallerBinary()
+}
+
 func (i *installer) GetInstallerDownloadURL() (string, error) {
 	releaseVersion, err := version.NewVersion(i.ApplianceConfig.Config.OcpRelease.Version)
 	if err != nil {
@@ -134,7 +149,7 @@ func (i *installer) downloadInstallerBinary() (string, error) {
 }
 
 func (i *installer) GetInstallerBinaryName() string {
-	if swag.BoolValue(i.ApplianceConfig.Config.EnableFips) {
+	if swag.BoolValue(i.ApplianceConfig.Config.Cluster.EnableFips) {
 		return installerFipsBinaryName
 	}
 	return installerBinaryName
@@ -173,6 +173,39 @@ var _ = Describe("Test Installer", func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(res).To(Equal(filepath.Join(tmpDir, unconfiguredIgnitionFileName)))
 	})
+
+	It("CreateNodeImage", func() {
+		version := "4.16.1"
+		channel := graph.ReleaseChannelStable
+		cpuArc := swag.String(config.CpuArchitectureX86)
+		workDir := "/path/to/tempdir/add-nodes"
+		kubeconfig := "/path/to/kubeconfig"
+
+		cmd := fmt.Sprintf(templateNodeImageCreate, workDir, kubeconfig)
+		mockExecuter.EXPECT().Execute(cmd).Return("", nil).Times(1)
+
+		installerConfig := InstallerConfig{
+			Executer: mockExecuter,
+			Release:  mockRelease,
+			EnvConfig: &config.EnvConfig{
+				DebugBaseIgnition: true,
+			},
+			ApplianceConfig: &config.ApplianceConfig{
+				Config: &types.ApplianceConfig{
+					OcpRelease: types.ReleaseImage{
+						Version:         version,
+						Channel:         &channel,
+						CpuArchitecture: cpuArc,
+					},
+				},
+			},
+		}
+		testInstaller = NewInstaller(installerConfig)
+
+		res, err := testInstaller.CreateNodeImage(workDir, kubeconfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res).To(Equal(filepath.Join(workDir, "node.x86_64.iso")))
+	})
 })
 
 func TestInstaller(t *testing.T) {
@@ -0,0 +1,438 @@
+package lock
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-openapi/swag"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/thoas/go-funk"
+
+	"github.com/openshift/appliance/pkg/asset/config"
+	"github.com/openshift/appliance/pkg/consts"
+	"github.com/openshift/appliance/pkg/coreos"
+	"github.com/openshift/appliance/pkg/registry"
+	"github.com/openshift/appliance/pkg/release"
+	"github.com/openshift/appliance/pkg/skopeo"
+	"github.com/openshift/appliance/pkg/types"
+)
+
+const (
+	// Maximum number of mismatching images listed in a verification error
+	maxListedMismatches = 10
+
+	fbcBundleSchema     = "olm.bundle"
+	fbcPackageProperty  = "olm.package"
+	operatorCatalogsDir = "working-dir/operator-catalogs"
+)
+
+// Lock resolves and verifies the content pinned in appliance-lock.yaml
+type Lock interface {
+	Generate() (*types.ApplianceLock, error)
+	Verify() error
+}
+
+type LockConfig struct {
+	EnvConfig       *config.EnvConfig
+	ApplianceConfig *config.ApplianceConfig
+	Release         release.Release
+	CoreOS          coreos.CoreOS
+	Skopeo          skopeo.Skopeo
+}
+
+type lock struct {
+	LockConfig
+}
+
+// fbcBundle is an olm.bundle entry of a file-based catalog
+type fbcBundle struct {
+	Schema     string `json:"schema"`
+	Name       string `json:"name"`
+	Package    string `json:"package"`
+	Image      string `json:"image"`
+	Properties []struct {
+		Type  string          `json:"type"`
+		Value json.RawMessage `json:"value"`
+	} `json:"properties"`
+}
+
+func NewLock(config LockConfig) Lock {
+	if config.Release == nil {
+		config.Release = release.NewRelease(release.ReleaseConfig{
+			ApplianceConfig: config.ApplianceConfig,
+			EnvConfig:       config.EnvConfig,
+		})
+	}
+	if config.CoreOS == nil {
+		config.CoreOS = coreos.NewCoreOS(coreos.CoreOSConfig{
+			ApplianceConfig: config.ApplianceConfig,
+			EnvConfig:       config.EnvConfig,
+			Release:         config.Release,
+		})
+	}
+	if config.Skopeo == nil {
+		config.Skopeo = skopeo.NewSkopeo(nil)
+	}
+
+	return &lock{
+		LockConfig: config,
+	}
+}
+
+// Generate resolves the content of the appliance config into an appliance lock
+func (l *lock) Generate() (*types.ApplianceLock, error) {
+	applianceLock := &types.ApplianceLock{}
+
+	// Release
+	releaseImage, releaseVersion, err := l.ApplianceConfig.GetRelease()
+	if err != nil {
+		return nil, err
+	}
+	applianceLock.Release = types.LockedRelease{Version: releaseVersion, Image: releaseImage}
+
+	// CoreOS
+	diskImageURL, diskImageSha256, err := l.CoreOS.GetDiskImageLocation()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get CoreOS disk image location")
+	}
+	applianceLock.CoreOS = types.LockedCoreOS{DiskImageURL: diskImageURL, DiskImageSha256: diskImageSha256}
+
+	// Registry image
+	if applianceLock.RegistryImage, err = l.lockRegistryImage(); err != nil {
+		return nil, err
+	}
+
+	// Mirrored images
+	mapping, workspaceDir, err := l.getMapping()
+	if err != nil {
+		return nil, err
+	}
+
+	// Operators
+	if applianceLock.Operators, err = l.lockOperators(workspaceDir); err != nil {
+		return nil, err
+	}
+
+	if applianceLock.Images, err = l.lockImages(parseMappingSources(mapping), applianceLock.Operators); err != nil {
+		return nil, err
+	}
+
+	return applianceLock, nil
+}
+
+// lockImages pins the mirrored images by digest (fails if an image can't be pinned,
+// so builds from the same lock don't mirror different content)
+func (l *lock) lockImages(sources []string, operators []types.LockedOperator) ([]types.LockedImage, error) {
+	var images []types.LockedImage
+	for _, source := range sources {
+		// The catalogs are already pinned
+		image := ""
+		for _, operator := range operators {
+			if source == operator.Source {
+				image = operator.Catalog
+			}
+		}
+		if image == "" {
+			var err error
+			if image, err = l.Skopeo.PinImage(source); err != nil {
+				return nil, errors.Wrapf(err, "failed to pin image %s", source)
+			}
+		}
+		images = append(images, types.LockedImage{Source: source, Image: image})
+	}
+	return images, nil
+}
+
+// Verify ensures that the mirrored images and operator bundles match the appliance lock
+func (l *lock) Verify() error {
+	applianceLock := l.ApplianceConfig.Lock
+	if applianceLock == nil {
+		return nil
+	}
+
+	mappingPath := filepath.Join(l.EnvConfig.CacheDir, consts.OcMirrorMappingFileName)
+	mapping, err := os.ReadFile(mappingPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			logrus.Warnf("Skipping verification of mirrored images (%s not found)", consts.OcMirrorMappingFileName)
+			return nil
+		}
+		return err
+	}
+	mirroredImages := pinnedImages(applianceLock.Images, parseMappingSources(mapping))
+	if err = compareLists("mirrored images", lockedImages(applianceLock.Images), mirroredImages); err != nil {
+		return errors.Wrapf(err, "mirrored content doesn't match %s", config.ApplianceLockFilename)
+	}
+
+	bundles, err := readBundles(l.getMirrorWorkspaceDir())
+	if err != nil {
+		return err
+	}
+	for _, operator := range applianceLock.Operators {
+		var locked, mirrored []string
+		for _, b := range operator.Bundles {
+			locked = append(locked, b.Name)
+		}
+		for _, b := range filterBundles(bundles, operator.Bundles) {
+			mirrored = append(mirrored, b.Name)
+		}
+		if err = compareLists(fmt.Sprintf("bundles of %s", operator.Source), locked, mirrored); err != nil {
+			return errors.Wrapf(err, "mirrored content doesn't match %s", config.ApplianceLockFilename)
+		}
+	}
+
+	logrus.Infof("Mirrored content matches %s", config.ApplianceLockFilename)
+	return nil
+}
+
+func (l *lock) lockRegistryImage() (types.LockedImage, error) {
+	source := registry.GetRegistryImageURI(l.EnvConfig, l.ApplianceConfig)
+	if l.ApplianceConfig.Lock != nil && swag.StringValue(l.ApplianceConfig.Config.Registry.URI) != "" {
+		// The configured registry image was replaced by the locked one
+		source = l.ApplianceConfig.Lock.RegistryImage.Source
+	}
+	if source == consts.RegistryImage {
+		// Built locally, can't be pinned
+		return types.LockedImage{Source: source}, nil
+	}
+
+	image, err := l.Skopeo.PinImage(source)
+	if err != nil {
+		return types.LockedImage{}, errors.Wrapf(err, "failed to pin registry image %s", source)
+	}
+	return types.LockedImage{Source: source, Image: image}, nil
+}
+
+func (l *lock) lockOperators(workspaceDir string) ([]types.LockedOperator, error) {
+	if l.ApplianceConfig.Config.Mirror.Operators == nil {
+		return nil, nil
+	}
+
+	bundles, err := readBundles(workspaceDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var lockedOperators []types.LockedOperator
+	for i, operator := range *l.ApplianceConfig.Config.Mirror.Operators {
+		source := operator.Catalog
+		if l.ApplianceConfig.Lock != nil {
+			// The configured catalog was replaced by the locked one
+			source = l.ApplianceConfig.Lock.Operators[i].Source
+		}
+		catalog, err := l.Skopeo.PinImage(operator.Catalog)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to pin operator catalog %s", operator.Catalog)
+		}
+
+		var packages []string
+		for _, p := range operator.Packages {
+			packages = append(packages, p.Name)
+		}
+		var lockedBundles []types.LockedBundle
+		for _, b := range bundles {
+			if funk.ContainsString(packages, b.Package) {
+				lockedBundles = append(lockedBundles, b)
+			}
+		}
+		if len(lockedBundles) == 0 && len(packages) > 0 {
+			logrus.Warnf("No bundles found for operator catalog %s, only the catalog is pinned", source)
+		}
+
+		lockedOperators = append(lockedOperators, types.LockedOperator{
+			Source:  source,
+			Catalog: catalog,
+			Bundles: lockedBundles,
+		})
+	}
+	return lockedOperators, nil
+}
+
+// getMapping returns the oc mirror mapping and the workspace it was generated in.
+// The mapping of the build is used when available, otherwise oc mirror runs in dry-run mode.
+func (l *lock) getMapping() ([]byte, string, error) {
+	mappingPath := filepath.Join(l.EnvConfig.CacheDir, consts.OcMirrorMappingFileName)
+	if mapping, err := os.ReadFile(mappingPath); err == nil {
+		return mapping, l.getMirrorWorkspaceDir(), nil
+	}
+
+	mapping, err := l.Release.GetMappingFile()
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to resolve mirrored images")
+	}
+	return mapping, filepath.Join(l.EnvConfig.TempDir, consts.OcMirrorDryRunWorkspaceDir), nil
+}
+
+func (l *lock) getMirrorWorkspaceDir() string {
+	if mirrorPath := swag.StringValue(l.ApplianceConfig.Config.Mirror.Path); mirrorPath != "" {
+		return mirrorPath
+	}
+	return filepath.Join(l.EnvConfig.TempDir, consts.OcMirrorWorkspaceDir)
+}
+
+// parseMappingSources returns the sorted source images of an oc mirror mapping
+// (lines in the format of 'docker://<source>=docker://<destination>')
+func parseMappingSources(mapping []byte) []string {
+	var sources []string
+	scanner := bufio.NewScanner(bytes.NewReader(mapping))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		source, _, _ := strings.Cut(line, "=")
+		sources = append(sources, strings.TrimPrefix(source, "docker://"))
+	}
+	sources = funk.UniqString(sources)
+	sort.Strings(sources)
+	return sources
+}
+
+// lockedImages returns the pinned references of the locked images
+func lockedImages(images []types.LockedImage) []string {
+	var pinned []string
+	for _, image := range images {
+		pinned = append(pinned, image.Image)
+	}
+	sort.Strings(pinned)
+	return pinned
+}
+
+// pinnedImages returns the references of the mirrored images, replacing the locked sources
+// by their pinned references (i.e. the images that can't be mirrored by digest, e.g. of helm charts)
+func pinnedImages(images []types.LockedImage, sources []string) []string {
+	pinnedBySource := map[string]string{}
+	for _, image := range images {
+		pinnedBySource[image.Source] = image.Image
+	}
+	var pinned []string
+	for _, source := range sources {
+		if image, ok := pinnedBySource[source]; ok {
+			source = image
+		}
+		pinned = append(pinned, source)
+	}
+	pinned = funk.UniqString(pinned)
+	sort.Strings(pinned)
+	return pinned
+}
+
+// readBundles returns the bundles of the file-based catalogs filtered by oc mirror
+func readBundles(workspaceDir string) ([]types.LockedBundle, error) {
+	catalogsDir := filepath.Join(workspaceDir, operatorCatalogsDir)
+	if _, err := os.Stat(catalogsDir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	bundlesByName := map[string]types.LockedBundle{}
+	err := filepath.WalkDir(catalogsDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".json" {
+			return err
+		}
+		bundles, err := parseFBCBundles(path)
+		if err != nil {
+			logrus.Debugf("Skipping %s: %v", path, err)
+			return nil
+		}
+		for _, b := range bundles {
+			bundlesByName[b.Name] = b
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read operator catalogs from %s", catalogsDir)
+	}
+
+	var bundles []types.LockedBundle
+	for _, b := range bundlesByName {
+		bundles = append(bundles, b)
+	}
+	sort.Slice(bundles, func(i, j int) bool {
+		return bundles[i].Name < bundles[j].Name
+	})
+	return bundles, nil
+}
+
+// parseFBCBundles returns the bundles of a file-based catalog JSON file
+// (a stream of JSON objects of different schemas)
+func parseFBCBundles(path string) ([]types.LockedBundle, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			logrus.Errorf("Failed to close %s: %s", path, err.Error())
+		}
+	}()
+
+	var bundles []types.LockedBundle
+	decoder := json.NewDecoder(file)
+	for {
+		var b fbcBundle
+		if err := decoder.Decode(&b); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if b.Schema != fbcBundleSchema {
+			continue
+		}
+		locked := types.LockedBundle{Package: b.Package, Name: b.Name, Image: b.Image}
+		for _, p := range b.Properties {
+			if p.Type != fbcPackageProperty {
+				continue
+			}
+			var value struct {
+				Version string `json:"version"`
+			}
+			if err := json.Unmarshal(p.Value, &value); err == nil {
+				locked.Version = value.Version
+			}
+		}
+		bundles = append(bundles, locked)
+	}
+	return bundles, nil
+}
+
+// filterBundles returns the bundles of the packages in the locked bundles
+func filterBundles(bundles, lockedBundles []types.LockedBundle) []types.LockedBundle {
+	var packages []string
+	for _, b := range lockedBundles {
+		packages = append(packages, b.Package)
+	}
+	var filtered []types.LockedBundle
+	for _, b := range bundles {
+		if funk.ContainsString(packages, b.Package) {
+			filtered = append(filtered, b)
+		}
+	}
+	return filtered
+}
+
+// compareLists returns an error listing the missing and unexpected items
+func compareLists(name string, expected, actual []string) error {
+	missing, unexpected := funk.DifferenceString(expected, actual)
+	if len(missing) == 0 && len(unexpected) == 0 {
+		return nil
+	}
+	return errors.Errorf("%s differ (missing: %s, unexpected: %s)", name,
+		truncateList(missing), truncateList(unexpected))
+}
+
+func truncateList(list []string) string {
+	if len(list) > maxListedMismatches {
+		return fmt.Sprintf("%s and %d more", strings.Join(list[:maxListedMismatches], ", "), len(list)-maxListedMismatches)
+	}
+	return fmt.Sprintf("[%s]", strings.Join(list, ", "))
+}
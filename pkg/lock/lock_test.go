@@ -0,0 +1,182 @@
+package lock
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/appliance/pkg/asset/config"
+	"github.com/openshift/appliance/pkg/consts"
+	"github.com/openshift/appliance/pkg/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	testCatalogSource = "registry.redhat.io/redhat/redhat-operator-index:v4.18"
+	testCatalogImage  = "registry.redhat.io/redhat/redhat-operator-index@sha256:eee"
+
+	testMapping = `docker://quay.io/openshift-release-dev/ocp-v4.0-art-dev@sha256:aaa=docker://127.0.0.1:5005/openshift/release:aaa
+docker://quay.io/openshift-release-dev/ocp-v4.0-art-dev@sha256:bbb=docker://127.0.0.1:5005/openshift/release:bbb
+docker://quay.io/openshift-release-dev/ocp-v4.0-art-dev@sha256:aaa=docker://127.0.0.1:5005/openshift/release:aaa
+
+docker://registry.redhat.io/redhat/redhat-operator-index:v4.18=docker://127.0.0.1:5005/redhat/redhat-operator-index:v4.18
+`
+	testCatalog = `{"schema":"olm.package","name":"lvms-operator","defaultChannel":"stable-4.18"}
+{"schema":"olm.channel","name":"stable-4.18","package":"lvms-operator","entries":[{"name":"lvms-operator.v4.18.2"}]}
+{"schema":"olm.bundle","name":"lvms-operator.v4.18.2","package":"lvms-operator","image":"registry.redhat.io/lvms4/lvms-operator-bundle@sha256:ccc",
+ "properties":[{"type":"olm.gvk","value":{"group":"lvm.topolvm.io","kind":"LVMCluster","version":"v1alpha1"}},
+               {"type":"olm.package","value":{"packageName":"lvms-operator","version":"4.18.2"}}]}
+{"schema":"olm.bundle","name":"other-operator.v1.0.0","package":"other-operator","image":"registry.example.com/other-bundle@sha256:ddd",
+ "properties":[{"type":"olm.package","value":{"packageName":"other-operator","version":"1.0.0"}}]}
+`
+)
+
+// fakeSkopeo pins the images of the map (and fails for other images)
+type fakeSkopeo map[string]string
+
+func (f fakeSkopeo) CopyToFile(imageUrl, imageName, filePath string) error {
+	return nil
+}
+
+func (f fakeSkopeo) GetDigest(imageUrl string) (string, error) {
+	return "", nil
+}
+
+func (f fakeSkopeo) PinImage(imageUrl string) (string, error) {
+	if strings.Contains(imageUrl, "@") {
+		return imageUrl, nil
+	}
+	if image, ok := f[imageUrl]; ok {
+		return image, nil
+	}
+	return "", errors.Errorf("manifest unknown: %s", imageUrl)
+}
+
+// lockedMapping returns the locked images of the test mapping
+func lockedMapping() []types.LockedImage {
+	var images []types.LockedImage
+	for _, source := range parseMappingSources([]byte(testMapping)) {
+		image := source
+		if source == testCatalogSource {
+			image = testCatalogImage
+		}
+		images = append(images, types.LockedImage{Source: source, Image: image})
+	}
+	return images
+}
+
+var _ = Describe("Test Lock", func() {
+	It("parseMappingSources - sorted unique sources", func() {
+		Expect(parseMappingSources([]byte(testMapping))).To(Equal([]string{
+			"quay.io/openshift-release-dev/ocp-v4.0-art-dev@sha256:aaa",
+			"quay.io/openshift-release-dev/ocp-v4.0-art-dev@sha256:bbb",
+			"registry.redhat.io/redhat/redhat-operator-index:v4.18",
+		}))
+	})
+
+	It("readBundles - file-based catalog", func() {
+		workspaceDir := GinkgoT().TempDir()
+		catalogDir := filepath.Join(workspaceDir, operatorCatalogsDir, "redhat-operator-index", "filtered-catalogs")
+		Expect(os.MkdirAll(catalogDir, os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(catalogDir, "catalog.json"), []byte(testCatalog), 0600)).To(Succeed())
+
+		bundles, err := readBundles(workspaceDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bundles).To(Equal([]types.LockedBundle{
+			{Package: "lvms-operator", Name: "lvms-operator.v4.18.2", Version: "4.18.2", Image: "registry.redhat.io/lvms4/lvms-operator-bundle@sha256:ccc"},
+			{Package: "other-operator", Name: "other-operator.v1.0.0", Version: "1.0.0", Image: "registry.example.com/other-bundle@sha256:ddd"},
+		}))
+	})
+
+	It("readBundles - missing catalogs dir", func() {
+		bundles, err := readBundles(GinkgoT().TempDir())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bundles).To(BeEmpty())
+	})
+
+	It("lockImages - pins the images by digest", func() {
+		testLock := &lock{LockConfig{Skopeo: fakeSkopeo{
+			"quay.io/fedora/httpd-24:latest": "quay.io/fedora/httpd-24@sha256:fff",
+		}}}
+		operators := []types.LockedOperator{{Source: testCatalogSource, Catalog: testCatalogImage}}
+
+		images, err := testLock.lockImages([]string{
+			"quay.io/fedora/httpd-24:latest",
+			"quay.io/openshift-release-dev/ocp-v4.0-art-dev@sha256:aaa",
+			testCatalogSource,
+		}, operators)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(images).To(Equal([]types.LockedImage{
+			{Source: "quay.io/fedora/httpd-24:latest", Image: "quay.io/fedora/httpd-24@sha256:fff"},
+			{Source: "quay.io/openshift-release-dev/ocp-v4.0-art-dev@sha256:aaa", Image: "quay.io/openshift-release-dev/ocp-v4.0-art-dev@sha256:aaa"},
+			{Source: testCatalogSource, Image: testCatalogImage},
+		}))
+
+		_, err = testLock.lockImages([]string{"quay.io/fedora/nginx:1.26"}, operators)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("compareLists - lists differences", func() {
+		Expect(compareLists("images", []string{"a", "b"}, []string{"b", "a"})).To(Succeed())
+		err := compareLists("images", []string{"a", "b"}, []string{"b", "c"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("images differ (missing: [a], unexpected: [c])"))
+	})
+
+	Context("Verify", func() {
+		var (
+			envConfig       *config.EnvConfig
+			applianceConfig *config.ApplianceConfig
+			testLock        Lock
+		)
+
+		BeforeEach(func() {
+			envConfig = &config.EnvConfig{CacheDir: GinkgoT().TempDir(), TempDir: GinkgoT().TempDir()}
+			Expect(os.WriteFile(filepath.Join(envConfig.CacheDir, consts.OcMirrorMappingFileName), []byte(testMapping), 0600)).To(Succeed())
+			applianceConfig = &config.ApplianceConfig{
+				Config: &types.ApplianceConfig{},
+				Lock: &types.ApplianceLock{
+					Images: lockedMapping(),
+				},
+			}
+			testLock = NewLock(LockConfig{EnvConfig: envConfig, ApplianceConfig: applianceConfig})
+		})
+
+		It("matching content", func() {
+			Expect(testLock.Verify()).To(Succeed())
+		})
+
+		It("mismatching images", func() {
+			applianceConfig.Lock.Images = lockedMapping()[:1]
+			Expect(testLock.Verify()).ToNot(Succeed())
+		})
+
+		It("mismatching bundles", func() {
+			applianceConfig.Lock.Operators = []types.LockedOperator{{
+				Source:  testCatalogSource,
+				Catalog: testCatalogImage,
+				Bundles: []types.LockedBundle{{Package: "lvms-operator", Name: "lvms-operator.v4.18.1"}},
+			}}
+			catalogDir := filepath.Join(envConfig.TempDir, consts.OcMirrorWorkspaceDir, operatorCatalogsDir)
+			Expect(os.MkdirAll(catalogDir, os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(catalogDir, "catalog.json"), []byte(testCatalog), 0600)).To(Succeed())
+
+			err := testLock.Verify()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("lvms-operator.v4.18.2"))
+		})
+
+		It("not locked", func() {
+			applianceConfig.Lock = nil
+			Expect(testLock.Verify()).To(Succeed())
+		})
+	})
+})
+
+func TestLock(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "lock_test")
+}
@@ -17,6 +17,9 @@ type Filehook struct {
 	level     logrus.Level
 
 	truncateAtNewLine bool
+
+	// beforeWrite is invoked before writing each entry
+	beforeWrite func()
 }
 
 func NewFileHook(file io.Writer, level logrus.Level, formatter logrus.Formatter) *Filehook {
@@ -50,6 +53,10 @@ func (h Filehook) Fire(entry *logrus.Entry) error {
 	orig := entry.Message
 	defer func() { entry.Message = orig }()
 
+	if h.beforeWrite != nil {
+		h.beforeWrite()
+	}
+
 	msgs := []string{orig}
 	if h.truncateAtNewLine {
 		msgs = strings.Split(orig, "\n")
@@ -105,12 +112,15 @@ func SetupOutputHook(logLevel string) {
 	logrus.SetOutput(io.Discard)
 	logrus.SetLevel(logrus.TraceLevel)
 
+	// Redact secrets before writing the entries (the hooks are fired in order)
+	logrus.AddHook(RedactHook{})
+
 	level, err := logrus.ParseLevel(logLevel)
 	if err != nil {
 		level = logrus.InfoLevel
 	}
 
-	logrus.AddHook(NewFileHookWithNewlineTruncate(os.Stderr, level, &logrus.TextFormatter{
+	hook := NewFileHookWithNewlineTruncate(os.Stderr, level, &logrus.TextFormatter{
 		// Setting ForceColors is necessary because logrus.TextFormatter determines
 		// whether to enable colors by looking at the output of the logger.
 		// In this case, the output is io.Discard, which is not a terminal.
@@ -120,5 +130,8 @@ func SetupOutputHook(logLevel string) {
 		DisableTimestamp:       true,
 		DisableLevelTruncation: true,
 		DisableQuote:           true,
-	}))
+	})
+	// Avoid overwriting log messages with the multi-line progress (when enabled)
+	hook.beforeWrite = clearProgress
+	logrus.AddHook(hook)
 }
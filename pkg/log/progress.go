@@ -0,0 +1,138 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/briandowns/spinner"
+	"golang.org/x/term"
+)
+
+// progress renders the progress messages of concurrently running tasks,
+// one line per task (a single spinner line can't be shared between tasks).
+type progress struct {
+	mu       sync.Mutex
+	out      *os.File
+	terminal bool
+	spinners []*Spinner
+	lines    int
+	frame    int
+	ticker   *time.Ticker
+	done     chan struct{}
+}
+
+var (
+	// multiProgress is the enabled multi-line progress (nil when disabled)
+	multiProgress   *progress
+	multiProgressMu sync.RWMutex
+)
+
+// getMultiProgress returns the enabled multi-line progress (or nil)
+func getMultiProgress() *progress {
+	multiProgressMu.RLock()
+	defer multiProgressMu.RUnlock()
+	return multiProgress
+}
+
+func setMultiProgress(p *progress) {
+	multiProgressMu.Lock()
+	defer multiProgressMu.Unlock()
+	multiProgress = p
+}
+
+// EnableMultiProgress switches new spinners to a multi-line progress display.
+// Should be used while running tasks concurrently.
+// Returns a function for restoring the single-line spinners.
+func EnableMultiProgress() func() {
+	p := &progress{
+		out:      os.Stderr,
+		terminal: term.IsTerminal(int(os.Stderr.Fd())),
+		ticker:   time.NewTicker(100 * time.Millisecond),
+		done:     make(chan struct{}),
+	}
+	setMultiProgress(p)
+
+	go func() {
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-p.ticker.C:
+				p.render()
+			}
+		}
+	}()
+
+	return func() {
+		p.ticker.Stop()
+		close(p.done)
+		p.mu.Lock()
+		p.clear()
+		p.mu.Unlock()
+		setMultiProgress(nil)
+	}
+}
+
+func (p *progress) add(s *Spinner) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.spinners = append(p.spinners, s)
+}
+
+func (p *progress) remove(s *Spinner) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	for i, item := range p.spinners {
+		if item == s {
+			p.spinners = append(p.spinners[:i], p.spinners[i+1:]...)
+			break
+		}
+	}
+}
+
+func (p *progress) setSuffix(s *Spinner, suffix string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s.suffix = suffix
+}
+
+func (p *progress) render() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.terminal {
+		return
+	}
+
+	p.clear()
+	p.frame++
+	chars := spinner.CharSets[9]
+	for _, s := range p.spinners {
+		_, _ = fmt.Fprintf(p.out, "\033[34m%s\033[0m%s\n", chars[p.frame%len(chars)], s.suffix)
+	}
+	p.lines = len(p.spinners)
+}
+
+// clear erases the rendered lines (the caller should hold the lock)
+func (p *progress) clear() {
+	if p.lines == 0 {
+		return
+	}
+	// Move the cursor up to the first line and clear to the end of the screen
+	_, _ = fmt.Fprintf(p.out, "\033[%dA\033[J", p.lines)
+	p.lines = 0
+}
+
+// clearProgress erases the multi-line progress (if enabled),
+// so log messages aren't overwritten by the next render.
+func clearProgress() {
+	p := getMultiProgress()
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+}
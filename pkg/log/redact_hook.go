@@ -0,0 +1,27 @@
+package log
+
+import (
+	"github.com/openshift/appliance/pkg/log/redact"
+	"github.com/sirupsen/logrus"
+)
+
+// RedactHook redacts the secrets from the log entries
+// (should be added before the hooks that write the entries).
+type RedactHook struct{}
+
+func (h RedactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h RedactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = redact.String(entry.Message)
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			entry.Data[key] = redact.String(v)
+		case error:
+			entry.Data[key] = redact.String(v.Error())
+		}
+	}
+	return nil
+}
@@ -25,24 +25,37 @@ type Spinner struct {
 	Ticker                                          *time.Ticker
 	ProgressMessage, SuccessMessage, FailureMessage string
 	FileToMonitor, DirToMonitor                     string
+
+	// progress is the multi-line progress rendering the spinner (nil for a single-line spinner)
+	progress *progress
+	// suffix is the rendered message when using a multi-line progress
+	suffix string
 }
 
 func NewSpinner(progressMessage, successMessage, failureMessage string, envConfig *config.EnvConfig) *Spinner {
-	// Create and start spinner with message
-	s := spinner.New(spinner.CharSets[9], 100*time.Millisecond, spinner.WithWriter(os.Stderr))
-	s.Suffix = fmt.Sprintf(" %s", progressMessage)
-	if err := s.Color("blue"); err != nil {
-		logrus.Fatalln(err)
-	}
-	s.Start()
-
 	wrapper := &Spinner{
-		Spinner:         s,
 		ProgressMessage: progressMessage,
 		SuccessMessage:  successMessage,
 		FailureMessage:  failureMessage,
 	}
 
+	// Use a line of the multi-line progress when running concurrently
+	p := getMultiProgress()
+	if p != nil {
+		wrapper.progress = p
+		wrapper.suffix = fmt.Sprintf(" %s", progressMessage)
+		p.add(wrapper)
+	} else {
+		// Create and start spinner with message
+		s := spinner.New(spinner.CharSets[9], 100*time.Millisecond, spinner.WithWriter(os.Stderr))
+		s.Suffix = fmt.Sprintf(" %s", progressMessage)
+		if err := s.Color("blue"); err != nil {
+			logrus.Fatalln(err)
+		}
+		s.Start()
+		wrapper.Spinner = s
+	}
+
 	wrapper.Ticker = time.NewTicker(1 * time.Second)
 	go func() {
 		for range wrapper.Ticker.C {
@@ -51,7 +64,12 @@ func NewSpinner(progressMessage, successMessage, failureMessage string, envConfi
 				continue
 			}
 
-			s.Suffix = fmt.Sprintf(" %s (%s)", progressMessage, humanize.Bytes(size))
+			suffix := fmt.Sprintf(" %s (%s)", progressMessage, humanize.Bytes(size))
+			if p != nil {
+				p.setSuffix(wrapper, suffix)
+			} else {
+				wrapper.Spinner.Suffix = suffix
+			}
 		}
 	}()
 
@@ -100,7 +118,11 @@ func StopSpinner(spinner *Spinner, err error) error {
 	if spinner == nil {
 		return err
 	}
-	spinner.Spinner.Stop()
+	if spinner.Spinner != nil {
+		spinner.Spinner.Stop()
+	} else if spinner.progress != nil {
+		spinner.progress.remove(spinner)
+	}
 	spinner.Ticker.Stop()
 	if err != nil {
 		logrus.Error(spinner.FailureMessage)
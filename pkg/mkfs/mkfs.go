@@ -0,0 +1,51 @@
+package mkfs
+
+import (
+	"fmt"
+
+	"github.com/openshift/appliance/pkg/executer"
+)
+
+// Both tools honour the SOURCE_DATE_EPOCH env var (fixed timestamps for reproducible builds)
+const (
+	genSquashfsImageCmd = "mksquashfs %s %s/%s -comp zstd -noappend -no-progress -quiet"
+	genErofsImageCmd    = "mkfs.erofs -zlz4hc -L %s %s/%s %s"
+	genErofsUUIDFlag    = " -U %s"
+)
+
+// MkFs generates compressed read-only filesystem images
+// (used as an alternative to ISO9660 for the data partition).
+type MkFs interface {
+	GenerateSquashfsImage(imagePath, imageName, dirPath string) error
+	GenerateErofsImage(imagePath, imageName, dirPath, volumeName, uuid string) error
+}
+
+type mkfs struct {
+	executer executer.Executer
+}
+
+func NewMkFs(exec executer.Executer) MkFs {
+	if exec == nil {
+		exec = executer.NewExecuter()
+	}
+
+	return &mkfs{
+		executer: exec,
+	}
+}
+
+func (m *mkfs) GenerateSquashfsImage(imagePath, imageName, dirPath string) error {
+	_, err := m.executer.Execute(fmt.Sprintf(genSquashfsImageCmd, dirPath, imagePath, imageName))
+	return err
+}
+
+// GenerateErofsImage generates an EROFS image of dirPath.
+// The filesystem UUID is random unless specified (i.e. for reproducible builds).
+func (m *mkfs) GenerateErofsImage(imagePath, imageName, dirPath, volumeName, uuid string) error {
+	cmd := fmt.Sprintf(genErofsImageCmd, volumeName, imagePath, imageName, dirPath)
+	if uuid != "" {
+		cmd += fmt.Sprintf(genErofsUUIDFlag, uuid)
+	}
+	_, err := m.executer.Execute(cmd)
+	return err
+}
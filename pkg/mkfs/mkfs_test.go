@@ -0,0 +1,74 @@
+package mkfs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/appliance/pkg/executer"
+)
+
+var _ = Describe("Test MkFs", func() {
+	var (
+		ctrl           *gomock.Controller
+		mockExecuter   *executer.MockExecuter
+		testMkFs       MkFs
+		fakeCachePath  = "/path/to/cache"
+		fakeDataPath   = "/path/to/data"
+		fakeVolumeName = "testvolume"
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockExecuter = executer.NewMockExecuter(ctrl)
+		testMkFs = NewMkFs(mockExecuter)
+	})
+
+	It("GenerateSquashfsImage - success", func() {
+		cmd := fmt.Sprintf(genSquashfsImageCmd, fakeDataPath, fakeCachePath, "testdata.squashfs")
+		mockExecuter.EXPECT().Execute(cmd).Return("", nil).Times(1)
+
+		err := testMkFs.GenerateSquashfsImage(fakeCachePath, "testdata.squashfs", fakeDataPath)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("GenerateSquashfsImage - failure", func() {
+		mockExecuter.EXPECT().Execute(gomock.Any()).Return("", errors.New("some error")).Times(1)
+
+		err := testMkFs.GenerateSquashfsImage(fakeCachePath, "testdata.squashfs", fakeDataPath)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("GenerateErofsImage - success", func() {
+		cmd := fmt.Sprintf(genErofsImageCmd, fakeVolumeName, fakeCachePath, "testdata.erofs", fakeDataPath)
+		mockExecuter.EXPECT().Execute(cmd).Return("", nil).Times(1)
+
+		err := testMkFs.GenerateErofsImage(fakeCachePath, "testdata.erofs", fakeDataPath, fakeVolumeName, "")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("GenerateErofsImage - with UUID", func() {
+		uuid := "11111111-2222-4333-8444-555555555555"
+		cmd := fmt.Sprintf(genErofsImageCmd, fakeVolumeName, fakeCachePath, "testdata.erofs", fakeDataPath) +
+			fmt.Sprintf(genErofsUUIDFlag, uuid)
+		mockExecuter.EXPECT().Execute(cmd).Return("", nil).Times(1)
+
+		err := testMkFs.GenerateErofsImage(fakeCachePath, "testdata.erofs", fakeDataPath, fakeVolumeName, uuid)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("GenerateErofsImage - failure", func() {
+		mockExecuter.EXPECT().Execute(gomock.Any()).Return("", errors.New("some error")).Times(1)
+
+		err := testMkFs.GenerateErofsImage(fakeCachePath, "testdata.erofs", fakeDataPath, fakeVolumeName, "")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+func TestMkFs(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "mkfs_test")
+}
@@ -28,6 +28,10 @@ const (
 	registryLoadCmd      = "skopeo copy dir:%s/registry containers-storage:localhost/registry:latest"
 	registryRunBinaryCmd = "/registry serve config.yml"
 
+	registryGarbageCollectCmd    = "podman run --rm --net=host --privileged -v %s:/var/lib/registry %s garbage-collect --delete-untagged config.yml"
+	registryGarbageCollectCmdOcp = "podman run --rm --net=host --privileged -v %s:/var/lib/registry -u 0 --entrypoint=/usr/bin/distribution containers-storage:%s garbage-collect --delete-untagged /etc/registry/config.yaml"
+	registryGarbageCollectBinary = "env REGISTRY_STORAGE_FILESYSTEM_ROOTDIRECTORY=%s /registry garbage-collect --delete-untagged config.yml"
+
 	registryAttempts             = 3
 	registrySleepBetweenAttempts = 5
 
@@ -38,6 +42,7 @@ const (
 type Registry interface {
 	StartRegistry() error
 	StopRegistry() error
+	GarbageCollect() error
 }
 
 type HTTPClient interface {
@@ -159,6 +164,28 @@ func (r *registry) StopRegistry() error {
 	return nil
 }
 
+// GarbageCollect removes blobs and untagged manifests that are no longer
+// referenced from the registry data dir. The registry must be stopped first
+// (or, for the registry binary, which runs until the build exits, no longer pushed to).
+func (r *registry) GarbageCollect() error {
+	var cmd string
+	switch {
+	case r.UseBinary:
+		// Same storage root as the registry binary (see runRegistryBinary)
+		cmd = fmt.Sprintf(registryGarbageCollectBinary, r.DataDirPath)
+	case r.UseOcpRegistry:
+		cmd = fmt.Sprintf(registryGarbageCollectCmdOcp, r.DataDirPath, r.URI)
+	default:
+		cmd = fmt.Sprintf(registryGarbageCollectCmd, r.DataDirPath, r.URI)
+	}
+	logrus.Debugf("Running registry garbage collection: %s", cmd)
+
+	if _, err := r.Executer.Execute(cmd); err != nil {
+		return errors.Wrapf(err, "registry garbage collection failure")
+	}
+	return nil
+}
+
 func GetRegistryDataPath(directory, subDirectory string) (string, error) {
 	pwd, err := os.Getwd()
 	if err != nil {
@@ -196,7 +223,7 @@ func LoadRegistryImage(cacheDir string) error {
 // Returns true only if no user config is set AND OCP version >= 4.21 AND OCP release has docker-registry available
 func ShouldUseOcpRegistry(envConfig *config.EnvConfig, applianceConfig *config.ApplianceConfig) bool {
 	// Only use OCP registry if user hasn't configured their own imageRegistry.uri
-	if swag.StringValue(applianceConfig.Config.ImageRegistry.URI) != "" {
+	if swag.StringValue(applianceConfig.Config.Registry.URI) != "" {
 		logrus.Debug("User-configured registry detected, not using OCP docker-registry")
 		return false
 	}
@@ -256,7 +283,7 @@ func ocpVersionContainsDistributionRegistry(applianceConfig *config.ApplianceCon
 // GetRegistryImageURI returns the registry image URI to use based on configuration priority
 func GetRegistryImageURI(envConfig *config.EnvConfig, applianceConfig *config.ApplianceConfig) string {
 	// First priority: appliance config imageRegistry.uri (user-specified)
-	sourceRegistryUri := swag.StringValue(applianceConfig.Config.ImageRegistry.URI)
+	sourceRegistryUri := swag.StringValue(applianceConfig.Config.Registry.URI)
 	if sourceRegistryUri != "" {
 		return sourceRegistryUri
 	}
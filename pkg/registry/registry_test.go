@@ -127,6 +127,75 @@ var _ = Describe("Test Image Registry", func() {
 		err := imageRegistry.StopRegistry()
 		Expect(err).To(HaveOccurred())
 	})
+
+	It("Garbage Collect - Success", func() {
+		dataDirPath := "/fake/path/data"
+		mockExecuter.EXPECT().Execute(fmt.Sprintf(registryGarbageCollectCmd, dataDirPath, uri)).Return("", nil).Times(1)
+
+		imageRegistry := NewRegistry(
+			RegistryConfig{
+				URI:         uri,
+				Port:        port,
+				Executer:    mockExecuter,
+				HTTPClient:  &ClientMock{},
+				DataDirPath: dataDirPath,
+			})
+
+		err := imageRegistry.GarbageCollect()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("Garbage Collect - OCP registry", func() {
+		dataDirPath := "/fake/path/data"
+		mockExecuter.EXPECT().Execute(fmt.Sprintf(registryGarbageCollectCmdOcp, dataDirPath, uri)).Return("", nil).Times(1)
+
+		imageRegistry := NewRegistry(
+			RegistryConfig{
+				URI:            uri,
+				Port:           port,
+				Executer:       mockExecuter,
+				HTTPClient:     &ClientMock{},
+				DataDirPath:    dataDirPath,
+				UseOcpRegistry: true,
+			})
+
+		err := imageRegistry.GarbageCollect()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("Garbage Collect - registry binary", func() {
+		dataDirPath := "/fake/path/data"
+		mockExecuter.EXPECT().Execute(fmt.Sprintf(registryGarbageCollectBinary, dataDirPath)).Return("", nil).Times(1)
+
+		imageRegistry := NewRegistry(
+			RegistryConfig{
+				URI:         uri,
+				Port:        port,
+				Executer:    mockExecuter,
+				HTTPClient:  &ClientMock{},
+				DataDirPath: dataDirPath,
+				UseBinary:   true,
+			})
+
+		err := imageRegistry.GarbageCollect()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("Garbage Collect - Fail", func() {
+		mockExecuter.EXPECT().Execute(gomock.Any()).Return("", errors.New("some error")).Times(1)
+
+		imageRegistry := NewRegistry(
+			RegistryConfig{
+				URI:         uri,
+				Port:        port,
+				Executer:    mockExecuter,
+				HTTPClient:  &ClientMock{},
+				DataDirPath: "/fake/path/data",
+			})
+
+		err := imageRegistry.GarbageCollect()
+		Expect(err).To(HaveOccurred())
+	})
 })
 
 func TestRegistry(t *testing.T) {
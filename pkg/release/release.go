@@ -73,6 +73,8 @@ type ReleaseConfig struct {
 	EnvConfig       *config.EnvConfig
 	ApplianceConfig *config.ApplianceConfig
 	OSInterface     fileutil.OSInterface
+	// CopyHelmCharts copies the mirrored helm charts archives to the cache dir (i.e. for the data partition)
+	CopyHelmCharts bool
 }
 
 type release struct {
@@ -211,7 +213,7 @@ func (r *release) execute(command string) (string, error) {
 	return "", err
 }
 
-func (r *release) mirrorImages(imageSetFile, blockedImages, additionalImages, operators string) error {
+func (r *release) mirrorImages(imageSetFile, blockedImages, additionalImages, operators, helm string) error {
 	var tempDir string
 
 	isStable, err := r.IsStableRelease()
@@ -221,15 +223,15 @@ func (r *release) mirrorImages(imageSetFile, blockedImages, additionalImages, op
 
 	// If a mirror path is provided in appliance-config, use it directly instead of running oc-mirror
 	var mirrorPath string
-	if r.ApplianceConfig.Config.MirrorPath != nil {
-		mirrorPath = *r.ApplianceConfig.Config.MirrorPath
+	if r.ApplianceConfig.Config.Mirror.Path != nil {
+		mirrorPath = *r.ApplianceConfig.Config.Mirror.Path
 	}
 
 	if mirrorPath == "" {
 		// Normal mirroring flow - run oc-mirror
 		if err := templates.RenderTemplateFile(
 			imageSetFile,
-			templates.GetImageSetTemplateData(r.ApplianceConfig, blockedImages, additionalImages, operators),
+			templates.GetImageSetTemplateData(r.ApplianceConfig, blockedImages, additionalImages, operators, helm),
 			r.EnvConfig.TempDir); err != nil {
 			return err
 		}
@@ -243,8 +245,8 @@ func (r *release) mirrorImages(imageSetFile, blockedImages, additionalImages, op
 			return err
 		}
 
-		tempDir = filepath.Join(r.EnvConfig.TempDir, "oc-mirror")
-		registryPort := swag.IntValue(r.ApplianceConfig.Config.ImageRegistry.Port)
+		tempDir = filepath.Join(r.EnvConfig.TempDir, consts.OcMirrorWorkspaceDir)
+		registryPort := swag.IntValue(r.ApplianceConfig.Config.Registry.Port)
 		cmd := fmt.Sprintf(ocMirror, imageSetFilePath, registryPort, tempDir)
 
 		if !isStable {
@@ -269,7 +271,7 @@ func (r *release) mirrorImages(imageSetFile, blockedImages, additionalImages, op
 	}
 
 	// Copy generated yaml files to cache dir (works for both mirror path and oc-mirror output)
-	if err := r.copyOutputYamls(tempDir, r.ApplianceConfig.Config.EnableInteractiveFlow); err != nil {
+	if err := r.copyOutputYamls(tempDir, r.ApplianceConfig.Config.Cluster.EnableInteractiveFlow); err != nil {
 		return err
 	}
 
@@ -278,6 +280,13 @@ func (r *release) mirrorImages(imageSetFile, blockedImages, additionalImages, op
 		return err
 	}
 
+	// Copy the helm charts archives (works for both mirror path and oc-mirror output)
+	if r.CopyHelmCharts {
+		if err := r.copyHelmCharts(tempDir); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -297,6 +306,35 @@ func (r *release) copyMappingFile(ocMirrorDir string) error {
 	return nil
 }
 
+// copyHelmCharts copies the helm charts archives downloaded by oc mirror to the cache dir
+// (to be included in the data partition)
+func (r *release) copyHelmCharts(ocMirrorDir string) error {
+	// Replace the charts of previous builds
+	chartsDir := filepath.Join(r.EnvConfig.CacheDir, consts.HelmChartsDir)
+	if err := r.OSInterface.RemoveAll(chartsDir); err != nil {
+		return err
+	}
+
+	charts, err := filepath.Glob(filepath.Join(ocMirrorDir, "working-dir", consts.OcMirrorHelmChartsDir, "*.tgz"))
+	if err != nil {
+		return err
+	}
+	if len(charts) == 0 {
+		return nil
+	}
+	if err = r.OSInterface.MkdirAll(chartsDir, os.ModePerm); err != nil {
+		return err
+	}
+	for _, chart := range charts {
+		logrus.Debugf("Copying helm chart from oc-mirror output: %s", chart)
+		if err = fileutil.CopyFile(chart, filepath.Join(chartsDir, filepath.Base(chart))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (r *release) copyOutputYamls(ocMirrorDir string, enableInteractiveFlow *bool) error {
 	// If interactive flow is enabled, use localhost as registry domain, otherwise use the default registry domain
 	var registryDomain string
@@ -320,7 +358,7 @@ func (r *release) copyOutputYamls(ocMirrorDir string, enableInteractiveFlow *boo
 		}
 
 		// Replace localhost with internal registry URI
-		buildRegistryURI := fmt.Sprintf("127.0.0.1:%d", swag.IntValue(r.ApplianceConfig.Config.ImageRegistry.Port))
+		buildRegistryURI := fmt.Sprintf("127.0.0.1:%d", swag.IntValue(r.ApplianceConfig.Config.Registry.Port))
 		internalRegistryURI := fmt.Sprintf("%s:%d", registryDomain, registry.RegistryPort)
 		newYaml := strings.ReplaceAll(string(yamlBytes), buildRegistryURI, internalRegistryURI)
 
@@ -412,8 +450,42 @@ func (r *release) generateOperatorsList(operators *[]types.Operator) string {
 	if operators == nil {
 		return ""
 	}
+	// Omit the installation config of the packages (not an oc-mirror field)
+	mirrorOperators := make([]types.Operator, len(*operators))
+	for i, operator := range *operators {
+		mirrorOperators[i] = operator
+		mirrorOperators[i].Packages = make([]types.IncludePackage, len(operator.Packages))
+		for j, p := range operator.Packages {
+			p.InstallConfig = types.InstallConfig{}
+			mirrorOperators[i].Packages[j] = p
+		}
+	}
+
+	var result strings.Builder
+	obj, err := yaml.Marshal(mirrorOperators)
+	if err != nil {
+		return ""
+	}
+	result.WriteString(indent.String("    ", string(obj)))
+	return result.String()
+}
+
+func (r *release) generateHelmConfig(helm *types.Helm) string {
+	if helm == nil {
+		return ""
+	}
+	// Resolve the paths of the local charts (relative to the assets directory)
+	mirrorHelm := *helm
+	mirrorHelm.Local = make([]types.HelmChart, len(helm.Local))
+	for i, chart := range helm.Local {
+		if chart.Path != "" && !filepath.IsAbs(chart.Path) {
+			chart.Path = filepath.Join(r.EnvConfig.AssetsDir, chart.Path)
+		}
+		mirrorHelm.Local[i] = chart
+	}
+
 	var result strings.Builder
-	obj, err := yaml.Marshal(operators)
+	obj, err := yaml.Marshal(mirrorHelm)
 	if err != nil {
 		return ""
 	}
@@ -424,9 +496,10 @@ func (r *release) generateOperatorsList(operators *[]types.Operator) string {
 func (r *release) MirrorInstallImages() error {
 	return r.mirrorImages(
 		consts.ImageSetTemplateFile,
-		r.generateImagesList(r.ApplianceConfig.Config.BlockedImages),
-		r.generateImagesList(r.ApplianceConfig.Config.AdditionalImages),
-		r.generateOperatorsList(r.ApplianceConfig.Config.Operators),
+		r.generateImagesList(r.ApplianceConfig.Config.Mirror.BlockedImages),
+		r.generateImagesList(r.ApplianceConfig.Config.Mirror.AdditionalImages),
+		r.generateOperatorsList(r.ApplianceConfig.Config.Mirror.Operators),
+		r.generateHelmConfig(r.ApplianceConfig.Config.Mirror.Helm),
 	)
 }
 
@@ -437,9 +510,10 @@ func (r *release) GetMappingFile() ([]byte, error) {
 	if err := templates.RenderTemplateFile(
 		consts.ImageSetTemplateFile,
 		templates.GetImageSetTemplateData(r.ApplianceConfig,
-			r.generateImagesList(r.ApplianceConfig.Config.BlockedImages),
-			r.generateImagesList(r.ApplianceConfig.Config.AdditionalImages),
-			r.generateOperatorsList(r.ApplianceConfig.Config.Operators)),
+			r.generateImagesList(r.ApplianceConfig.Config.Mirror.BlockedImages),
+			r.generateImagesList(r.ApplianceConfig.Config.Mirror.AdditionalImages),
+			r.generateOperatorsList(r.ApplianceConfig.Config.Mirror.Operators),
+			r.generateHelmConfig(r.ApplianceConfig.Config.Mirror.Helm)),
 		r.EnvConfig.TempDir); err != nil {
 		return nil, err
 	}
@@ -449,8 +523,8 @@ func (r *release) GetMappingFile() ([]byte, error) {
 		return nil, err
 	}
 
-	dryRunDir := filepath.Join(r.EnvConfig.TempDir, "oc-mirror-dry-run")
-	registryPort := swag.IntValue(r.ApplianceConfig.Config.ImageRegistry.Port)
+	dryRunDir := filepath.Join(r.EnvConfig.TempDir, consts.OcMirrorDryRunWorkspaceDir)
+	registryPort := swag.IntValue(r.ApplianceConfig.Config.Registry.Port)
 	dryRunCmd := fmt.Sprintf(ocMirrorDryRun, imageSetFilePath, registryPort, dryRunDir)
 
 	// Add --ignore-release-signature for CI/nightly builds to avoid signature verification errors
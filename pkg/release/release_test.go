@@ -12,7 +12,9 @@ import (
 	. "github.com/onsi/ginkgo/v2/dsl/core"
 	. "github.com/onsi/gomega"
 	"github.com/openshift/appliance/pkg/asset/config"
+	"github.com/openshift/appliance/pkg/consts"
 	"github.com/openshift/appliance/pkg/executer"
+	"github.com/openshift/appliance/pkg/fileutil"
 	"github.com/openshift/appliance/pkg/graph"
 	"github.com/openshift/appliance/pkg/types"
 )
@@ -75,7 +77,7 @@ var _ = Describe("Test Release", func() {
 					Version:         "4.13.1",
 					Channel:         &channel,
 				},
-				ImageRegistry: &types.ImageRegistry{
+				Registry: types.RegistryConfig{
 					Port: swag.Int(5123),
 				},
 			},
@@ -125,6 +127,77 @@ var _ = Describe("Test Release", func() {
 		Expect(err).To(HaveOccurred())
 	})
 
+	It("MirrorInstallImages - keeps the cached helm charts", func() {
+		cacheDir := GinkgoT().TempDir()
+		chart := filepath.Join(cacheDir, consts.HelmChartsDir, "podinfo-6.0.0.tgz")
+		Expect(os.MkdirAll(filepath.Dir(chart), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(chart, []byte("chart"), 0600)).To(Succeed())
+		testRelease = NewRelease(ReleaseConfig{
+			OSInterface:     &fileutil.OSFS{},
+			ApplianceConfig: applianceConfig,
+			Executer:        mockExecuter,
+			EnvConfig: &config.EnvConfig{
+				TempDir:  tempDir,
+				CacheDir: cacheDir,
+			},
+		})
+
+		// Mock IsStableRelease call
+		metadataCmd := fmt.Sprintf(templateGetMetadata, swag.StringValue(applianceConfig.Config.OcpRelease.URL))
+		jsonOutput := `{"metadata":{"version":"4.13.1"}}`
+		mockExecuter.EXPECT().Execute(metadataCmd).Return(jsonOutput, nil).Times(1)
+
+		// Mock oc mirror command
+		mockExecuter.EXPECT().Execute(gomock.Any()).Return("", nil).Times(1)
+
+		err = testRelease.MirrorInstallImages()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(chart).To(BeAnExistingFile())
+	})
+
+	It("generateOperatorsList - omits the installation config", func() {
+		operators := []types.Operator{{
+			Catalog: "registry.redhat.io/redhat/redhat-operator-index:v4.19",
+			IncludeConfig: types.IncludeConfig{
+				Packages: []types.IncludePackage{{
+					Name:     "lvms-operator",
+					Channels: []types.IncludeChannel{{Name: "stable-4.19"}},
+					InstallConfig: types.InstallConfig{
+						Install:             swag.Bool(true),
+						InstallPlanApproval: swag.String("Manual"),
+					},
+				}},
+			},
+		}}
+
+		res := (&release{}).generateOperatorsList(&operators)
+		Expect(res).To(ContainSubstring("name: lvms-operator"))
+		Expect(res).To(ContainSubstring("name: stable-4.19"))
+		Expect(res).NotTo(ContainSubstring("install"))
+		Expect(operators[0].Packages[0].Install).NotTo(BeNil())
+	})
+
+	It("generateHelmConfig - resolves the local charts paths", func() {
+		helm := &types.Helm{
+			Repositories: []types.HelmRepository{{
+				Name:   "podinfo",
+				URL:    "https://stefanprodan.github.io/podinfo",
+				Charts: []types.HelmChart{{Name: "podinfo", Version: "5.0.0"}},
+			}},
+			Local: []types.HelmChart{
+				{Name: "relative", Path: "charts/relative-0.1.0.tgz"},
+				{Name: "absolute", Path: "/charts/absolute-0.1.0.tgz"},
+			},
+		}
+
+		res := (&release{EnvConfig: &config.EnvConfig{AssetsDir: "/assets"}}).generateHelmConfig(helm)
+		Expect(res).To(ContainSubstring("    repositories:\n"))
+		Expect(res).To(ContainSubstring("url: https://stefanprodan.github.io/podinfo"))
+		Expect(res).To(ContainSubstring("path: /assets/charts/relative-0.1.0.tgz"))
+		Expect(res).To(ContainSubstring("path: /charts/absolute-0.1.0.tgz"))
+		Expect(helm.Local[0].Path).To(Equal("charts/relative-0.1.0.tgz"))
+	})
+
 	Context("MirrorInstallImages with signature handling", func() {
 		It("should add --ignore-release-signature for CI release", func() {
 			// Set up CI release
@@ -114,7 +114,7 @@ func buildDisableSigstoreRegistriesConfig(registryHosts []string) []byte {
 }
 
 func (r *release) disableSigstoreForRelevantRegistries() error {
-	registryHosts := disableSigstoreRegistryHosts(r.ApplianceConfig.Config.DisableSigstoreRegistries)
+	registryHosts := disableSigstoreRegistryHosts(r.ApplianceConfig.Config.Mirror.DisableSigstoreRegistries)
 	if len(registryHosts) == 0 {
 		return nil
 	}
@@ -0,0 +1,46 @@
+package reproducible
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/openshift/appliance/pkg/sgdisk"
+)
+
+const (
+	// CoreOS images use this disk GUID to mark a disk whose GUIDs should be
+	// randomized on first boot, so it's kept as is
+	coreosUninitializedDiskGUID = "00000000-0000-4000-a000-000000000001"
+)
+
+// SetPartitionTableGUIDs replaces the (random) GPT disk GUID and partitions unique GUIDs
+// of the image with GUIDs derived from the seed.
+func SetPartitionTableGUIDs(imagePath, seed string) error {
+	gdisk := sgdisk.NewSgdisk(nil)
+	table, err := gdisk.GetPartitionTable(imagePath)
+	if err != nil {
+		return err
+	}
+	diskGUID, partitionGUIDs := partitionTableGUIDs(table, seed)
+	return gdisk.SetGUIDs(imagePath, diskGUID, partitionGUIDs)
+}
+
+// MBRID returns an MBR disk signature derived from the seed
+func MBRID(seed string) uint32 {
+	return binary.LittleEndian.Uint32(seededBytes(seed, "mbr-disk-signature", 4))
+}
+
+// partitionTableGUIDs returns the GUIDs derived from the seed for the disk
+// (empty when it should be kept) and for each partition of the table
+func partitionTableGUIDs(table *sgdisk.PartitionTable, seed string) (string, map[int]string) {
+	var diskGUID string
+	if !strings.EqualFold(table.DiskGUID, coreosUninitializedDiskGUID) {
+		diskGUID = UUID(seed, "gpt-disk")
+	}
+	partitionGUIDs := map[int]string{}
+	for _, number := range table.PartitionNumbers {
+		partitionGUIDs[number] = UUID(seed, fmt.Sprintf("gpt-partition-%d", number))
+	}
+	return diskGUID, partitionGUIDs
+}
@@ -0,0 +1,68 @@
+package reproducible
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/blowfish"
+)
+
+const (
+	bcryptSaltSize = 16
+	bcryptHashSize = 23
+)
+
+var (
+	// bcrypt uses a non-standard base64 alphabet (without padding)
+	bcryptEncoding = base64.NewEncoding("./ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789").WithPadding(base64.NoPadding)
+
+	// The bcrypt initial cipher data
+	bcryptMagic = []byte("OrpheanBeholderScryDoubt")
+)
+
+// HashPassword returns the bcrypt hash of the password of the user.
+// When a seed is specified, the salt is derived from it and the user name (instead of a random salt),
+// so the same password, user and seed always produce the same hash.
+func HashPassword(password, user, seed string) (string, error) {
+	if seed == "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		return string(hash), err
+	}
+
+	// Same as bcrypt.GenerateFromPassword (which doesn't allow specifying the salt)
+	if len(password) > 72 {
+		return "", bcrypt.ErrPasswordTooLong
+	}
+	salt := passwordSalt(user, seed)
+	// The trailing NULL is used in the key expansion (C implementations compatibility)
+	key := append([]byte(password), 0)
+	c, err := blowfish.NewSaltedCipher(key, salt)
+	if err != nil {
+		return "", err
+	}
+	for i := 0; i < 1<<bcrypt.DefaultCost; i++ {
+		blowfish.ExpandKey(key, c)
+		blowfish.ExpandKey(salt, c)
+	}
+
+	cipherData := make([]byte, len(bcryptMagic))
+	copy(cipherData, bcryptMagic)
+	for i := 0; i < len(cipherData); i += blowfish.BlockSize {
+		for j := 0; j < 64; j++ {
+			c.Encrypt(cipherData[i:i+blowfish.BlockSize], cipherData[i:i+blowfish.BlockSize])
+		}
+	}
+
+	return fmt.Sprintf("$2a$%02d$%s%s", bcrypt.DefaultCost, bcryptEncoding.EncodeToString(salt),
+		bcryptEncoding.EncodeToString(cipherData[:bcryptHashSize])), nil
+}
+
+// passwordSalt returns the bcrypt salt of the user, derived from the seed (HMAC keyed by the seed)
+func passwordSalt(user, seed string) []byte {
+	mac := hmac.New(sha256.New, []byte(seed))
+	mac.Write([]byte("password-salt:" + user))
+	return mac.Sum(nil)[:bcryptSaltSize]
+}
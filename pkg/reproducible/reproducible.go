@@ -0,0 +1,51 @@
+package reproducible
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// SourceDateEpochEnv is the standard env var for reproducible builds
+	// (see https://reproducible-builds.org/specs/source-date-epoch/)
+	SourceDateEpochEnv = "SOURCE_DATE_EPOCH"
+)
+
+// GetSourceDateEpoch returns the time specified by the SOURCE_DATE_EPOCH env var
+// (or nil when not set).
+func GetSourceDateEpoch() (*time.Time, error) {
+	value := strings.TrimSpace(os.Getenv(SourceDateEpochEnv))
+	if value == "" {
+		return nil, nil
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 || seconds > int64(^uint32(0)) {
+		return nil, errors.Errorf("invalid %s value: %s (expected seconds since the Unix epoch)", SourceDateEpochEnv, value)
+	}
+	t := time.Unix(seconds, 0).UTC()
+	return &t, nil
+}
+
+// seededBytes returns n bytes deterministically derived from the seed and purpose
+func seededBytes(seed, purpose string, n int) []byte {
+	var b []byte
+	for i := 0; len(b) < n; i++ {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%d", seed, purpose, i)))
+		b = append(b, sum[:]...)
+	}
+	return b[:n]
+}
+
+// UUID returns a (version 4 formatted) UUID deterministically derived from the seed and purpose
+func UUID(seed, purpose string) string {
+	b := seededBytes(seed, purpose, 16)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,110 @@
+package reproducible
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/appliance/pkg/sgdisk"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var _ = Describe("Test Reproducible", func() {
+	var (
+		epoch = time.Date(2024, 5, 1, 12, 30, 15, 0, time.UTC)
+	)
+
+	Context("GetSourceDateEpoch", func() {
+		It("not set", func() {
+			GinkgoT().Setenv(SourceDateEpochEnv, "")
+			t, err := GetSourceDateEpoch()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(t).To(BeNil())
+		})
+
+		It("valid value", func() {
+			GinkgoT().Setenv(SourceDateEpochEnv, fmt.Sprintf("%d", epoch.Unix()))
+			t, err := GetSourceDateEpoch()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*t).To(Equal(epoch))
+		})
+
+		It("invalid value", func() {
+			GinkgoT().Setenv(SourceDateEpochEnv, "yesterday")
+			_, err := GetSourceDateEpoch()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	It("UUID - derived from seed and purpose", func() {
+		uuid := UUID("seed", "data")
+		Expect(uuid).To(MatchRegexp(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`))
+		Expect(UUID("seed", "data")).To(Equal(uuid))
+		Expect(UUID("seed", "other")).ToNot(Equal(uuid))
+		Expect(UUID("other", "data")).ToNot(Equal(uuid))
+	})
+
+	It("MBRID - derived from seed", func() {
+		Expect(MBRID("seed")).To(Equal(MBRID("seed")))
+		Expect(MBRID("other")).ToNot(Equal(MBRID("seed")))
+	})
+
+	Context("HashPassword", func() {
+		It("seeded - same hash for the same seed and user", func() {
+			hash, err := HashPassword("secret", "core", "seed")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hash).To(HavePrefix("$2a$10$"))
+			Expect(bcrypt.CompareHashAndPassword([]byte(hash), []byte("secret"))).To(Succeed())
+			Expect(bcrypt.CompareHashAndPassword([]byte(hash), []byte("other"))).ToNot(Succeed())
+
+			again, err := HashPassword("secret", "core", "seed")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(again).To(Equal(hash))
+
+			otherSeed, err := HashPassword("secret", "core", "other-seed")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(otherSeed).ToNot(Equal(hash))
+
+			otherUser, err := HashPassword("secret", "admin", "seed")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(otherUser).ToNot(Equal(hash))
+		})
+
+		It("not seeded - random salt", func() {
+			hash, err := HashPassword("secret", "core", "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bcrypt.CompareHashAndPassword([]byte(hash), []byte("secret"))).To(Succeed())
+
+			again, err := HashPassword("secret", "core", "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(again).ToNot(Equal(hash))
+		})
+	})
+
+	Context("partitionTableGUIDs", func() {
+		It("all GUIDs derived from seed", func() {
+			table := &sgdisk.PartitionTable{DiskGUID: "3B8F8425-20E0-4F3B-907F-1A25A76F98E8", PartitionNumbers: []int{1, 2}}
+			diskGUID, partitionGUIDs := partitionTableGUIDs(table, "seed")
+			Expect(diskGUID).To(Equal(UUID("seed", "gpt-disk")))
+			Expect(partitionGUIDs).To(Equal(map[int]string{
+				1: UUID("seed", "gpt-partition-1"),
+				2: UUID("seed", "gpt-partition-2"),
+			}))
+		})
+
+		It("CoreOS uninitialized disk GUID is kept", func() {
+			table := &sgdisk.PartitionTable{DiskGUID: "00000000-0000-4000-A000-000000000001", PartitionNumbers: []int{4, 5, 6}}
+			diskGUID, partitionGUIDs := partitionTableGUIDs(table, "seed")
+			Expect(diskGUID).To(BeEmpty())
+			Expect(partitionGUIDs).To(HaveLen(3))
+			Expect(partitionGUIDs[6]).To(Equal(UUID("seed", "gpt-partition-6")))
+		})
+	})
+})
+
+func TestReproducible(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "reproducible_test")
+}
@@ -0,0 +1,92 @@
+package sgdisk
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openshift/appliance/pkg/executer"
+	"github.com/pkg/errors"
+)
+
+const (
+	printCmd             = "sgdisk -p %s"
+	setDiskGUIDFlag      = " -U %s"
+	setPartitionGUIDFlag = " -u %d:%s"
+)
+
+var (
+	diskGUIDRegex        = regexp.MustCompile(`(?m)^Disk identifier \(GUID\): (\S+)$`)
+	partitionNumberRegex = regexp.MustCompile(`(?m)^\s+(\d+)\s+\d+\s+\d+\s`)
+)
+
+// PartitionTable is the GPT partition table of a disk image
+type PartitionTable struct {
+	DiskGUID         string
+	PartitionNumbers []int
+}
+
+// Sgdisk edits the GPT partition table of disk images
+type Sgdisk interface {
+	GetPartitionTable(imagePath string) (*PartitionTable, error)
+	SetGUIDs(imagePath, diskGUID string, partitionGUIDs map[int]string) error
+}
+
+type sgdisk struct {
+	executer executer.Executer
+}
+
+func NewSgdisk(exec executer.Executer) Sgdisk {
+	if exec == nil {
+		exec = executer.NewExecuter()
+	}
+
+	return &sgdisk{
+		executer: exec,
+	}
+}
+
+// GetPartitionTable returns the disk GUID and the numbers of the (used) partitions
+func (s *sgdisk) GetPartitionTable(imagePath string) (*PartitionTable, error) {
+	out, err := s.executer.Execute(fmt.Sprintf(printCmd, imagePath))
+	if err != nil {
+		return nil, err
+	}
+	match := diskGUIDRegex.FindStringSubmatch(out)
+	if match == nil {
+		return nil, errors.Errorf("failed to find the GPT disk GUID of %s", imagePath)
+	}
+	table := &PartitionTable{DiskGUID: match[1]}
+	for _, match := range partitionNumberRegex.FindAllStringSubmatch(out, -1) {
+		number, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, err
+		}
+		table.PartitionNumbers = append(table.PartitionNumbers, number)
+	}
+	return table, nil
+}
+
+// SetGUIDs sets the disk GUID (unless empty) and the unique GUIDs of the
+// specified partitions (by partition number)
+func (s *sgdisk) SetGUIDs(imagePath, diskGUID string, partitionGUIDs map[int]string) error {
+	var cmd strings.Builder
+	cmd.WriteString("sgdisk")
+	if diskGUID != "" {
+		cmd.WriteString(fmt.Sprintf(setDiskGUIDFlag, diskGUID))
+	}
+	numbers := make([]int, 0, len(partitionGUIDs))
+	for number := range partitionGUIDs {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	for _, number := range numbers {
+		cmd.WriteString(fmt.Sprintf(setPartitionGUIDFlag, number, partitionGUIDs[number]))
+	}
+	cmd.WriteString(" " + imagePath)
+
+	_, err := s.executer.Execute(cmd.String())
+	return err
+}
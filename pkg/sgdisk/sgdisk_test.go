@@ -0,0 +1,83 @@
+package sgdisk
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/appliance/pkg/executer"
+)
+
+var _ = Describe("Test Sgdisk", func() {
+	var (
+		ctrl          *gomock.Controller
+		mockExecuter  *executer.MockExecuter
+		testSgdisk    Sgdisk
+		fakeImagePath = "/path/to/appliance.raw"
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockExecuter = executer.NewMockExecuter(ctrl)
+		testSgdisk = NewSgdisk(mockExecuter)
+	})
+
+	It("sgdisk GetPartitionTable - success", func() {
+		out := `Disk /path/to/appliance.raw: 41943040 sectors, 20.0 GiB
+Sector size (logical): 512 bytes
+Disk identifier (GUID): 00000000-0000-4000-A000-000000000001
+Partition table holds up to 128 entries
+Main partition table begins at sector 2 and ends at sector 33
+First usable sector is 34, last usable sector is 41943006
+Partitions will be aligned on 2048-sector boundaries
+Total free space is 4061 sectors (2.0 MiB)
+
+Number  Start (sector)    End (sector)  Size       Code  Name
+   1            2048            4095   1024.0 KiB  EF02  BIOS-BOOT
+   2            4096          264191   127.0 MiB   EF00  EFI-SYSTEM
+   3          264192         1050623   384.0 MiB   8300  boot
+   5        17827840        20000000   1.0 GiB     8300  agentboot`
+		mockExecuter.EXPECT().Execute(fmt.Sprintf(printCmd, fakeImagePath)).Return(out, nil).Times(1)
+
+		table, err := testSgdisk.GetPartitionTable(fakeImagePath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(table.DiskGUID).To(Equal("00000000-0000-4000-A000-000000000001"))
+		Expect(table.PartitionNumbers).To(Equal([]int{1, 2, 3, 5}))
+	})
+
+	It("sgdisk GetPartitionTable - no GPT", func() {
+		mockExecuter.EXPECT().Execute(gomock.Any()).Return("Creating new GPT entries in memory.", nil).Times(1)
+
+		_, err := testSgdisk.GetPartitionTable(fakeImagePath)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("sgdisk SetGUIDs - success", func() {
+		mockExecuter.EXPECT().Execute("sgdisk -U disk-guid -u 1:first-guid -u 2:second-guid /path/to/appliance.raw").Return("", nil).Times(1)
+
+		err := testSgdisk.SetGUIDs(fakeImagePath, "disk-guid", map[int]string{2: "second-guid", 1: "first-guid"})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("sgdisk SetGUIDs - keep disk GUID", func() {
+		mockExecuter.EXPECT().Execute("sgdisk -u 4:root-guid /path/to/appliance.raw").Return("", nil).Times(1)
+
+		err := testSgdisk.SetGUIDs(fakeImagePath, "", map[int]string{4: "root-guid"})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("sgdisk SetGUIDs - failure", func() {
+		mockExecuter.EXPECT().Execute(gomock.Any()).Return("", errors.New("some error")).Times(1)
+
+		err := testSgdisk.SetGUIDs(fakeImagePath, "", map[int]string{1: "guid"})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+func TestSgdisk(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "sgdisk_test")
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/openshift/appliance/pkg/executer"
 )
@@ -27,10 +28,15 @@ const (
 	// dir: format: Stores the image as a directory structure instead of a tar archive
 	//        This format preserves all image metadata and supports podman pull dir: for loading
 	templateCopyToFile = "skopeo copy --all --preserve-digests docker://%s dir:%s"
+
+	// templateGetDigest returns the digest of the top-level image manifest (i.e. the manifest list of multi-arch images)
+	templateGetDigest = "skopeo inspect --no-tags --format {{.Digest}} docker://%s"
 )
 
 type Skopeo interface {
 	CopyToFile(imageUrl, imageName, filePath string) error
+	GetDigest(imageUrl string) (string, error)
+	PinImage(imageUrl string) (string, error)
 }
 
 type skopeo struct {
@@ -55,3 +61,37 @@ func (s *skopeo) CopyToFile(imageUrl, imageName, filePath string) error {
 	_, err := s.executer.Execute(fmt.Sprintf(templateCopyToFile, imageUrl, filePath))
 	return err
 }
+
+// GetDigest returns the image digest (sha256:<hash>) of the specified image
+func (s *skopeo) GetDigest(imageUrl string) (string, error) {
+	stdout, err := s.executer.Execute(fmt.Sprintf(templateGetDigest, imageUrl))
+	if err != nil {
+		return "", err
+	}
+	digest := strings.TrimSpace(stdout)
+	if !strings.HasPrefix(digest, "sha256:") {
+		return "", fmt.Errorf("failed to get the digest of %s: %s", imageUrl, digest)
+	}
+	return digest, nil
+}
+
+// PinImage returns the image reference pinned by digest
+// (image references that already contain a digest are returned as-is)
+func (s *skopeo) PinImage(imageUrl string) (string, error) {
+	if strings.Contains(imageUrl, "@") {
+		return imageUrl, nil
+	}
+	digest, err := s.GetDigest(imageUrl)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s@%s", imageName(imageUrl), digest), nil
+}
+
+// imageName strips the tag from an image reference
+func imageName(imageUrl string) string {
+	if idx := strings.LastIndex(imageUrl, ":"); idx > strings.LastIndex(imageUrl, "/") {
+		return imageUrl[:idx]
+	}
+	return imageUrl
+}
@@ -42,6 +42,36 @@ var _ = Describe("Test Skopeo", func() {
 		err := testSkopeo.CopyToFile(consts.RegistryImage, consts.RegistryImage, fakePath)
 		Expect(err).To(HaveOccurred())
 	})
+
+	It("skopeo PinImage - tagged image", func() {
+		cmd := fmt.Sprintf(templateGetDigest, "quay.io/libpod/registry:2.8")
+		mockExecuter.EXPECT().Execute(cmd).Return("sha256:abc123", nil).Times(1)
+
+		image, err := testSkopeo.PinImage("quay.io/libpod/registry:2.8")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(image).To(Equal("quay.io/libpod/registry@sha256:abc123"))
+	})
+
+	It("skopeo PinImage - registry with port", func() {
+		mockExecuter.EXPECT().Execute(gomock.Any()).Return("sha256:abc123", nil).Times(1)
+
+		image, err := testSkopeo.PinImage("registry.example.com:5000/registry")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(image).To(Equal("registry.example.com:5000/registry@sha256:abc123"))
+	})
+
+	It("skopeo PinImage - already pinned", func() {
+		image, err := testSkopeo.PinImage("quay.io/libpod/registry@sha256:abc123")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(image).To(Equal("quay.io/libpod/registry@sha256:abc123"))
+	})
+
+	It("skopeo GetDigest - failure", func() {
+		mockExecuter.EXPECT().Execute(gomock.Any()).Return("", errors.New("some error")).Times(1)
+
+		_, err := testSkopeo.GetDigest("quay.io/libpod/registry:2.8")
+		Expect(err).To(HaveOccurred())
+	})
 })
 
 func TestSkopeo(t *testing.T) {
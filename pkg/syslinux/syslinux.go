@@ -7,11 +7,13 @@ import (
 )
 
 const (
-	isoHybridCmd = "isohybrid -u %s"
+	isoHybridCmd       = "isohybrid -u %s"
+	isoHybridWithIDCmd = "isohybrid -u --id %d %s"
 )
 
 type IsoHybrid interface {
 	Convert(imagePath string) error
+	ConvertWithID(imagePath string, id uint32) error
 }
 
 type isohybrid struct {
@@ -32,3 +34,9 @@ func (s *isohybrid) Convert(imagePath string) error {
 	_, err := s.executer.Execute(fmt.Sprintf(isoHybridCmd, imagePath))
 	return err
 }
+
+// ConvertWithID is the same as Convert, but with the specified MBR ID (instead of a random one)
+func (s *isohybrid) ConvertWithID(imagePath string, id uint32) error {
+	_, err := s.executer.Execute(fmt.Sprintf(isoHybridWithIDCmd, id, imagePath))
+	return err
+}
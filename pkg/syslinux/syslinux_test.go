@@ -36,6 +36,14 @@ var _ = Describe("Test IsoHybrid", func() {
 		Expect(err).ToNot(HaveOccurred())
 	})
 
+	It("isohybrid ConvertWithID - success", func() {
+		cmd := fmt.Sprintf(isoHybridWithIDCmd, 1234, fakeImagePath)
+		mockExecuter.EXPECT().Execute(cmd).Return("", nil).Times(1)
+
+		err := testIsoHybrid.ConvertWithID(fakeImagePath, 1234)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
 	It("isohybrid Convert - failure", func() {
 		mockExecuter.EXPECT().Execute(gomock.Any()).Return("", errors.New("some error")).Times(1)
 
@@ -4,43 +4,60 @@ import (
 	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/go-openapi/swag"
 	"github.com/openshift/appliance/pkg/asset/config"
+	"github.com/openshift/appliance/pkg/asset/manifests"
 	"github.com/openshift/appliance/pkg/asset/registry"
 	"github.com/openshift/appliance/pkg/consts"
 	"github.com/openshift/appliance/pkg/types"
 	"github.com/sirupsen/logrus"
 )
 
-func GetUserCfgTemplateData(grubMenuEntryName string, enableFips bool, setDefault bool) interface{} {
+// GetUserCfgTemplateData returns the data of the GRUB user.cfg
+// (defaultEntry is set only when specified, a passwordHash restricts the menu entry to the GRUB superuser,
+// consoleArgs replace the default console arguments and kernelArgs are appended)
+func GetUserCfgTemplateData(grubMenuEntryName, defaultEntry string, grubTimeout int, passwordHash string,
+	enableFips bool, consoleArgs, kernelArgs []string) interface{} {
 	var fipsArg string
 	if enableFips {
 		fipsArg = "fips=1"
 	}
+	consoleArg := consts.GrubConsoleArgs
+	if len(consoleArgs) > 0 {
+		consoleArg = strings.Join(consoleArgs, " ")
+	}
 
 	return struct {
-		GrubTimeout                              int
-		GrubMenuEntryName, RecoveryPartitionName string
-		FipsArg                                  string
-		SetDefault                               bool
+		GrubTimeout                                            int
+		GrubMenuEntryName, DefaultEntry, RecoveryPartitionName string
+		GrubSuperuser, PasswordHash                            string
+		FipsArg, ConsoleArgs, KernelArgs                       string
 	}{
-		GrubTimeout:           consts.GrubTimeout,
+		GrubTimeout:           grubTimeout,
 		RecoveryPartitionName: consts.RecoveryPartitionName,
 		GrubMenuEntryName:     grubMenuEntryName,
+		DefaultEntry:          defaultEntry,
+		GrubSuperuser:         consts.GrubSuperuser,
+		PasswordHash:          passwordHash,
 		FipsArg:               fipsArg,
-		SetDefault:            setDefault,
+		ConsoleArgs:           consoleArg,
+		KernelArgs:            strings.Join(kernelArgs, " "),
 	}
 }
 
+// GetGuestfishScriptTemplateData returns the data of the guestfish script
+// (handleGrub is unset when the boot partition is updated without mounting it, i.e. for reproducible builds)
 func GetGuestfishScriptTemplateData(isCompact bool, diskSize, baseIsoSize, recoveryIsoSize, dataIsoSize int64,
-	baseImageFile, applianceImageFile, recoveryIsoFile, dataIsoFile, userCfgFile, grubCfgFile, tempDir string) interface{} {
+	baseImageFile, applianceImageFile, recoveryIsoFile, dataIsoFile, userCfgFile, grubCfgFile, tempDir string, handleGrub bool) interface{} {
 
 	partitionsInfo := NewPartitions().GetAgentPartitions(diskSize, baseIsoSize, recoveryIsoSize, dataIsoSize, isCompact)
 
 	return struct {
 		ApplianceFile, RecoveryIsoFile, DataIsoFile, CoreOSImage, RecoveryPartitionName, DataPartitionName, ReservedPartitionGUID string
 		UserCfgFile, GrubCfgFile, GrubTempDir                                                                                     string
+		HandleGrub                                                                                                                bool
 		DiskSize, RecoveryStartSector, RecoveryEndSector, DataStartSector, DataEndSector, RootStartSector, RootEndSector          int64
 	}{
 		ApplianceFile:         applianceImageFile,
@@ -60,20 +77,23 @@ func GetGuestfishScriptTemplateData(isCompact bool, diskSize, baseIsoSize, recov
 		UserCfgFile:           userCfgFile,
 		GrubCfgFile:           grubCfgFile,
 		GrubTempDir:           filepath.Join(tempDir, "scripts/grub"),
+		HandleGrub:            handleGrub,
 	}
 }
 
-func GetImageSetTemplateData(applianceConfig *config.ApplianceConfig, blockedImages, additionalImages, operators string) interface{} {
+func GetImageSetTemplateData(applianceConfig *config.ApplianceConfig, blockedImages, additionalImages, operators, helm string) interface{} {
 	return struct {
 		ReleaseImage     string
 		BlockedImages    string
 		AdditionalImages string
 		Operators        string
+		Helm             string
 	}{
 		ReleaseImage:     swag.StringValue(applianceConfig.Config.OcpRelease.URL),
 		BlockedImages:    blockedImages,
 		AdditionalImages: additionalImages,
 		Operators:        operators,
+		Helm:             helm,
 	}
 }
 
@@ -87,7 +107,30 @@ func GetPinnedImageSetTemplateData(images, role string) interface{} {
 	}
 }
 
-func GetBootstrapIgnitionTemplateData(isLiveISO, enableInteractiveFlow bool, ocpReleaseImage types.ReleaseImage, installIgnitionConfig, coreosImagePath, rendezvousHostEnvPlaceholder string) interface{} {
+func GetAddUsersTemplateData(users []types.User) interface{} {
+	type user struct {
+		Name, Groups, PasswordHash, SshKeys string
+	}
+	data := struct {
+		Users []user
+	}{}
+	for _, u := range users {
+		data.Users = append(data.Users, user{
+			Name:         shellQuote(u.Name),
+			Groups:       shellQuote(strings.Join(u.Groups, ",")),
+			PasswordHash: shellQuote(swag.StringValue(u.PasswordHash)),
+			SshKeys:      shellQuote(strings.Join(u.SshKeys, "\n")),
+		})
+	}
+	return data
+}
+
+// shellQuote returns s as a single-quoted shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func GetBootstrapIgnitionTemplateData(isLiveISO, enableInteractiveFlow bool, ocpReleaseImage types.ReleaseImage, installIgnitionConfig, coreosImagePath, rendezvousHostEnvPlaceholder, dataPartitionFormat string) interface{} {
 	releaseImageArr := []map[string]any{
 		{
 			"openshift_version": ocpReleaseImage.Version,
@@ -117,6 +160,7 @@ func GetBootstrapIgnitionTemplateData(isLiveISO, enableInteractiveFlow bool, ocp
 
 		ReleaseImages, ReleaseImage, OsImages           string
 		RegistryDomain, RegistryFilePath, RegistryImage string
+		DataPartitionFormat                             string
 
 		Partition0, Partition1, Partition2, Partition3 Partition
 	}{
@@ -125,6 +169,7 @@ func GetBootstrapIgnitionTemplateData(isLiveISO, enableInteractiveFlow bool, ocp
 		EnableInteractiveFlow:        enableInteractiveFlow,
 		InstallIgnitionConfig:        installIgnitionConfig,
 		RendezvousHostEnvPlaceholder: rendezvousHostEnvPlaceholder,
+		DataPartitionFormat:          dataPartitionFormat,
 
 		// Images
 		ReleaseImages: string(releaseImages),
@@ -161,7 +206,7 @@ func GetBootstrapIgnitionTemplateData(isLiveISO, enableInteractiveFlow bool, ocp
 	return data
 }
 
-func GetInstallIgnitionTemplateData(isLiveISO, enableInteractiveFlow bool, corePassHash string) interface{} {
+func GetInstallIgnitionTemplateData(isLiveISO, enableInteractiveFlow bool, corePassHash, dataPartitionFormat string) interface{} {
 	// If interactive flow is enabled, use localhost as registry domain, otherwise use the default registry domain
 	var registryDomain string
 	if enableInteractiveFlow {
@@ -176,9 +221,11 @@ func GetInstallIgnitionTemplateData(isLiveISO, enableInteractiveFlow bool, coreP
 
 		RegistryDataPath, RegistryDomain, RegistryFilePath, RegistryImage string
 		CorePassHash, GrubCfgFilePath, UserCfgFilePath                    string
+		DataPartitionFormat                                               string
 	}{
-		IsBootstrapStep: false,
-		IsLiveISO:       isLiveISO,
+		IsBootstrapStep:     false,
+		IsLiveISO:           isLiveISO,
+		DataPartitionFormat: dataPartitionFormat,
 
 		// Registry
 		RegistryDomain:   registryDomain,
@@ -190,6 +237,73 @@ func GetInstallIgnitionTemplateData(isLiveISO, enableInteractiveFlow bool, coreP
 	}
 }
 
+// OperatorCRStep is a step of applying a post-installation CR (by the apply-operator-crs.sh script)
+type OperatorCRStep struct {
+	manifests.CRStep
+	// Files of the CR manifests (a file per YAML document)
+	Files []string
+}
+
+func GetApplyOperatorCRsTemplateData(steps []OperatorCRStep) interface{} {
+	type step struct {
+		Wave, Name, Files                   string
+		CSV, CSVNamespace, CRD              string
+		ReadinessJSONPath, ReadinessTimeout string
+	}
+	data := struct {
+		StatusFilePath string
+		Steps          []step
+	}{
+		StatusFilePath: consts.OperatorCRsStatusPath,
+	}
+	for _, s := range steps {
+		var files []string
+		for _, f := range s.Files {
+			files = append(files, shellQuote(f))
+		}
+		data.Steps = append(data.Steps, step{
+			Wave:              shellQuote(s.Wave),
+			Name:              shellQuote(s.File),
+			Files:             strings.Join(files, " "),
+			CSV:               shellQuote(s.CSV),
+			CSVNamespace:      shellQuote(s.CSVNamespace),
+			CRD:               shellQuote(s.CRD),
+			ReadinessJSONPath: shellQuote(s.ReadinessJSONPath),
+			ReadinessTimeout:  shellQuote(s.ReadinessTimeout),
+		})
+	}
+	return data
+}
+
+func GetAddNodesIgnitionTemplateData(isBootstrapStep, enableInteractiveFlow bool, installIgnitionConfig, dataPartitionFormat string) interface{} {
+	// If interactive flow is enabled, use localhost as registry domain, otherwise use the default registry domain
+	var registryDomain string
+	if enableInteractiveFlow {
+		registryDomain = "localhost"
+	} else {
+		registryDomain = registry.RegistryDomain
+	}
+
+	return struct {
+		IsBootstrapStep bool
+		IsLiveISO       bool
+
+		RegistryDomain, RegistryFilePath, RegistryImage string
+		InstallIgnitionConfig, DataPartitionFormat      string
+	}{
+		// The registry data is copied from the node ISO (as in the live ISO flow)
+		IsBootstrapStep:       isBootstrapStep,
+		IsLiveISO:             true,
+		InstallIgnitionConfig: installIgnitionConfig,
+		DataPartitionFormat:   dataPartitionFormat,
+
+		// Registry
+		RegistryDomain:   registryDomain,
+		RegistryFilePath: consts.RegistryFilePath,
+		RegistryImage:    consts.RegistryImage,
+	}
+}
+
 func GetDeployIgnitionTemplateData(targetDevice, postScript string, sparseClone, dryRun bool) interface{} {
 	return struct {
 		ApplianceFileName, ApplianceImageName, ApplianceImageTar string
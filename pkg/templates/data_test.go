@@ -0,0 +1,67 @@
+package templates
+
+import (
+	"github.com/go-openapi/swag"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/appliance/pkg/consts"
+	"github.com/openshift/appliance/pkg/types"
+)
+
+var _ = Describe("Test add users template", func() {
+	It("quotes the user fields", func() {
+		content, err := Scripts.ReadFile(consts.AddUsersTemplateFile)
+		Expect(err).ToNot(HaveOccurred())
+
+		data := GetAddUsersTemplateData([]types.User{{
+			Name:         "breakglass",
+			Groups:       []string{"wheel", "adm"},
+			SshKeys:      []string{"ssh-rsa AAAA", "ssh-ed25519 BBBB"},
+			PasswordHash: swag.String("$6$salt$hash"),
+		}, {
+			Name:    "support",
+			SshKeys: []string{"ssh-rsa CCCC"},
+		}})
+		script, err := applyTemplateData("add-users.sh", content, data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(script)).To(ContainSubstring("add_user 'breakglass' 'wheel,adm' '$6$salt$hash' 'ssh-rsa AAAA\nssh-ed25519 BBBB'\n"))
+		Expect(string(script)).To(ContainSubstring("add_user 'support' '' '' 'ssh-rsa CCCC'"))
+	})
+
+	It("escapes single quotes", func() {
+		Expect(shellQuote("it's")).To(Equal(`'it'\''s'`))
+	})
+})
+
+var _ = Describe("Test user.cfg template", func() {
+	render := func(defaultEntry, passwordHash string, consoleArgs, kernelArgs []string) string {
+		content, err := Scripts.ReadFile(consts.UserCfgTemplateFile)
+		Expect(err).ToNot(HaveOccurred())
+		data := GetUserCfgTemplateData(consts.GrubMenuEntryName, defaultEntry, consts.GrubTimeout, passwordHash,
+			true, consoleArgs, kernelArgs)
+		userCfg, err := applyTemplateData("user.cfg", content, data)
+		Expect(err).ToNot(HaveOccurred())
+		return string(userCfg)
+	}
+
+	It("uses the default console", func() {
+		Expect(render("", "", nil, nil)).To(ContainSubstring("random.trust_cpu=on " + consts.GrubConsoleArgs + " ignition.firstboot"))
+	})
+
+	It("appends the console and kernel arguments", func() {
+		userCfg := render("", "", []string{"console=tty0", "console=ttyS1,9600"}, []string{"nosmt", "quiet"})
+		Expect(userCfg).To(ContainSubstring("random.trust_cpu=on console=tty0 console=ttyS1,9600 ignition.firstboot"))
+		Expect(userCfg).To(ContainSubstring("fips=1 nosmt quiet\n"))
+		Expect(userCfg).ToNot(ContainSubstring("ttyS0"))
+	})
+
+	It("sets the default entry", func() {
+		Expect(render("", "", nil, nil)).To(HavePrefix("set timeout=10\nmenuentry 'Agent-Based Installer'"))
+		Expect(render("Custom", "", nil, nil)).To(HavePrefix("set default='Custom'\nset timeout=10\nmenuentry"))
+	})
+
+	It("protects the menu entry with a password", func() {
+		Expect(render("", "grub.pbkdf2.sha512.10000.AB.CD", nil, nil)).To(HavePrefix(
+			"set timeout=10\nset superusers=\"root\"\npassword_pbkdf2 root grub.pbkdf2.sha512.10000.AB.CD\nmenuentry"))
+	})
+})
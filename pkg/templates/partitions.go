@@ -9,6 +9,9 @@ import (
 )
 
 const (
+	// SectorSize is the logical sector size of the appliance disk image
+	SectorSize = sectorSize
+
 	sectorSize    = int64(512)
 	sectorSize64K = int64(64 * 1024)
 
@@ -41,12 +44,12 @@ func NewPartitions() Partitions {
 func (p *partitions) GetAgentPartitions(diskSize, baseIsoSize, recoveryIsoSize, dataIsoSize int64, isCompact bool) *AgentPartitions {
 	// Calc data partition start/end sectors
 	dataEndSector := (conversions.GibToBytes(diskSize) - conversions.MibToBytes(1)) / sectorSize
-	dataStartSector := dataEndSector - (dataIsoSize / sectorSize)
+	dataStartSector := dataEndSector - bytesToSectors(dataIsoSize)
 	dataStartSector = roundToNearestSector(dataStartSector, sectorAlignmentFactor)
 
 	// Calc recovery partition start/end sectors
 	recoveryEndSector := dataStartSector - sectorAlignmentFactor
-	recoveryStartSector := recoveryEndSector - (recoveryIsoSize / sectorSize)
+	recoveryStartSector := recoveryEndSector - bytesToSectors(recoveryIsoSize)
 	recoveryStartSector = roundToNearestSector(recoveryStartSector, sectorAlignmentFactor)
 
 	// Calc root partition start/end sectors
@@ -109,6 +112,13 @@ func (p *partitions) getRootPartitionSize(diskSize, baseIsoSize, recoveryIsoSize
 	return conversions.GbToBytes(diskSize) - (baseIsoSize + recoveryIsoSize + dataIsoSize)
 }
 
+// Returns the number of sectors required to hold the specified amount of bytes.
+// Compressed filesystem images (squashfs/EROFS) aren't necessarily a multiple
+// of the sector size, so the result is rounded up.
+func bytesToSectors(size int64) int64 {
+	return (size + sectorSize - 1) / sectorSize
+}
+
 // Returns the nearest (and lowest) sector according to a specified alignment factor
 // E.g. for 'sector: 19' and 'alignmentFactor: 8' -> returns 16
 func roundToNearestSector(sector int64, alignmentFactor int64) int64 {
@@ -44,4 +44,11 @@ var _ = Describe("Test Partitions", func() {
 		emptyBytes := (diskSizeInSectors - testPartitions.DataPartition.EndSector) * sectorSize
 		Expect(emptyBytes).To(Equal(conversions.MibToBytes(1)))
 	})
+
+	It("data partition is large enough for an unaligned image size", func() {
+		unalignedDataSize := conversions.GibToBytes(30) + 100
+		partitions := NewPartitions().GetAgentPartitions(diskSize, baseIsoSize, recoveryIsoSize, unalignedDataSize, false)
+		partitionSize := (partitions.DataPartition.EndSector - partitions.DataPartition.StartSector) * sectorSize
+		Expect(partitionSize >= unalignedDataSize).To(BeTrue())
+	})
 })
@@ -6,43 +6,116 @@ import (
 )
 
 // ApplianceConfigApiVersion is the version supported by this package.
-const ApplianceConfigApiVersion = "v1beta1"
+const ApplianceConfigApiVersion = "v1beta2"
 
 type ApplianceConfig struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	OcpRelease                         ReleaseImage   `json:"ocpRelease"`
-	DiskSizeGB                         *int           `json:"diskSizeGb"`
-	PullSecret                         string         `json:"pullSecret"`
-	SshKey                             *string        `json:"sshKey"`
-	UserCorePass                       *string        `json:"userCorePass"`
-	ImageRegistry                      *ImageRegistry `json:"imageRegistry"`
-	MirrorPath                         *string        `json:"mirrorPath,omitempty"`
-	EnableDefaultSources               *bool          `json:"enableDefaultSources"`
-	EnableFips                         *bool          `json:"enableFips"`
-	StopLocalRegistry                  *bool          `json:"stopLocalRegistry"`
-	SkipLocalRegistry                  *bool          `json:"skipLocalRegistry"`
-	CreatePinnedImageSets              *bool          `json:"createPinnedImageSets"`
-	EnableInteractiveFlow              *bool          `json:"enableInteractiveFlow"`
-	UseDefaultSourceNames              *bool          `json:"useDefaultSourceNames"`
-	DisableSigstoreRegistries          *[]string      `json:"disableSigstoreRegistries,omitempty"`
-	AdditionalImages                   *[]Image       `json:"additionalImages,omitempty"`
-	BlockedImages                      *[]Image       `json:"blockedImages,omitempty"`
-	Operators                          *[]Operator    `json:"operators,omitempty"`
+	OcpRelease ReleaseImage `json:"ocpRelease"`
+	BuildSeed  *string      `json:"buildSeed,omitempty"`
+
+	// Secrets can be specified inline, or referenced by a file path (or as a hash for userCorePass).
+	// All these fields support ${ENV_VAR} interpolation.
+	PullSecret       string  `json:"pullSecret,omitempty"`
+	PullSecretFile   *string `json:"pullSecretFile,omitempty"`
+	SshKey           *string `json:"sshKey,omitempty"`
+	SshKeyFile       *string `json:"sshKeyFile,omitempty"`
+	UserCorePass     *string `json:"userCorePass,omitempty"`
+	UserCorePassHash *string `json:"userCorePassHash,omitempty"`
+
+	// SshKeys are additional public SSH keys of user 'core'
+	SshKeys *[]string `json:"sshKeys,omitempty"`
+	// Users are additional users (other than 'core')
+	Users *[]User `json:"users,omitempty"`
+
+	Registry RegistryConfig `json:"registry,omitempty"`
+	Mirror   MirrorConfig   `json:"mirror,omitempty"`
+	Cluster  ClusterConfig  `json:"cluster,omitempty"`
+	Disk     DiskConfig     `json:"disk,omitempty"`
+	Boot     BootConfig     `json:"boot,omitempty"`
+}
+
+// RegistryConfig configures the local image registry
+// (used when building the appliance and post cluster installation).
+type RegistryConfig struct {
+	URI             *string `json:"uri,omitempty"`
+	Port            *int    `json:"port,omitempty"`
+	UseBinary       *bool   `json:"useBinary,omitempty"`
+	StopPostInstall *bool   `json:"stopPostInstall,omitempty"`
+	SkipPostInstall *bool   `json:"skipPostInstall,omitempty"`
+}
+
+// MirrorConfig configures the images mirrored into the appliance.
+type MirrorConfig struct {
+	Path                      *string     `json:"path,omitempty"`
+	DisableSigstoreRegistries *[]string   `json:"disableSigstoreRegistries,omitempty"`
+	AdditionalImages          *[]Image    `json:"additionalImages,omitempty"`
+	BlockedImages             *[]Image    `json:"blockedImages,omitempty"`
+	Operators                 *[]Operator `json:"operators,omitempty"`
+	Helm                      *Helm       `json:"helm,omitempty"`
+}
+
+// ClusterConfig configures the installed cluster.
+type ClusterConfig struct {
+	EnableFips            *bool `json:"enableFips,omitempty"`
+	EnableInteractiveFlow *bool `json:"enableInteractiveFlow,omitempty"`
+	EnableDefaultSources  *bool `json:"enableDefaultSources,omitempty"`
+	UseDefaultSourceNames *bool `json:"useDefaultSourceNames,omitempty"`
+	CreatePinnedImageSets *bool `json:"createPinnedImageSets,omitempty"`
+
+	// CatalogSourceNames configures the CatalogSources generated by oc-mirror (keyed by catalog image)
+	CatalogSourceNames map[string]CatalogSourceConfig `json:"catalogSourceNames,omitempty"`
+}
+
+// CatalogSourceConfig configures the CatalogSource of a mirrored operators catalog.
+type CatalogSourceConfig struct {
+	Name         *string `json:"name,omitempty"`
+	DisplayName  *string `json:"displayName,omitempty"`
+	Priority     *int    `json:"priority,omitempty"`
+	PollInterval *string `json:"pollInterval,omitempty"`
+}
+
+// DiskConfig configures the appliance disk image.
+type DiskConfig struct {
+	SizeGB              *int    `json:"sizeGB,omitempty"`
+	DataPartitionFormat *string `json:"dataPartitionFormat,omitempty"`
+}
+
+// BootConfig configures the kernel command line of the appliance boot paths and the cluster nodes.
+type BootConfig struct {
+	KernelArguments *[]string `json:"kernelArguments,omitempty"`
+	Console         *string   `json:"console,omitempty"`
+
+	Grub GrubConfig `json:"grub,omitempty"`
+}
+
+// GrubConfig configures the GRUB menu of the appliance disk image and the recovery entry of the cluster nodes.
+type GrubConfig struct {
+	Timeout               *int    `json:"timeout,omitempty"`
+	DefaultEntry          *string `json:"defaultEntry,omitempty"`
+	MenuEntryName         *string `json:"menuEntryName,omitempty"`
+	RecoveryMenuEntryName *string `json:"recoveryMenuEntryName,omitempty"`
+	RecoveryPasswordHash  *string `json:"recoveryPasswordHash,omitempty"`
+}
+
+// User is an additional user created in the appliance and in the cluster nodes.
+type User struct {
+	Name         string   `json:"name"`
+	Groups       []string `json:"groups,omitempty"`
+	SshKeys      []string `json:"sshKeys,omitempty"`
+	PasswordHash *string  `json:"passwordHash,omitempty"`
 }
 
 type ReleaseImage struct {
 	Version         string                `json:"version"`
-	Channel         *graph.ReleaseChannel `json:"channel"`
-	CpuArchitecture *string               `json:"cpuArchitecture"`
-	URL             *string               `json:"url"`
-}
+	Channel         *graph.ReleaseChannel `json:"channel,omitempty"`
+	CpuArchitecture *string               `json:"cpuArchitecture,omitempty"`
+	URL             *string               `json:"url,omitempty"`
+	GraphURL        *string               `json:"graphURL,omitempty"`
+	GraphFile       *string               `json:"graphFile,omitempty"`
 
-type ImageRegistry struct {
-	URI       *string `json:"uri"`
-	Port      *int    `json:"port"`
-	UseBinary *bool   `json:"useBinary"`
+	AllowVersionFallback *bool `json:"allowVersionFallback,omitempty"`
 }
 
 // Structs copied from oc-mirror: https://github.com/openshift/oc-mirror/blob/main/v2/pkg/api/v1alpha2/types_config.go
@@ -86,6 +159,23 @@ type IncludePackage struct {
 
 	// All channels containing these bundles are parsed for an upgrade graph.
 	IncludeBundle `json:",inline"`
+
+	// Installation of the package in the cluster (not passed to oc-mirror).
+	InstallConfig `json:",inline"`
+}
+
+// InstallConfig defines the installation of an operator package in the cluster
+// (i.e. the generated Namespace, OperatorGroup and Subscription manifests).
+type InstallConfig struct {
+	// Install the package in the cluster.
+	Install *bool `json:"install,omitempty"`
+	// Namespace to install the package into (default: the package name).
+	Namespace *string `json:"namespace,omitempty"`
+	// TargetNamespaces of the generated OperatorGroup (default: the package namespace, i.e. OwnNamespace install mode).
+	// An empty list selects the AllNamespaces install mode.
+	TargetNamespaces *[]string `json:"targetNamespaces,omitempty"`
+	// InstallPlanApproval of the Subscription (Automatic or Manual).
+	InstallPlanApproval *string `json:"installPlanApproval,omitempty"`
 }
 
 // IncludeChannel contains a name (required) and versions (optional)
@@ -109,3 +199,36 @@ type IncludeBundle struct {
 	// Set this field only if the named bundle has no semantic version metadata.
 	MinBundle string `json:"minBundle,omitempty" yaml:"minBundle,omitempty"`
 }
+
+// Helm defines the configuration for Helm chart download
+// and image mirroring
+type Helm struct {
+	// Repositories are the Helm repositories containing the charts
+	Repositories []HelmRepository `json:"repositories,omitempty"`
+	// Local is the configuration for locally stored helm charts
+	Local []HelmChart `json:"local,omitempty"`
+}
+
+// HelmRepository defines the configuration for a Helm repository.
+type HelmRepository struct {
+	// URL is the url of the Helm repository
+	URL string `json:"url"`
+	// Name is the name of the Helm repository
+	Name string `json:"name"`
+	// Charts is a list of charts to pull from the repo
+	Charts []HelmChart `json:"charts"`
+}
+
+// HelmChart is a Helm chart to mirror.
+type HelmChart struct {
+	// Name is the chart name
+	Name string `json:"name"`
+	// Version is the chart version
+	Version string `json:"version,omitempty"`
+	// Path defines the path on disk where the chart is stored.
+	// This is applicable for a local chart.
+	Path string `json:"path,omitempty"`
+	// ImagePaths are custom JSON paths for images location
+	// in the helm manifest or templates
+	ImagePaths []string `json:"imagePaths,omitempty"`
+}
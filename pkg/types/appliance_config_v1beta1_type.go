@@ -0,0 +1,142 @@
+package types
+
+import (
+	"strings"
+
+	"github.com/openshift/appliance/pkg/graph"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ApplianceConfigV1Beta1ApiVersion is the previous (flat) version of appliance-config.yaml,
+// still supported by converting it to the current version.
+const ApplianceConfigV1Beta1ApiVersion = "v1beta1"
+
+// ApplianceConfigV1Beta1 is the released v1beta1 schema (keep as is, new fields are added to ApplianceConfig only)
+type ApplianceConfigV1Beta1 struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	OcpRelease                ReleaseImageV1Beta1 `json:"ocpRelease"`
+	DiskSizeGB                *int                `json:"diskSizeGb"`
+	PullSecret                string              `json:"pullSecret"`
+	SshKey                    *string             `json:"sshKey"`
+	UserCorePass              *string             `json:"userCorePass"`
+	ImageRegistry             *ImageRegistry      `json:"imageRegistry"`
+	MirrorPath                *string             `json:"mirrorPath,omitempty"`
+	EnableDefaultSources      *bool               `json:"enableDefaultSources"`
+	EnableFips                *bool               `json:"enableFips"`
+	StopLocalRegistry         *bool               `json:"stopLocalRegistry"`
+	SkipLocalRegistry         *bool               `json:"skipLocalRegistry"`
+	CreatePinnedImageSets     *bool               `json:"createPinnedImageSets"`
+	EnableInteractiveFlow     *bool               `json:"enableInteractiveFlow"`
+	UseDefaultSourceNames     *bool               `json:"useDefaultSourceNames"`
+	DisableSigstoreRegistries *[]string           `json:"disableSigstoreRegistries,omitempty"`
+	AdditionalImages          *[]Image            `json:"additionalImages,omitempty"`
+	BlockedImages             *[]Image            `json:"blockedImages,omitempty"`
+	Operators                 *[]OperatorV1Beta1  `json:"operators,omitempty"`
+}
+
+type ReleaseImageV1Beta1 struct {
+	Version         string                `json:"version"`
+	Channel         *graph.ReleaseChannel `json:"channel"`
+	CpuArchitecture *string               `json:"cpuArchitecture"`
+	URL             *string               `json:"url"`
+}
+
+// OperatorV1Beta1 is an operator catalog to mirror (without the installation config)
+type OperatorV1Beta1 struct {
+	IncludeConfig `json:",inline"`
+	Catalog       string `json:"catalog"`
+}
+
+type ImageRegistry struct {
+	URI       *string `json:"uri"`
+	Port      *int    `json:"port"`
+	UseBinary *bool   `json:"useBinary"`
+}
+
+// Convert returns the config in the current apiVersion
+func (c *ApplianceConfigV1Beta1) Convert() *ApplianceConfig {
+	config := &ApplianceConfig{
+		TypeMeta:   c.TypeMeta,
+		ObjectMeta: c.ObjectMeta,
+		OcpRelease: ReleaseImage{
+			Version:         c.OcpRelease.Version,
+			Channel:         c.OcpRelease.Channel,
+			CpuArchitecture: c.OcpRelease.CpuArchitecture,
+			URL:             c.OcpRelease.URL,
+		},
+		PullSecret:   c.PullSecret,
+		SshKey:       c.SshKey,
+		UserCorePass: c.UserCorePass,
+		Registry: RegistryConfig{
+			StopPostInstall: c.StopLocalRegistry,
+			SkipPostInstall: c.SkipLocalRegistry,
+		},
+		Mirror: MirrorConfig{
+			Path:                      c.MirrorPath,
+			DisableSigstoreRegistries: c.DisableSigstoreRegistries,
+			AdditionalImages:          c.AdditionalImages,
+			BlockedImages:             c.BlockedImages,
+		},
+		Cluster: ClusterConfig{
+			EnableFips:            c.EnableFips,
+			EnableInteractiveFlow: c.EnableInteractiveFlow,
+			EnableDefaultSources:  c.EnableDefaultSources,
+			UseDefaultSourceNames: c.UseDefaultSourceNames,
+			CreatePinnedImageSets: c.CreatePinnedImageSets,
+		},
+		Disk: DiskConfig{
+			SizeGB: c.DiskSizeGB,
+		},
+	}
+	config.APIVersion = ApplianceConfigApiVersion
+	// v1beta1 configs always fell back to the latest supported version
+	allowVersionFallback := true
+	config.OcpRelease.AllowVersionFallback = &allowVersionFallback
+	if c.ImageRegistry != nil {
+		config.Registry.URI = c.ImageRegistry.URI
+		config.Registry.Port = c.ImageRegistry.Port
+		config.Registry.UseBinary = c.ImageRegistry.UseBinary
+	}
+	if c.Operators != nil {
+		operators := make([]Operator, len(*c.Operators))
+		for i, operator := range *c.Operators {
+			operators[i] = Operator{IncludeConfig: operator.IncludeConfig, Catalog: operator.Catalog}
+		}
+		config.Mirror.Operators = &operators
+	}
+	return config
+}
+
+// v1beta1FieldPaths maps the field paths of the current apiVersion to the v1beta1 ones
+var v1beta1FieldPaths = map[string]string{
+	"disk.sizeGB":                      "diskSizeGb",
+	"registry.uri":                     "imageRegistry.uri",
+	"registry.port":                    "imageRegistry.port",
+	"registry.useBinary":               "imageRegistry.useBinary",
+	"registry.stopPostInstall":         "stopLocalRegistry",
+	"registry.skipPostInstall":         "skipLocalRegistry",
+	"mirror.path":                      "mirrorPath",
+	"mirror.disableSigstoreRegistries": "disableSigstoreRegistries",
+	"mirror.additionalImages":          "additionalImages",
+	"mirror.blockedImages":             "blockedImages",
+	"mirror.operators":                 "operators",
+	"cluster.enableFips":               "enableFips",
+	"cluster.enableInteractiveFlow":    "enableInteractiveFlow",
+	"cluster.enableDefaultSources":     "enableDefaultSources",
+	"cluster.useDefaultSourceNames":    "useDefaultSourceNames",
+	"cluster.createPinnedImageSets":    "createPinnedImageSets",
+}
+
+// V1Beta1FieldPath returns the v1beta1 path of a field path of the current apiVersion
+// (e.g. 'mirror.additionalImages[0].name' is 'additionalImages[0].name'),
+// for reporting errors in terms of a v1beta1 config file.
+func V1Beta1FieldPath(path string) string {
+	for current, v1beta1 := range v1beta1FieldPaths {
+		if rest, found := strings.CutPrefix(path, current); found && (rest == "" || rest[0] == '.' || rest[0] == '[') {
+			return v1beta1 + rest
+		}
+	}
+	return path
+}
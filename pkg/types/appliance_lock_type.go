@@ -0,0 +1,54 @@
+package types
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ApplianceLockApiVersion is the version of the appliance-lock.yaml supported by this package.
+const ApplianceLockApiVersion = "v1beta1"
+
+// ApplianceLock pins the content resolved for an appliance-config.yaml,
+// so later builds produce the same appliance.
+type ApplianceLock struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Release       LockedRelease    `json:"release"`
+	CoreOS        LockedCoreOS     `json:"coreos"`
+	RegistryImage LockedImage      `json:"registryImage"`
+	Operators     []LockedOperator `json:"operators,omitempty"`
+	// Images are all the mirrored images (by their source references), pinned by digest
+	Images []LockedImage `json:"images,omitempty"`
+}
+
+type LockedRelease struct {
+	Version string `json:"version"`
+	// Image is the release image pinned by digest
+	Image string `json:"image"`
+}
+
+type LockedCoreOS struct {
+	DiskImageURL    string `json:"diskImageURL"`
+	DiskImageSha256 string `json:"diskImageSha256"`
+}
+
+type LockedImage struct {
+	// Source is the image reference as resolved from appliance-config.yaml
+	Source string `json:"source"`
+	// Image is the image pinned by digest (empty for images built locally)
+	Image string `json:"image,omitempty"`
+}
+
+type LockedOperator struct {
+	// Source is the catalog as specified in appliance-config.yaml
+	Source string `json:"source"`
+	// Catalog is the catalog image pinned by digest
+	Catalog string         `json:"catalog"`
+	Bundles []LockedBundle `json:"bundles,omitempty"`
+}
+
+type LockedBundle struct {
+	Package string `json:"package"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Image   string `json:"image"`
+}
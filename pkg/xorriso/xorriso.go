@@ -0,0 +1,49 @@
+package xorriso
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/openshift/appliance/pkg/executer"
+)
+
+const (
+	// Rewrites the image (keeping its boot setup) with all the volume and file dates set
+	setDatesCmd = "xorriso -indev %s -outdev %s -boot_image any replay" +
+		" -volume_date all_file_dates =%d -volume_date c =%d -volume_date m =%d -volume_date uuid %s -commit"
+)
+
+// Xorriso edits existing ISO9660 images
+type Xorriso interface {
+	SetDates(imagePath string, t time.Time) error
+}
+
+type xorriso struct {
+	executer executer.Executer
+}
+
+func NewXorriso(exec executer.Executer) Xorriso {
+	if exec == nil {
+		exec = executer.NewExecuter()
+	}
+
+	return &xorriso{
+		executer: exec,
+	}
+}
+
+// SetDates sets all the timestamps of the image (volume descriptors and files) to t
+// (i.e. for images generated by tools that don't support fixed timestamps).
+func (x *xorriso) SetDates(imagePath string, t time.Time) error {
+	tmpImagePath := imagePath + ".tmp"
+	if err := os.RemoveAll(tmpImagePath); err != nil {
+		return err
+	}
+	// The volume UUID (e.g. used by GRUB) is derived from the date as well
+	uuid := t.UTC().Format("20060102150405") + "00"
+	if _, err := x.executer.Execute(fmt.Sprintf(setDatesCmd, imagePath, tmpImagePath, t.Unix(), t.Unix(), t.Unix(), uuid)); err != nil {
+		return err
+	}
+	return os.Rename(tmpImagePath, imagePath)
+}
@@ -0,0 +1,58 @@
+package xorriso
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/appliance/pkg/executer"
+)
+
+var _ = Describe("Test Xorriso", func() {
+	var (
+		ctrl          *gomock.Controller
+		mockExecuter  *executer.MockExecuter
+		testXorriso   Xorriso
+		fakeImagePath string
+		fakeTime      = time.Unix(1700000000, 0)
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockExecuter = executer.NewMockExecuter(ctrl)
+		testXorriso = NewXorriso(mockExecuter)
+		fakeImagePath = filepath.Join(GinkgoT().TempDir(), "testdata.iso")
+		Expect(os.WriteFile(fakeImagePath, []byte("original"), 0600)).To(Succeed())
+	})
+
+	It("xorriso SetDates - success", func() {
+		tmpImagePath := fakeImagePath + ".tmp"
+		cmd := fmt.Sprintf(setDatesCmd, fakeImagePath, tmpImagePath, 1700000000, 1700000000, 1700000000, "2023111422132000")
+		mockExecuter.EXPECT().Execute(cmd).DoAndReturn(func(string) (string, error) {
+			return "", os.WriteFile(tmpImagePath, []byte("rewritten"), 0600)
+		}).Times(1)
+
+		err := testXorriso.SetDates(fakeImagePath, fakeTime)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.ReadFile(fakeImagePath)).To(Equal([]byte("rewritten")))
+	})
+
+	It("xorriso SetDates - failure", func() {
+		mockExecuter.EXPECT().Execute(gomock.Any()).Return("", errors.New("some error")).Times(1)
+
+		err := testXorriso.SetDates(fakeImagePath, fakeTime)
+		Expect(err).To(HaveOccurred())
+		Expect(os.ReadFile(fakeImagePath)).To(Equal([]byte("original")))
+	})
+})
+
+func TestXorriso(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "xorriso_test")
+}